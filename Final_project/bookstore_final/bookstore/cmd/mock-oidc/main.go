@@ -0,0 +1,244 @@
+// Command mock-oidc is a local stand-in for the company identity provider.
+// It implements just enough of OpenID Connect for the bookstore's login:
+// discovery, an authorization endpoint that signs every visitor in as the
+// configured user, a token endpoint that checks PKCE, and a JWKS.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"bookstore/internal/auth"
+)
+
+const codeLifetime = time.Minute
+
+// authCode is an issued authorization code waiting to be redeemed.
+type authCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	expires     time.Time
+}
+
+type provider struct {
+	issuer   string
+	clientID string
+	user     string
+	email    string
+	groups   []string
+	key      *auth.SigningKey
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+func main() {
+	port := flag.Int("port", 9091, "Port to listen on")
+	issuer := flag.String("issuer", "", "Issuer URL (default http://localhost:<port>)")
+	clientID := flag.String("client-id", "bookstore", "The only client ID accepted")
+	user := flag.String("user", "jane", "preferred_username of the signed-in user")
+	email := flag.String("email", "jane@example.com", "Email of the signed-in user")
+	groups := flag.String("groups", "bookstore-admins", "Comma-separated groups of the signed-in user")
+	flag.Parse()
+
+	if *issuer == "" {
+		*issuer = fmt.Sprintf("http://localhost:%d", *port)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+
+	p := &provider{
+		issuer:   strings.TrimSuffix(*issuer, "/"),
+		clientID: *clientID,
+		user:     *user,
+		email:    *email,
+		key: &auth.SigningKey{
+			ID:      "mock-oidc",
+			Method:  jwt.SigningMethodRS256,
+			Private: rsaKey,
+			Public:  rsaKey.Public(),
+		},
+		codes: make(map[string]authCode),
+	}
+	for _, g := range strings.Split(*groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			p.groups = append(p.groups, g)
+		}
+	}
+
+	http.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	http.HandleFunc("/authorize", p.handleAuthorize)
+	http.HandleFunc("/token", p.handleToken)
+	http.HandleFunc("/jwks", p.handleJWKS)
+
+	log.Printf("Mock OIDC provider %s signing in %s (groups %v)", p.issuer, p.user, p.groups)
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), nil))
+}
+
+func (p *provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{p.key.Method.Alg()},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email", "groups"},
+	})
+}
+
+func (p *provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth.JWKSet{Keys: []auth.JWK{p.key.JWK()}})
+}
+
+// handleAuthorize skips the login page and redirects straight back with a
+// code, as if the user had signed in.
+func (p *provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("client_id") != p.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+
+	redirect := func(params url.Values) {
+		params.Set("state", q.Get("state"))
+		redirectURI.RawQuery = params.Encode()
+		http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+	}
+	switch {
+	case q.Get("response_type") != "code":
+		redirect(url.Values{"error": {"unsupported_response_type"}})
+		return
+	case !strings.Contains(" "+q.Get("scope")+" ", " openid "):
+		redirect(url.Values{"error": {"invalid_scope"}, "error_description": {"openid scope required"}})
+		return
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		redirect(url.Values{"error": {"invalid_request"}, "error_description": {"S256 PKCE challenge required"}})
+		return
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = authCode{
+		clientID:    p.clientID,
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		expires:     time.Now().Add(codeLifetime),
+	}
+	p.mu.Unlock()
+
+	log.Printf("Signed in %s for %s", p.user, redirectURI.Host)
+	redirect(url.Values{"code": {code}})
+}
+
+// handleToken redeems a code once, checking the client, redirect URI and
+// PKCE verifier it was issued for.
+func (p *provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+	clientID := r.PostForm.Get("client_id")
+	if id, _, ok := r.BasicAuth(); ok {
+		clientID = id
+	}
+
+	p.mu.Lock()
+	code, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code":
+		tokenError(w, "unsupported_grant_type", "")
+		return
+	case !ok || time.Now().After(code.expires):
+		tokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	case clientID != code.clientID || r.PostForm.Get("redirect_uri") != code.redirectURI:
+		tokenError(w, "invalid_grant", "client or redirect_uri mismatch")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.challenge)) != 1 {
+		tokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(p.key.Method, jwt.MapClaims{
+		"iss":                p.issuer,
+		"sub":                "mock|" + p.user,
+		"aud":                code.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              code.nonce,
+		"preferred_username": p.user,
+		"email":              p.email,
+		"email_verified":     true,
+		"groups":             p.groups,
+	})
+	idToken.Header["kid"] = p.key.ID
+	signed, err := idToken.SignedString(p.key.Private)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Failed to read random bytes: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+// Command webhook-receiver is a local stand-in for a webhook consumer. It
+// verifies and prints every delivery it receives, and can be told to fail
+// so that retries and redelivery can be tried out.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"bookstore/internal/webhooks"
+)
+
+func main() {
+	port := flag.Int("port", 9090, "Port to listen on")
+	secret := flag.String("secret", "", "Subscription secret; signatures are not checked when empty")
+	status := flag.Int("status", http.StatusOK, "Status code to answer deliveries with")
+	failFirst := flag.Int("fail-first", 0, "Answer the first N deliveries with 500")
+	flag.Parse()
+
+	var mu sync.Mutex
+	received := 0
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if *secret != "" {
+			err := webhooks.Verify(*secret,
+				r.Header.Get(webhooks.TimestampHeader),
+				r.Header.Get(webhooks.SignatureHeader),
+				body, time.Now(), 5*time.Minute)
+			if err != nil {
+				log.Printf("Rejected delivery %s: %v", r.Header.Get(webhooks.DeliveryHeader), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var envelope webhooks.Envelope
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		received++
+		n := received
+		mu.Unlock()
+
+		code := *status
+		if n <= *failFirst {
+			code = http.StatusInternalServerError
+		}
+		log.Printf("Delivery %s: event %d %s %s -> %d",
+			r.Header.Get(webhooks.DeliveryHeader), envelope.ID, envelope.Type, envelope.Data, code)
+		w.WriteHeader(code)
+		fmt.Fprintf(w, "received %d\n", n)
+	})
+
+	log.Printf("Listening for webhooks on :%d", *port)
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), nil))
+}
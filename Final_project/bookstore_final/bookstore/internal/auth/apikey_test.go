@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyID(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		wantID string
+		wantOK bool
+	}{
+		{"valid", "bk_0a1b2c3d_" + strings.Repeat("f", 64), "0a1b2c3d", true},
+		{"no prefix", "0a1b2c3d_" + strings.Repeat("f", 64), "", false},
+		{"other prefix", "sk_0a1b2c3d_secret", "", false},
+		{"no secret", "bk_0a1b2c3d", "0a1b2c3d", false},
+		{"short id", "bk_0a1b_secret", "0a1b", false},
+		{"long id", "bk_0a1b2c3d4e_secret", "0a1b2c3d4e", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := apiKeyID(tt.key)
+			if ok != tt.wantOK || (ok && id != tt.wantID) {
+				t.Errorf("apiKeyID(%q) = %q, %v; want %q, %v", tt.key, id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey: %v", err)
+	}
+	id, ok := apiKeyID(key)
+	if !ok || id != prefix {
+		t.Errorf("apiKeyID(%q) = %q, %v; want %q, true", key, id, ok, prefix)
+	}
+	if hash != HashAPIKey(key) {
+		t.Errorf("hash does not match HashAPIKey of the key")
+	}
+	if other, _, _, _ := GenerateAPIKey(); other == key {
+		t.Errorf("two generated keys are equal")
+	}
+}
+
+func TestValidateScope(t *testing.T) {
+	tests := []struct {
+		scope   string
+		wantErr bool
+	}{
+		{"*", false},
+		{"books:read", false},
+		{"books:write", false},
+		{"orders:*", false},
+		{"shipping-rates:read", false},
+		{"books", true},
+		{"books:delete", true},
+		{"unicorns:read", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateScope(tt.scope); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateScope(%q) = %v, want error %v", tt.scope, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/api/books", "books:read"},
+		{"HEAD", "/api/books/7", "books:read"},
+		{"POST", "/api/orders/7/pay", "orders:write"},
+		{"DELETE", "/api/api-keys/3", "api-keys:write"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := RequiredScope(r); got != tt.want {
+			t.Errorf("RequiredScope(%s %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestClaimsAllows(t *testing.T) {
+	key := &Claims{APIKeyID: 1, Scopes: []string{"books:read", "orders:*"}}
+
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{"books:read", true},
+		{"books:write", false},
+		{"orders:read", true},
+		{"orders:write", true},
+		{"customers:read", false},
+	}
+	for _, tt := range tests {
+		if got := key.Allows(tt.scope); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package auth
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the authenticated claims.
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims stored by the auth middleware, if any.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// Username returns the authenticated username, or "system" for work that
+// does not originate from an authenticated request.
+func Username(ctx context.Context) string {
+	if claims, ok := FromContext(ctx); ok && claims.Username != "" {
+		return claims.Username
+	}
+	return "system"
+}
@@ -3,19 +3,34 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// JWTManager signs tokens either with a shared HS256 secret or, when
+// built with NewJWTManagerWithKeys, with one of a set of asymmetric keys.
+// Tokens carry the kid of their key so that every key in the set keeps
+// verifying while a new one is rolled out.
 type JWTManager struct {
 	secretKey     string
+	keys          map[string]*SigningKey
+	signingKey    *SigningKey
 	tokenDuration time.Duration
 }
 
 
 type Claims struct {
-	Username string `json:"username"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"`
+	// CustomerID is set for customers signed in to their own account.
+	CustomerID int `json:"customer_id,omitempty"`
+	// Scopes and APIKeyID are set when the request was authenticated with
+	// an API key rather than a token.
+	Scopes   []string `json:"-"`
+	APIKeyID int      `json:"-"`
 	jwt.RegisteredClaims
 }
 
@@ -26,24 +41,69 @@ func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	}
 }
 
-func (j *JWTManager) Generate(username string) (string, error) {
+// NewJWTManagerWithKeys signs with the key named signingKID, or the last
+// key holding a private part when signingKID is empty. All keys verify.
+func NewJWTManagerWithKeys(keys []*SigningKey, signingKID string, tokenDuration time.Duration) (*JWTManager, error) {
+	j := &JWTManager{
+		keys:          make(map[string]*SigningKey, len(keys)),
+		tokenDuration: tokenDuration,
+	}
+	for _, key := range keys {
+		if _, ok := j.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		j.keys[key.ID] = key
+		if key.Private != nil && (signingKID == "" || key.ID == signingKID) {
+			j.signingKey = key
+		}
+	}
+	if j.signingKey == nil {
+		if signingKID != "" {
+			return nil, fmt.Errorf("no private key with id %q", signingKID)
+		}
+		return nil, errors.New("no private key to sign with")
+	}
+	return j, nil
+}
+
+// JWKS returns the public keys that tokens may be verified with. It is
+// empty when tokens are signed with a shared secret.
+func (j *JWTManager) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(j.keys))}
+	for _, key := range j.keys {
+		set.Keys = append(set.Keys, key.JWK())
+	}
+	sort.Slice(set.Keys, func(a, b int) bool { return set.Keys[a].ID < set.Keys[b].ID })
+	return set
+}
+
+func (j *JWTManager) Generate(username string, roles []string) (string, error) {
+	return j.sign(&Claims{Username: username, Roles: roles})
+}
+
+// GenerateCustomer issues a token for a customer's own account.
+func (j *JWTManager) GenerateCustomer(email string, customerID int) (string, error) {
+	return j.sign(&Claims{Username: email, Roles: []string{RoleCustomer}, CustomerID: customerID})
+}
+
+func (j *JWTManager) sign(claims *Claims) (string, error) {
 	now := time.Now()
-	claims := &Claims{
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(j.tokenDuration)),
-		},
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(j.tokenDuration)),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	if j.signingKey == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(j.secretKey))
+	}
+	token := jwt.NewWithClaims(j.signingKey.Method, claims)
+	token.Header["kid"] = j.signingKey.ID
+	return token.SignedString(j.signingKey.Private)
 }
 
 func (j *JWTManager) Validate(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(j.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.verificationKey)
 	if err != nil {
 		return nil, err
 	}
@@ -52,3 +112,24 @@ func (j *JWTManager) Validate(tokenString string) (*Claims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
+
+// verificationKey picks the key named by the token's kid. The algorithm
+// must be the one of that key, never whatever the token asks for.
+func (j *JWTManager) verificationKey(t *jwt.Token) (interface{}, error) {
+	if j.signingKey == nil {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return []byte(j.secretKey), nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+	return key.Public, nil
+}
@@ -1,23 +1,47 @@
-
 package auth
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
+
+	"bookstore/internal/interfaces"
+	"bookstore/pkg/utils"
 )
 
+// apiKeyTouchInterval limits how often a key's last use is written back.
+const apiKeyTouchInterval = time.Minute
+
+var errInvalidAPIKey = errors.New("invalid API key")
+
 type AuthMiddleware struct {
-	jwtManager *JWTManager
+	jwtManager  *JWTManager
+	apiKeyStore interfaces.APIKeyStore
 }
 
-func NewAuthMiddleware(jwtManager *JWTManager) *AuthMiddleware {
-	return &AuthMiddleware{jwtManager: jwtManager}
+func NewAuthMiddleware(jwtManager *JWTManager, apiKeyStore interfaces.APIKeyStore) *AuthMiddleware {
+	return &AuthMiddleware{jwtManager: jwtManager, apiKeyStore: apiKeyStore}
 }
 
 
+// Middleware accepts a bearer JWT or an API key, either as a bearer token
+// or in X-API-Key. API keys are further limited to their scopes.
 func (am *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
+		if key := r.Header.Get(APIKeyHeader); authHeader == "" && key != "" {
+			authHeader = "Bearer " + key
+		}
+		// Browsers cannot set headers on an EventSource, so event streams
+		// may pass the token as ?access_token= instead.
+		if authHeader == "" && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			if token := r.URL.Query().Get("access_token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
 			http.Error(w, "missing Authorization header", http.StatusUnauthorized)
 			return
@@ -30,14 +54,63 @@ func (am *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 		}
 		tokenString := parts[1]
 
+		var claims *Claims
+		var err error
+		if strings.HasPrefix(tokenString, APIKeyPrefix) {
+			claims, err = am.validateAPIKey(r.Context(), tokenString)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		} else {
+			claims, err = am.jwtManager.Validate(tokenString)
+			if err != nil {
+				http.Error(w, "invalid or expired token: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
 
-		_, err := am.jwtManager.Validate(tokenString)
-		if err != nil {
-			http.Error(w, "invalid or expired token: "+err.Error(), http.StatusUnauthorized)
+		if scope := RequiredScope(r); !claims.Allows(scope) {
+			http.Error(w, "not permitted: requires scope "+scope, http.StatusForbidden)
 			return
 		}
 
-
-		next.ServeHTTP(w, r)
+		utils.SetRequestUser(r.Context(), claims.Username)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
 	})
 }
+
+// validateAPIKey looks up an API key and returns claims carrying its
+// scopes. Every failure gives the same error so as not to reveal which
+// keys exist.
+func (am *AuthMiddleware) validateAPIKey(ctx context.Context, key string) (*Claims, error) {
+	id, ok := apiKeyID(key)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+	apiKey, err := am.apiKeyStore.GetAPIKeyByPrefix(ctx, id)
+	if err != nil {
+		return nil, errInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(apiKey.Hash)) != 1 {
+		return nil, errInvalidAPIKey
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil {
+		return nil, errors.New("API key revoked")
+	}
+	if apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt) {
+		return nil, errors.New("API key expired")
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		// Tracking is best effort; a failed write must not fail the request.
+		am.apiKeyStore.TouchAPIKey(ctx, apiKey.ID, now)
+	}
+
+	return &Claims{
+		Username: "apikey:" + apiKey.Prefix,
+		Scopes:   apiKey.Scopes,
+		APIKeyID: apiKey.ID,
+	}, nil
+}
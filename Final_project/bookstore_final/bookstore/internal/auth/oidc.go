@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// OIDCLoginTimeout is how long a user has to finish signing in at the
+	// identity provider.
+	OIDCLoginTimeout = 10 * time.Minute
+	// maxPendingOIDCLogins bounds the logins waiting for a callback, so
+	// that unfinished logins cannot grow without limit.
+	maxPendingOIDCLogins = 10000
+)
+
+var (
+	ErrOIDCUnknownState = errors.New("unknown or expired login state")
+	ErrOIDCNoRole       = errors.New("identity has no bookstore role")
+)
+
+type OIDCConfig struct {
+	IssuerURL string
+	ClientID  string
+	// ClientSecret may be empty for a public client; PKCE protects the
+	// code exchange either way.
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested in addition to openid.
+	Scopes []string
+	// GroupsClaim names the ID token claim listing the user's groups.
+	GroupsClaim string
+	// RoleMap maps identity provider groups to bookstore roles.
+	RoleMap map[string]string
+}
+
+// Identity is a user signed in through the identity provider.
+type Identity struct {
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+	Roles    []string
+}
+
+// OIDCProvider runs the authorization code flow with PKCE against an
+// OpenID Connect identity provider.
+type OIDCProvider struct {
+	config   OIDCConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+
+	mu      sync.Mutex
+	pending map[string]pendingLogin
+}
+
+// pendingLogin is what is remembered between redirecting to the provider
+// and its callback, keyed by state.
+type pendingLogin struct {
+	nonce    string
+	verifier string
+	expires  time.Time
+}
+
+// NewOIDCProvider fetches the provider's discovery document, so the
+// provider must be reachable at startup.
+func NewOIDCProvider(ctx context.Context, config OIDCConfig) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	return &OIDCProvider{
+		config: config,
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, config.Scopes...),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+		pending:  make(map[string]pendingLogin),
+	}, nil
+}
+
+// AuthCodeURL starts a login. It returns the provider URL to send the user
+// to and the state that the callback must present.
+func (p *OIDCProvider) AuthCodeURL() (authURL, state string, err error) {
+	state, err = randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	p.mu.Lock()
+	for s, login := range p.pending {
+		if now.After(login.expires) {
+			delete(p.pending, s)
+		}
+	}
+	if len(p.pending) >= maxPendingOIDCLogins {
+		p.mu.Unlock()
+		return "", "", errors.New("too many logins in progress")
+	}
+	p.pending[state] = pendingLogin{nonce: nonce, verifier: verifier, expires: now.Add(OIDCLoginTimeout)}
+	p.mu.Unlock()
+
+	authURL = p.oauth2.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oidc.Nonce(nonce))
+	return authURL, state, nil
+}
+
+// Exchange completes a login: it redeems the code, verifies the ID token
+// and maps the user's groups to roles. A state can be used only once.
+func (p *OIDCProvider) Exchange(ctx context.Context, state, code string) (*Identity, error) {
+	p.mu.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return nil, ErrOIDCUnknownState
+	}
+
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(login.verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != login.nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	identity := &Identity{
+		Subject: idToken.Subject,
+		Groups:  stringList(claims[p.config.GroupsClaim]),
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.Username, _ = claims["preferred_username"].(string)
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		identity.Username = idToken.Subject
+	}
+
+	identity.Roles = p.roles(identity.Groups)
+	if len(identity.Roles) == 0 {
+		return identity, ErrOIDCNoRole
+	}
+	return identity, nil
+}
+
+// roles maps groups to roles, without duplicates and in a stable order.
+func (p *OIDCProvider) roles(groups []string) []string {
+	seen := make(map[string]bool)
+	var roles []string
+	for _, group := range groups {
+		if role, ok := p.config.RoleMap[group]; ok && !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// stringList reads a claim that providers send either as a list or, with a
+// single value, as a plain string.
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
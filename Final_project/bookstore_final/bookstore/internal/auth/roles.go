@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Roles a user token can carry. Each grants a fixed set of scopes, the same
+// scopes API keys are limited by.
+const (
+	RoleAdmin  = "admin"
+	RoleStaff  = "staff"
+	RoleViewer = "viewer"
+	// RoleCustomer is given to customers signing in to their own account,
+	// and only reaches /api/account.
+	RoleCustomer = "customer"
+)
+
+var roleScopes = map[string][]string{
+	RoleAdmin:    {ScopeAll},
+	RoleStaff:    resourceScopes(ScopeAll, "account", "api-keys"),
+	RoleViewer:   resourceScopes(ScopeRead, "account"),
+	RoleCustomer: {"account:" + ScopeAll},
+}
+
+// resourceScopes returns <resource>:<action> for every resource except
+// those excluded.
+func resourceScopes(action string, exclude ...string) []string {
+	scopes := make([]string, 0, len(Resources))
+next:
+	for _, r := range Resources {
+		for _, e := range exclude {
+			if r == e {
+				continue next
+			}
+		}
+		scopes = append(scopes, r+":"+action)
+	}
+	return scopes
+}
+
+func ValidateRole(role string) error {
+	if _, ok := roleScopes[role]; !ok {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	return nil
+}
+
+// ParseRoleMap parses "group=role,group=role" as used to map identity
+// provider groups to roles.
+func ParseRoleMap(s string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok || group == "" {
+			return nil, fmt.Errorf("invalid role mapping %q: want group=role", pair)
+		}
+		if err := ValidateRole(role); err != nil {
+			return nil, err
+		}
+		if role == RoleCustomer {
+			return nil, fmt.Errorf("role %q is only for customer accounts", role)
+		}
+		roles[group] = role
+	}
+	return roles, nil
+}
+
+// scopes returns what the claims are allowed to do: an API key's own
+// scopes, or the union of the user's roles. Tokens issued before roles
+// existed were only ever given to the admin, so no roles means admin.
+func (c *Claims) scopes() []string {
+	if c.APIKeyID != 0 {
+		return c.Scopes
+	}
+	if len(c.Roles) == 0 {
+		return roleScopes[RoleAdmin]
+	}
+	var scopes []string
+	for _, role := range c.Roles {
+		scopes = append(scopes, roleScopes[role]...)
+	}
+	return scopes
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"bookstore/internal/models"
+)
+
+// Broker fans events published on the bus out to live listeners, such as
+// stream clients. A listener that falls behind by more than its buffer is
+// dropped, and is expected to reconnect and catch up from the outbox.
+type Broker struct {
+	mu        sync.Mutex
+	listeners map[chan models.Event]struct{}
+	buffer    int
+	closed    bool
+}
+
+func NewBroker(buffer int) *Broker {
+	return &Broker{
+		listeners: make(map[chan models.Event]struct{}),
+		buffer:    buffer,
+	}
+}
+
+// Listen registers a listener. The channel is closed when cancel is called
+// or when the listener is dropped for being too slow.
+func (b *Broker) Listen() (<-chan models.Event, func()) {
+	ch := make(chan models.Event, b.buffer)
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.listeners[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return ch, func() { b.drop(ch) }
+}
+
+// Handler is the bus handler feeding the broker. It never fails, so a slow
+// listener cannot hold up delivery to other subscribers.
+func (b *Broker) Handler() Handler {
+	return func(ctx context.Context, event models.Event) error {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for ch := range b.listeners {
+			select {
+			case ch <- event:
+			default:
+				delete(b.listeners, ch)
+				close(ch)
+			}
+		}
+		return nil
+	}
+}
+
+// Close disconnects every listener, e.g. so that open streams do not hold
+// up server shutdown.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.listeners {
+		delete(b.listeners, ch)
+		close(ch)
+	}
+}
+
+func (b *Broker) drop(ch chan models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.listeners[ch]; ok {
+		delete(b.listeners, ch)
+		close(ch)
+	}
+}
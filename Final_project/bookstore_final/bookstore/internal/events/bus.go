@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"bookstore/internal/models"
+)
+
+// All subscribes a handler to every event type.
+const All = "*"
+
+// Handler processes one event. Since delivery is at least once, handlers
+// must tolerate seeing the same event (same ID) more than once.
+type Handler func(ctx context.Context, event models.Event) error
+
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers handler for eventType, or for every event when
+// eventType is All.
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish delivers event to its subscribers in registration order. All of
+// them run even if one fails; the failures are joined into the returned
+// error, and the caller retries the whole event.
+func (b *Bus) Publish(ctx context.Context, event models.Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[event.Type]...), b.handlers[All]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, h := range handlers {
+		if err := h(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Decode unmarshals the payload of an event into the typed event v.
+func Decode(event models.Event, v interface{}) error {
+	if err := json.Unmarshal(event.Payload, v); err != nil {
+		return fmt.Errorf("decode %s event %d: %w", event.Type, event.ID, err)
+	}
+	return nil
+}
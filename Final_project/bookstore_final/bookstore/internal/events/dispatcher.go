@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"bookstore/internal/interfaces"
+	"bookstore/pkg/utils"
+)
+
+const (
+	dispatchBatch = 100
+	maxBackoff    = 10 * time.Minute
+	pruneInterval = time.Hour
+)
+
+// Dispatcher moves events from the outbox onto the bus. An event is only
+// marked dispatched once every subscriber has handled it; failed events are
+// retried with exponential backoff, so delivery is at least once.
+type Dispatcher struct {
+	outbox    interfaces.OutboxStore
+	bus       *Bus
+	interval  time.Duration
+	retention time.Duration
+	logger    *utils.Logger
+}
+
+// NewDispatcher polls the outbox every interval and deletes dispatched
+// events once they are older than retention.
+func NewDispatcher(outbox interfaces.OutboxStore, bus *Bus, interval, retention time.Duration, logger *utils.Logger) *Dispatcher {
+	return &Dispatcher{
+		outbox:    outbox,
+		bus:       bus,
+		interval:  interval,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+func (d *Dispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	lastPrune := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.dispatch(ctx)
+			if now.Sub(lastPrune) >= pruneInterval {
+				lastPrune = now
+				if _, err := d.outbox.DeleteDispatchedEvents(ctx, now.Add(-d.retention)); err != nil {
+					d.logger.Error("Failed to prune outbox: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// dispatch publishes the pending events, a batch at a time, until none are
+// due or the context ends.
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		pending, err := d.outbox.PendingEvents(ctx, time.Now(), dispatchBatch)
+		if err != nil {
+			d.logger.Error("Failed to read outbox: %v", err)
+			return
+		}
+
+		for _, event := range pending {
+			if err := d.bus.Publish(ctx, event); err != nil {
+				next := time.Now().Add(backoff(event.Attempts + 1))
+				d.logger.Error("Event %d (%s) failed, retrying at %s: %v", event.ID, event.Type, next.Format(time.RFC3339), err)
+				if err := d.outbox.MarkEventFailed(ctx, event.ID, err.Error(), next); err != nil {
+					d.logger.Error("Failed to record outbox failure: %v", err)
+				}
+				continue
+			}
+			if err := d.outbox.MarkEventDispatched(ctx, event.ID); err != nil {
+				d.logger.Error("Failed to mark event %d dispatched: %v", event.ID, err)
+			}
+		}
+
+		if len(pending) < dispatchBatch {
+			return
+		}
+	}
+}
+
+// backoff doubles the retry delay from one second up to maxBackoff.
+func backoff(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,139 @@
+// Package events defines the domain events published by the stores and the
+// bus that delivers them to in-process subscribers.
+//
+// Stores never publish directly: they write events to the outbox table in
+// the same transaction as the change, so an event exists if and only if the
+// change was committed. The Dispatcher then reads the outbox and publishes
+// each event on the Bus at least once.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event types.
+const (
+	OrderCreated       = "order.created"
+	OrderStatusChanged = "order.status_changed"
+	BookPriceChanged   = "book.price_changed"
+	StockLow           = "stock.low"
+	CustomerRegistered = "customer.registered"
+	StockChanged       = "stock.changed"
+	ReportGenerated    = "report.generated"
+	CustomerErased     = "customer.erased"
+)
+
+// Types lists every event type.
+var Types = []string{
+	OrderCreated,
+	OrderStatusChanged,
+	BookPriceChanged,
+	StockLow,
+	CustomerRegistered,
+	StockChanged,
+	ReportGenerated,
+	CustomerErased,
+}
+
+// Known reports whether eventType is one of Types.
+func Known(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderCreatedEvent struct {
+	OrderID    int     `json:"order_id"`
+	CustomerID int     `json:"customer_id"`
+	TotalPrice float64 `json:"total_price"`
+}
+
+type OrderStatusChangedEvent struct {
+	OrderID int    `json:"order_id"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+type BookPriceChangedEvent struct {
+	BookID   int     `json:"book_id"`
+	OldPrice float64 `json:"old_price"`
+	NewPrice float64 `json:"new_price"`
+}
+
+// StockLowEvent is raised when a book's stock falls to or below its
+// low-stock threshold.
+type StockLowEvent struct {
+	BookID    int    `json:"book_id"`
+	Title     string `json:"title"`
+	Stock     int    `json:"stock"`
+	Threshold int    `json:"threshold"`
+}
+
+// StockChangedEvent is raised for every stock ledger movement.
+type StockChangedEvent struct {
+	BookID int    `json:"book_id"`
+	Change int    `json:"change"`
+	Stock  int    `json:"stock"`
+	Reason string `json:"reason"`
+}
+
+type ReportGeneratedEvent struct {
+	Timestamp    time.Time `json:"timestamp"`
+	TotalOrders  int       `json:"total_orders"`
+	TotalRevenue float64   `json:"total_revenue"`
+	NetRevenue   float64   `json:"net_revenue"`
+	File         string    `json:"file"`
+}
+
+type CustomerRegisteredEvent struct {
+	CustomerID int    `json:"customer_id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+}
+
+// CustomerErasedEvent is raised when a customer's personal data has been
+// erased, so that subscribers can erase their copies too.
+type CustomerErasedEvent struct {
+	CustomerID int `json:"customer_id"`
+}
+
+// TypeOf returns the event type of a typed event.
+func TypeOf(event interface{}) (string, error) {
+	switch event.(type) {
+	case OrderCreatedEvent:
+		return OrderCreated, nil
+	case OrderStatusChangedEvent:
+		return OrderStatusChanged, nil
+	case BookPriceChangedEvent:
+		return BookPriceChanged, nil
+	case StockLowEvent:
+		return StockLow, nil
+	case CustomerRegisteredEvent:
+		return CustomerRegistered, nil
+	case StockChangedEvent:
+		return StockChanged, nil
+	case ReportGeneratedEvent:
+		return ReportGenerated, nil
+	case CustomerErasedEvent:
+		return CustomerErased, nil
+	}
+	return "", fmt.Errorf("unknown event %T", event)
+}
+
+// Encode returns the type and JSON payload of a typed event.
+func Encode(event interface{}) (string, []byte, error) {
+	eventType, err := TypeOf(event)
+	if err != nil {
+		return "", nil, err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", nil, err
+	}
+	return eventType, payload, nil
+}
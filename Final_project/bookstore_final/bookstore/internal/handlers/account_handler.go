@@ -0,0 +1,355 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/mail"
+    "strconv"
+    "strings"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/auth"
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+    "bookstore/internal/ratelimit"
+)
+
+// AccountHandler is the customers' self-service API. Every route but
+// register and login works on the customer in the token only, so a
+// customer has no way to name another customer's data.
+type AccountHandler struct {
+    accountStore  interfaces.AccountStore
+    customerStore interfaces.CustomerStore
+    orderStore    interfaces.OrderStore
+    jwtManager    *auth.JWTManager
+    limiter       *ratelimit.Limiter
+}
+
+func NewAccountHandler(
+    accountStore interfaces.AccountStore,
+    customerStore interfaces.CustomerStore,
+    orderStore interfaces.OrderStore,
+    jwtManager *auth.JWTManager,
+    limiter *ratelimit.Limiter,
+) *AccountHandler {
+    return &AccountHandler{
+        accountStore:  accountStore,
+        customerStore: customerStore,
+        orderStore:    orderStore,
+        jwtManager:    jwtManager,
+        limiter:       limiter,
+    }
+}
+
+func (h *AccountHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.HandleFunc("/account/register", h.handleRegister).Methods("POST")
+    router.HandleFunc("/account/login", h.handleLogin).Methods("POST")
+
+    router.Handle("/account", mw(http.HandlerFunc(h.handleProfile))).
+        Methods("GET", "PUT")
+
+    router.Handle("/account/password", mw(http.HandlerFunc(h.handlePassword))).
+        Methods("PUT")
+
+    router.Handle("/account/orders", mw(http.HandlerFunc(h.handleOrders))).
+        Methods("GET")
+
+    router.Handle("/account/orders/{id:[0-9]+}", mw(http.HandlerFunc(h.handleOrderByID))).
+        Methods("GET")
+
+    router.Handle("/account/orders/{id:[0-9]+}/cancel", mw(http.HandlerFunc(h.handleCancelOrder))).
+        Methods("POST")
+}
+
+type registerRequest struct {
+    Name     string         `json:"name"`
+    Email    string         `json:"email"`
+    Password string         `json:"password"`
+    Address  models.Address `json:"address"`
+}
+
+type accountLoginRequest struct {
+    Email    string `json:"email"`
+    Password string `json:"password"`
+}
+
+type profileRequest struct {
+    Name    string         `json:"name"`
+    Address models.Address `json:"address"`
+}
+
+type passwordRequest struct {
+    CurrentPassword string `json:"current_password"`
+    NewPassword     string `json:"new_password"`
+}
+
+// handleRegister creates a new customer with an account and signs them
+// in. It never links to an existing customer with the same email, as the
+// email has not been verified.
+func (h *AccountHandler) handleRegister(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    var req registerRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    email, ok := normalizeEmail(req.Email)
+    if !ok {
+        http.Error(w, "A valid email is required", http.StatusBadRequest)
+        return
+    }
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" {
+        http.Error(w, "Name is required", http.StatusBadRequest)
+        return
+    }
+    if err := auth.ValidatePassword(req.Password); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    // Registering is rate limited like logging in, so that it cannot be
+    // used to probe which emails have accounts.
+    if !h.limiter.AllowLogin(w, r, email) {
+        return
+    }
+
+    hash, err := auth.HashPassword(req.Password)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    account, customer, err := h.accountStore.CreateAccount(r.Context(),
+        models.Account{Email: email, PasswordHash: hash},
+        models.Customer{Name: req.Name, Email: email, Address: req.Address},
+    )
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+
+    token, err := h.jwtManager.GenerateCustomer(account.Email, account.CustomerID)
+    if err != nil {
+        http.Error(w, "failed to generate token", http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "token":    token,
+        "customer": customer,
+    })
+}
+
+func (h *AccountHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+    var req accountLoginRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "invalid JSON", http.StatusBadRequest)
+        return
+    }
+    email, _ := normalizeEmail(req.Email)
+    if !h.limiter.AllowLogin(w, r, email) {
+        return
+    }
+
+    account, err := h.accountStore.GetAccountByEmail(r.Context(), email)
+    if err != nil {
+        account = models.Account{}
+    }
+    if !auth.CheckPassword(account.PasswordHash, req.Password) {
+        h.limiter.LoginFailed(r, email)
+        http.Error(w, "invalid credentials", http.StatusUnauthorized)
+        return
+    }
+    h.limiter.LoginSucceeded(r, email)
+
+    token, err := h.jwtManager.GenerateCustomer(account.Email, account.CustomerID)
+    if err != nil {
+        http.Error(w, "failed to generate token", http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+func (h *AccountHandler) handleProfile(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    customer, ok := accountCustomer(w, r, h.customerStore)
+    if !ok {
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet:
+        json.NewEncoder(w).Encode(customer)
+    case http.MethodPut:
+        // The email is the sign-in name and stays as it is.
+        var req profileRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        req.Name = strings.TrimSpace(req.Name)
+        if req.Name == "" {
+            http.Error(w, "Name is required", http.StatusBadRequest)
+            return
+        }
+        customer.Name = req.Name
+        customer.Address = req.Address
+        updated, err := h.customerStore.UpdateCustomer(r.Context(), customer.ID, customer)
+        if err != nil {
+            writeStoreError(w, err)
+            return
+        }
+        json.NewEncoder(w).Encode(updated)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *AccountHandler) handlePassword(w http.ResponseWriter, r *http.Request) {
+    if _, ok := accountCustomer(w, r, h.customerStore); !ok {
+        return
+    }
+    claims, _ := auth.FromContext(r.Context())
+
+    var req passwordRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    account, err := h.accountStore.GetAccountByEmail(r.Context(), claims.Username)
+    if err != nil || account.CustomerID != claims.CustomerID {
+        http.Error(w, "account not found", http.StatusNotFound)
+        return
+    }
+    if !auth.CheckPassword(account.PasswordHash, req.CurrentPassword) {
+        http.Error(w, "current password is wrong", http.StatusForbidden)
+        return
+    }
+    if err := auth.ValidatePassword(req.NewPassword); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    hash, err := auth.HashPassword(req.NewPassword)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if err := h.accountStore.UpdatePassword(r.Context(), account.ID, hash); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AccountHandler) handleOrders(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    customer, ok := accountCustomer(w, r, h.customerStore)
+    if !ok {
+        return
+    }
+    orders, err := h.orderStore.ListCustomerOrders(r.Context(), customer.ID)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(orders)
+}
+
+func (h *AccountHandler) handleOrderByID(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    order, ok := h.ownOrder(w, r)
+    if !ok {
+        return
+    }
+    json.NewEncoder(w).Encode(order)
+}
+
+func (h *AccountHandler) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    order, ok := h.ownOrder(w, r)
+    if !ok {
+        return
+    }
+    cancelled, err := h.orderStore.CancelOrder(r.Context(), order.ID)
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    json.NewEncoder(w).Encode(cancelled)
+}
+
+// ownOrder loads the order in the path if it belongs to the signed-in
+// customer. Other customers' orders are reported as not found, so that
+// their existence is not given away.
+func (h *AccountHandler) ownOrder(w http.ResponseWriter, r *http.Request) (models.Order, bool) {
+    customer, ok := accountCustomer(w, r, h.customerStore)
+    if !ok {
+        return models.Order{}, false
+    }
+    idStr := mux.Vars(r)["id"]
+    id, err := strconv.Atoi(idStr)
+    if err != nil {
+        http.Error(w, "Invalid order ID", http.StatusBadRequest)
+        return models.Order{}, false
+    }
+
+    order, err := h.orderStore.GetOrder(r.Context(), id)
+    if err != nil || order.Customer.ID != customer.ID {
+        http.Error(w, "order not found with id: "+idStr, http.StatusNotFound)
+        return models.Order{}, false
+    }
+    return order, true
+}
+
+// accountCustomerID returns the customer the request's token was issued
+// for. Staff tokens and API keys have none.
+func accountCustomerID(w http.ResponseWriter, r *http.Request) (int, bool) {
+    claims, ok := auth.FromContext(r.Context())
+    if !ok || claims.CustomerID == 0 {
+        http.Error(w, "not a customer account", http.StatusForbidden)
+        return 0, false
+    }
+    return claims.CustomerID, true
+}
+
+// accountCustomer loads the signed-in customer. A customer's token stays
+// valid until it expires, even after their data was erased.
+func accountCustomer(w http.ResponseWriter, r *http.Request, customerStore interfaces.CustomerStore) (models.Customer, bool) {
+    customerID, ok := accountCustomerID(w, r)
+    if !ok {
+        return models.Customer{}, false
+    }
+    return activeCustomer(w, r, customerStore, customerID)
+}
+
+// activeCustomer loads a customer, refusing those whose data was erased.
+func activeCustomer(w http.ResponseWriter, r *http.Request, customerStore interfaces.CustomerStore, id int) (models.Customer, bool) {
+    customer, err := customerStore.GetCustomer(r.Context(), id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return customer, false
+    }
+    if customer.ErasedAt != nil {
+        writeStoreError(w, fmt.Errorf("%w: customer %d", models.ErrCustomerErased, id))
+        return customer, false
+    }
+    return customer, true
+}
+
+// normalizeEmail trims and lower-cases a bare email address.
+func normalizeEmail(s string) (string, bool) {
+    s = strings.ToLower(strings.TrimSpace(s))
+    addr, err := mail.ParseAddress(s)
+    if err != nil || addr.Address != s {
+        return s, false
+    }
+    return s, true
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+// AddressHandler manages customers' saved addresses, both for staff under
+// /customers/{id} and for customers themselves under /account.
+type AddressHandler struct {
+    addressStore  interfaces.AddressStore
+    customerStore interfaces.CustomerStore
+}
+
+func NewAddressHandler(addressStore interfaces.AddressStore, customerStore interfaces.CustomerStore) *AddressHandler {
+    return &AddressHandler{
+        addressStore:  addressStore,
+        customerStore: customerStore,
+    }
+}
+
+func (h *AddressHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/customers/{id:[0-9]+}/addresses", mw(h.forCustomer(h.handleAddresses))).
+        Methods("GET", "POST")
+
+    router.Handle("/customers/{id:[0-9]+}/addresses/{addressId:[0-9]+}", mw(h.forCustomer(h.handleAddressByID))).
+        Methods("GET", "PUT", "DELETE")
+
+    router.Handle("/account/addresses", mw(forAccount(h.customerStore, h.handleAddresses))).
+        Methods("GET", "POST")
+
+    router.Handle("/account/addresses/{addressId:[0-9]+}", mw(forAccount(h.customerStore, h.handleAddressByID))).
+        Methods("GET", "PUT", "DELETE")
+}
+
+type customerHandlerFunc func(w http.ResponseWriter, r *http.Request, customerID int)
+
+// forCustomer takes the customer from the path and checks that it exists
+// and was not erased.
+func (h *AddressHandler) forCustomer(next customerHandlerFunc) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+
+        id, err := strconv.Atoi(mux.Vars(r)["id"])
+        if err != nil {
+            http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+            return
+        }
+        if _, ok := activeCustomer(w, r, h.customerStore, id); !ok {
+            return
+        }
+        next(w, r, id)
+    })
+}
+
+// forAccount takes the customer from the signed-in customer's token.
+func forAccount(customerStore interfaces.CustomerStore, next customerHandlerFunc) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+
+        customer, ok := accountCustomer(w, r, customerStore)
+        if !ok {
+            return
+        }
+        next(w, r, customer.ID)
+    })
+}
+
+func (h *AddressHandler) handleAddresses(w http.ResponseWriter, r *http.Request, customerID int) {
+    switch r.Method {
+    case http.MethodGet:
+        addresses, err := h.addressStore.ListAddresses(r.Context(), customerID)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(addresses)
+    case http.MethodPost:
+        var address models.CustomerAddress
+        if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if err := validateAddress(address.Address); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        address.CustomerID = customerID
+
+        created, err := h.addressStore.CreateAddress(r.Context(), address)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(created)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *AddressHandler) handleAddressByID(w http.ResponseWriter, r *http.Request, customerID int) {
+    id, err := strconv.Atoi(mux.Vars(r)["addressId"])
+    if err != nil {
+        http.Error(w, "Invalid address ID", http.StatusBadRequest)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet:
+        address, err := h.addressStore.GetAddress(r.Context(), customerID, id)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        json.NewEncoder(w).Encode(address)
+    case http.MethodPut:
+        var address models.CustomerAddress
+        if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if err := validateAddress(address.Address); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if _, err := h.addressStore.GetAddress(r.Context(), customerID, id); err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+
+        updated, err := h.addressStore.UpdateAddress(r.Context(), customerID, id, address)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(updated)
+    case http.MethodDelete:
+        if _, err := h.addressStore.GetAddress(r.Context(), customerID, id); err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        if err := h.addressStore.DeleteAddress(r.Context(), customerID, id); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func validateAddress(address models.Address) error {
+    if strings.TrimSpace(address.Street) == "" || strings.TrimSpace(address.City) == "" {
+        return errors.New("street and city are required")
+    }
+    if strings.TrimSpace(address.Country) == "" {
+        return errors.New("country is required")
+    }
+    return nil
+}
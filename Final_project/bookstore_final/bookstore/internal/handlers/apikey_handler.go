@@ -0,0 +1,150 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/auth"
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+type APIKeyHandler struct {
+    apiKeyStore interfaces.APIKeyStore
+}
+
+func NewAPIKeyHandler(apiKeyStore interfaces.APIKeyStore) *APIKeyHandler {
+    return &APIKeyHandler{apiKeyStore: apiKeyStore}
+}
+
+func (h *APIKeyHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/api-keys", mw(http.HandlerFunc(h.handleAPIKeys))).
+        Methods("GET", "POST")
+
+    router.Handle("/api-keys/{id:[0-9]+}", mw(http.HandlerFunc(h.handleAPIKeyByID))).
+        Methods("GET", "DELETE")
+}
+
+type createAPIKeyRequest struct {
+    Name      string     `json:"name"`
+    Scopes    []string   `json:"scopes"`
+    ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (h *APIKeyHandler) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    switch r.Method {
+    case http.MethodGet:
+        keys, err := h.apiKeyStore.ListAPIKeys(r.Context())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(keys)
+    case http.MethodPost:
+        h.createAPIKey(w, r)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *APIKeyHandler) createAPIKey(w http.ResponseWriter, r *http.Request) {
+    var req createAPIKeyRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    if err := validateAPIKeyRequest(req); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if claims, ok := auth.FromContext(r.Context()); ok {
+        for _, scope := range req.Scopes {
+            if !claims.CanGrant(scope) {
+                http.Error(w, "cannot grant scope "+scope+" you do not have", http.StatusForbidden)
+                return
+            }
+        }
+    }
+
+    key, prefix, hash, err := auth.GenerateAPIKey()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    created, err := h.apiKeyStore.CreateAPIKey(r.Context(), models.APIKey{
+        Name:      req.Name,
+        Prefix:    prefix,
+        Hash:      hash,
+        Scopes:    req.Scopes,
+        CreatedBy: auth.Username(r.Context()),
+        ExpiresAt: req.ExpiresAt,
+    })
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    // The key is returned this once; only its hash is stored.
+    created.Key = key
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(created)
+}
+
+func (h *APIKeyHandler) handleAPIKeyByID(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet:
+        key, err := h.apiKeyStore.GetAPIKey(r.Context(), id)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        json.NewEncoder(w).Encode(key)
+    case http.MethodDelete:
+        if _, err := h.apiKeyStore.GetAPIKey(r.Context(), id); err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        revoked, err := h.apiKeyStore.RevokeAPIKey(r.Context(), id)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(revoked)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func validateAPIKeyRequest(req createAPIKeyRequest) error {
+    if strings.TrimSpace(req.Name) == "" {
+        return errors.New("name is required")
+    }
+    if len(req.Scopes) == 0 {
+        return errors.New("at least one scope is required")
+    }
+    for _, scope := range req.Scopes {
+        if err := auth.ValidateScope(scope); err != nil {
+            return err
+        }
+    }
+    if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+        return errors.New("expires_at must be in the future")
+    }
+    return nil
+}
@@ -3,27 +3,55 @@
 package handlers
 
 import (
+    "context"
     "encoding/json"
+    "errors"
     "net/http"
+    "time"
 
     "bookstore/internal/auth"
+    "bookstore/internal/ratelimit"
     "github.com/gorilla/mux"
 )
 
+const (
+    // oidcStateCookie ties the provider's callback to the browser that
+    // started the login.
+    oidcStateCookie = "bookstore_oidc_state"
+    oidcExchangeTimeout = 10 * time.Second
+)
+
 type AuthHandler struct {
     JWTManager *auth.JWTManager
+    limiter    *ratelimit.Limiter
+    oidc       *auth.OIDCProvider
 }
 
 
-func NewAuthHandler(jwtManager *auth.JWTManager) *AuthHandler {
+// NewAuthHandler takes a nil oidcProvider when single sign-on is not
+// configured.
+func NewAuthHandler(jwtManager *auth.JWTManager, limiter *ratelimit.Limiter, oidcProvider *auth.OIDCProvider) *AuthHandler {
     return &AuthHandler{
         JWTManager: jwtManager,
+        limiter:    limiter,
+        oidc:       oidcProvider,
     }
 }
 
 func (h *AuthHandler) RegisterRoutes(router *mux.Router) {
 
     router.HandleFunc("/login", h.handleLogin).Methods("POST")
+
+    if h.oidc != nil {
+        router.HandleFunc("/auth/oidc/login", h.handleOIDCLogin).Methods("GET")
+        router.HandleFunc("/auth/oidc/callback", h.handleOIDCCallback).Methods("GET")
+    }
+}
+
+// RegisterWellKnownRoutes serves the JWKS at the root of the server, where
+// other services expect to find it.
+func (h *AuthHandler) RegisterWellKnownRoutes(router *mux.Router) {
+    router.HandleFunc("/.well-known/jwks.json", h.handleJWKS).Methods("GET")
 }
 
 type loginRequest struct {
@@ -37,9 +65,13 @@ func (h *AuthHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "invalid JSON", http.StatusBadRequest)
         return
     }
+    if !h.limiter.AllowLogin(w, r, req.Username) {
+        return
+    }
 
     if req.Username == "admin" && req.Password == "password" {
-        token, err := h.JWTManager.Generate(req.Username)
+        h.limiter.LoginSucceeded(r, req.Username)
+        token, err := h.JWTManager.Generate(req.Username, []string{auth.RoleAdmin})
         if err != nil {
             http.Error(w, "failed to generate token", http.StatusInternalServerError)
             return
@@ -47,6 +79,80 @@ func (h *AuthHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
         w.Header().Set("Content-Type", "application/json")
         w.Write([]byte(`{"token":"` + token + `"}`))
     } else {
+        h.limiter.LoginFailed(r, req.Username)
         http.Error(w, "invalid credentials", http.StatusUnauthorized)
     }
 }
+
+// handleJWKS publishes the public signing keys. Verifiers cache the set,
+// so a new key should be published for at least max-age before it signs.
+func (h *AuthHandler) handleJWKS(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Cache-Control", "public, max-age=300")
+    json.NewEncoder(w).Encode(h.JWTManager.JWKS())
+}
+
+// handleOIDCLogin sends the browser to the identity provider.
+func (h *AuthHandler) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+    authURL, state, err := h.oidc.AuthCodeURL()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+        return
+    }
+    http.SetCookie(w, &http.Cookie{
+        Name:     oidcStateCookie,
+        Value:    state,
+        Path:     "/api/auth/oidc",
+        MaxAge:   int(auth.OIDCLoginTimeout.Seconds()),
+        HttpOnly: true,
+        Secure:   r.TLS != nil,
+        // Lax, so that the cookie comes back on the provider's redirect.
+        SameSite: http.SameSiteLaxMode,
+    })
+    http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleOIDCCallback finishes the login and answers like handleLogin,
+// with a bookstore token carrying the roles mapped from the user's groups.
+func (h *AuthHandler) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+    if errCode := query.Get("error"); errCode != "" {
+        http.Error(w, "identity provider error: "+errCode+" "+query.Get("error_description"), http.StatusUnauthorized)
+        return
+    }
+
+    state := query.Get("state")
+    cookie, err := r.Cookie(oidcStateCookie)
+    if err != nil || state == "" || cookie.Value != state {
+        http.Error(w, "login state mismatch", http.StatusBadRequest)
+        return
+    }
+    http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc", MaxAge: -1})
+
+    ctx, cancel := context.WithTimeout(r.Context(), oidcExchangeTimeout)
+    defer cancel()
+    identity, err := h.oidc.Exchange(ctx, state, query.Get("code"))
+    switch {
+    case errors.Is(err, auth.ErrOIDCNoRole):
+        http.Error(w, "user "+identity.Username+" is not allowed to use the bookstore", http.StatusForbidden)
+        return
+    case errors.Is(err, auth.ErrOIDCUnknownState):
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    case err != nil:
+        http.Error(w, err.Error(), http.StatusUnauthorized)
+        return
+    }
+
+    token, err := h.JWTManager.Generate(identity.Username, identity.Roles)
+    if err != nil {
+        http.Error(w, "failed to generate token", http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "token":    token,
+        "username": identity.Username,
+        "roles":    identity.Roles,
+    })
+}
@@ -4,7 +4,6 @@ package handlers
 
 import (
     "encoding/json"
-    "log"
     "net/http"
     "strconv"
 
@@ -34,7 +33,11 @@ func (h *AuthorHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler)
     router.Handle("/authors/{id:[0-9]+}", mw(http.HandlerFunc(h.handleAuthorByID))).
         Methods("GET", "PUT", "DELETE")
 
-    log.Println("Author routes registered at /api/authors")
+    router.Handle("/authors/{id:[0-9]+}/books", mw(http.HandlerFunc(h.listAuthorBooks))).
+        Methods("GET")
+
+    router.Handle("/authors/{id:[0-9]+}/summary", mw(http.HandlerFunc(h.getAuthorSummary))).
+        Methods("GET")
 }
 
 func (h *AuthorHandler) handleAuthors(w http.ResponseWriter, r *http.Request) {
@@ -74,26 +77,18 @@ func (h *AuthorHandler) handleAuthorByID(w http.ResponseWriter, r *http.Request)
 
 
 func (h *AuthorHandler) createAuthor(w http.ResponseWriter, r *http.Request) {
-    log.Println("Attempting to create author...")
-
     var author models.Author
     if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
-        log.Printf("Error decoding request body: %v", err)
         http.Error(w, "Invalid request body", http.StatusBadRequest)
         return
     }
 
-    log.Printf("Decoded author data: %+v", author)
-
     createdAuthor, err := h.authorStore.CreateAuthor(r.Context(), author)
     if err != nil {
-        log.Printf("Error creating author: %v", err)
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
 
-    log.Printf("Successfully created author: %+v", createdAuthor)
-
     w.WriteHeader(http.StatusCreated)
     json.NewEncoder(w).Encode(createdAuthor)
 }
@@ -128,17 +123,15 @@ func (h *AuthorHandler) updateAuthor(w http.ResponseWriter, r *http.Request, id
 
 func (h *AuthorHandler) deleteAuthor(w http.ResponseWriter, r *http.Request, id int) {
 
-    books, err := h.bookStore.ListBooks(r.Context())
+    // Any credit counts, not only as the main author.
+    books, err := h.bookStore.ListBooksByAuthor(r.Context(), id)
     if err != nil {
         http.Error(w, "Failed to retrieve books: "+err.Error(), http.StatusInternalServerError)
         return
     }
-
-    for _, b := range books {
-        if b.Author.ID == id {
-            http.Error(w, "Cannot delete author who still has books", http.StatusConflict)
-            return
-        }
+    if len(books) > 0 {
+        http.Error(w, "Cannot delete author who still has books", http.StatusConflict)
+        return
     }
 
 
@@ -159,3 +152,48 @@ func (h *AuthorHandler) listAuthors(w http.ResponseWriter, r *http.Request) {
     }
     json.NewEncoder(w).Encode(authors)
 }
+
+func (h *AuthorHandler) listAuthorBooks(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid author ID", http.StatusBadRequest)
+        return
+    }
+    if _, err := h.authorStore.GetAuthor(r.Context(), id); err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    books, err := h.bookStore.ListBooksByAuthor(r.Context(), id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(books)
+}
+
+// getAuthorSummary reports an author's titles and sales for the period
+// given by start_date and end_date, by default the last month.
+func (h *AuthorHandler) getAuthorSummary(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid author ID", http.StatusBadRequest)
+        return
+    }
+    start, end, err := parseDateRange(r)
+    if err != nil {
+        http.Error(w, "Invalid date range: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    summary, err := h.authorStore.GetAuthorSummary(r.Context(), id, start, end)
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    json.NewEncoder(w).Encode(summary)
+}
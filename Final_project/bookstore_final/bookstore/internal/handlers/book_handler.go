@@ -4,13 +4,18 @@ package handlers
 
 import (
     "encoding/json"
+    "errors"
+    "fmt"
     "net/http"
+    "net/url"
     "strconv"
+    "strings"
     "time"
 
     "github.com/gorilla/mux"
 
     "bookstore/internal/interfaces"
+    "bookstore/internal/isbn"
     "bookstore/internal/models"
 )
 
@@ -19,7 +24,9 @@ type BookHandler struct {
 }
 
 func NewBookHandler(bookStore interfaces.BookStore) *BookHandler {
-    return &BookHandler{bookStore: bookStore}
+    return &BookHandler{
+        bookStore: bookStore,
+    }
 }
 
 
@@ -73,10 +80,14 @@ func (h *BookHandler) createBook(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "Invalid request body", http.StatusBadRequest)
         return
     }
+    if err := validateBook(&book); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
 
     createdBook, err := h.bookStore.CreateBook(r.Context(), book)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeStoreError(w, err)
         return
     }
     w.WriteHeader(http.StatusCreated)
@@ -98,10 +109,16 @@ func (h *BookHandler) updateBook(w http.ResponseWriter, r *http.Request, id int)
         http.Error(w, "Invalid request body", http.StatusBadRequest)
         return
     }
+    if err := validateBook(&book); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
 
+    // Stock is not part of an update; it changes through the inventory
+    // routes, which record each change in the stock ledger.
     updatedBook, err := h.bookStore.UpdateBook(r.Context(), id, book)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeStoreError(w, err)
         return
     }
     json.NewEncoder(w).Encode(updatedBook)
@@ -125,6 +142,21 @@ func (h *BookHandler) searchBooks(w http.ResponseWriter, r *http.Request) {
         Genres:   query["genres"], 
         MinPrice: parseFloat(query.Get("min_price"), 0),
         MaxPrice: parseFloat(query.Get("max_price"), 0),
+
+        Publisher: query.Get("publisher"),
+        Language:  query.Get("language"),
+        Format:    query.Get("format"),
+        MinPages:  parseInt(query.Get("min_pages"), 0),
+        MaxPages:  parseInt(query.Get("max_pages"), 0),
+        WorkID:    parseInt(query.Get("work_id"), 0),
+    }
+    if s := query.Get("isbn"); s != "" {
+        normalized, err := isbn.Normalize(s)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        criteria.ISBN = normalized
     }
 
 
@@ -161,6 +193,80 @@ func (h *BookHandler) searchBooks(w http.ResponseWriter, r *http.Request) {
 }
 
 
+// validateBook checks the bibliographic fields and normalizes the ISBN,
+// language and format.
+func validateBook(book *models.Book) error {
+    if book.ISBN != "" {
+        normalized, err := isbn.Normalize(book.ISBN)
+        if err != nil {
+            return err
+        }
+        book.ISBN = normalized
+    }
+
+    book.Format = strings.ToLower(strings.TrimSpace(book.Format))
+    switch book.Format {
+    case "", models.FormatHardcover, models.FormatPaperback, models.FormatEbook:
+    default:
+        return fmt.Errorf("format must be %s, %s or %s",
+            models.FormatHardcover, models.FormatPaperback, models.FormatEbook)
+    }
+
+    // Languages are ISO 639 codes, such as "en" or "deu".
+    book.Language = strings.ToLower(strings.TrimSpace(book.Language))
+    if book.Language != "" {
+        if len(book.Language) < 2 || len(book.Language) > 3 || strings.Trim(book.Language, "abcdefghijklmnopqrstuvwxyz") != "" {
+            return errors.New("language must be an ISO 639 code such as en")
+        }
+    }
+
+    if book.Pages < 0 {
+        return errors.New("pages cannot be negative")
+    }
+
+    // Without contributors a new book is credited to its author alone, and
+    // an updated one keeps its contributors.
+    if len(book.Contributors) > 0 {
+        hasAuthor := false
+        seen := make(map[models.Contributor]bool)
+        for i, c := range book.Contributors {
+            c.Role = strings.ToLower(strings.TrimSpace(c.Role))
+            if c.Role == "" {
+                c.Role = models.RoleAuthor
+            }
+            switch c.Role {
+            case models.RoleAuthor, models.RoleEditor, models.RoleTranslator, models.RoleIllustrator:
+            default:
+                return fmt.Errorf("contributor role must be %s, %s, %s or %s",
+                    models.RoleAuthor, models.RoleEditor, models.RoleTranslator, models.RoleIllustrator)
+            }
+            if c.Author.ID <= 0 {
+                return errors.New("every contributor needs an author id")
+            }
+            key := models.Contributor{Author: models.Author{ID: c.Author.ID}, Role: c.Role}
+            if seen[key] {
+                return fmt.Errorf("author %d is listed twice as %s", c.Author.ID, c.Role)
+            }
+            seen[key] = true
+            hasAuthor = hasAuthor || c.Role == models.RoleAuthor
+            book.Contributors[i] = key
+        }
+        if !hasAuthor {
+            return errors.New("contributors must include at least one author")
+        }
+    } else if book.Author.ID <= 0 {
+        return errors.New("author id is required")
+    }
+
+    if book.CoverURL != "" {
+        u, err := url.Parse(book.CoverURL)
+        if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+            return errors.New("cover_url must be an absolute http or https URL")
+        }
+    }
+    return nil
+}
+
 func parseFloat(s string, defaultVal float64) float64 {
     if s == "" {
         return defaultVal
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"testing"
+
+	"bookstore/internal/models"
+)
+
+func TestValidateBookContributors(t *testing.T) {
+	author := func(id int, role string) models.Contributor {
+		return models.Contributor{Author: models.Author{ID: id}, Role: role}
+	}
+	tests := []struct {
+		name         string
+		book         models.Book
+		wantErr      bool
+		contributors []models.Contributor
+	}{
+		{"author only", models.Book{Author: models.Author{ID: 1}}, false, nil},
+		{"no author", models.Book{}, true, nil},
+		{"role defaults to author", models.Book{Contributors: []models.Contributor{author(1, "")}}, false,
+			[]models.Contributor{author(1, models.RoleAuthor)}},
+		{"role normalised", models.Book{Contributors: []models.Contributor{author(1, "author"), author(2, " Translator ")}}, false,
+			[]models.Contributor{author(1, models.RoleAuthor), author(2, models.RoleTranslator)}},
+		{"same author in two roles", models.Book{Contributors: []models.Contributor{author(1, "author"), author(1, "editor")}}, false,
+			[]models.Contributor{author(1, models.RoleAuthor), author(1, models.RoleEditor)}},
+		{"listed twice", models.Book{Contributors: []models.Contributor{author(1, "author"), author(1, "Author")}}, true, nil},
+		{"no author credit", models.Book{Contributors: []models.Contributor{author(1, "editor")}}, true, nil},
+		{"unknown role", models.Book{Contributors: []models.Contributor{author(1, "narrator")}}, true, nil},
+		{"missing author id", models.Book{Contributors: []models.Contributor{author(0, "author")}}, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := tt.book
+			err := validateBook(&book)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateBook = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.contributors == nil {
+				return
+			}
+			if len(book.Contributors) != len(tt.contributors) {
+				t.Fatalf("contributors = %v, want %v", book.Contributors, tt.contributors)
+			}
+			for i, c := range tt.contributors {
+				if book.Contributors[i] != c {
+					t.Errorf("contributor %d = %v, want %v", i, book.Contributors[i], c)
+				}
+			}
+		})
+	}
+}
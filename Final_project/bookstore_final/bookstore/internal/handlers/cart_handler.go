@@ -0,0 +1,268 @@
+package handlers
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+type CartHandler struct {
+    cartStore  interfaces.CartStore
+    orderStore interfaces.OrderStore
+    bookStore  interfaces.BookStore
+}
+
+func NewCartHandler(cartStore interfaces.CartStore, orderStore interfaces.OrderStore, bookStore interfaces.BookStore) *CartHandler {
+    return &CartHandler{
+        cartStore:  cartStore,
+        orderStore: orderStore,
+        bookStore:  bookStore,
+    }
+}
+
+func (h *CartHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/carts", mw(http.HandlerFunc(h.handleCarts))).
+        Methods("POST")
+
+    router.Handle("/carts/{id:[0-9]+}", mw(http.HandlerFunc(h.handleCartByID))).
+        Methods("GET", "DELETE")
+
+    router.Handle("/carts/{id:[0-9]+}/items", mw(http.HandlerFunc(h.handleAddItem))).
+        Methods("POST")
+
+    router.Handle("/carts/{id:[0-9]+}/items/{bookId:[0-9]+}", mw(http.HandlerFunc(h.handleCartItem))).
+        Methods("PUT", "DELETE")
+
+    router.Handle("/carts/{id:[0-9]+}/checkout", mw(http.HandlerFunc(h.handleCheckout))).
+        Methods("POST")
+}
+
+type cartRequest struct {
+    CustomerID int `json:"customer_id"`
+}
+
+type checkoutRequest struct {
+    CouponCode        string `json:"coupon_code"`
+    ShippingAddressID int    `json:"shipping_address_id"`
+    BillingAddressID  int    `json:"billing_address_id"`
+}
+
+type cartItemRequest struct {
+    BookID   int `json:"book_id"`
+    Quantity int `json:"quantity"`
+}
+
+// handleCarts returns the customer's active cart, creating it if needed.
+func (h *CartHandler) handleCarts(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    var req cartRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    if req.CustomerID <= 0 {
+        http.Error(w, "customer_id is required", http.StatusBadRequest)
+        return
+    }
+
+    cart, err := h.cartStore.GetOrCreateCart(r.Context(), req.CustomerID)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(cart)
+}
+
+func (h *CartHandler) handleCartByID(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid cart ID", http.StatusBadRequest)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet:
+        cart, ok := h.loadCart(w, r, id)
+        if !ok {
+            return
+        }
+        json.NewEncoder(w).Encode(cart)
+    case http.MethodDelete:
+        if err := h.cartStore.DeleteCart(r.Context(), id); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *CartHandler) handleAddItem(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid cart ID", http.StatusBadRequest)
+        return
+    }
+
+    var req cartItemRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    if req.Quantity <= 0 {
+        http.Error(w, "Quantity must be positive", http.StatusBadRequest)
+        return
+    }
+    if _, err := h.bookStore.GetBook(r.Context(), req.BookID); err != nil {
+        http.Error(w, "Book not found: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    cart, err := h.cartStore.AddItem(r.Context(), id, req.BookID, req.Quantity)
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    json.NewEncoder(w).Encode(cart)
+}
+
+func (h *CartHandler) handleCartItem(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    vars := mux.Vars(r)
+    id, err := strconv.Atoi(vars["id"])
+    if err != nil {
+        http.Error(w, "Invalid cart ID", http.StatusBadRequest)
+        return
+    }
+    bookID, err := strconv.Atoi(vars["bookId"])
+    if err != nil {
+        http.Error(w, "Invalid book ID", http.StatusBadRequest)
+        return
+    }
+
+    var cart models.Cart
+    switch r.Method {
+    case http.MethodPut:
+        var req cartItemRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if req.Quantity < 0 {
+            http.Error(w, "Quantity must not be negative", http.StatusBadRequest)
+            return
+        }
+        if req.Quantity > 0 {
+            if _, err := h.bookStore.GetBook(r.Context(), bookID); err != nil {
+                http.Error(w, "Book not found: "+err.Error(), http.StatusBadRequest)
+                return
+            }
+        }
+        cart, err = h.cartStore.SetItemQuantity(r.Context(), id, bookID, req.Quantity)
+    case http.MethodDelete:
+        cart, err = h.cartStore.RemoveItem(r.Context(), id, bookID)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    json.NewEncoder(w).Encode(cart)
+}
+
+// handleCheckout turns the cart into an order. The order store prices the
+// items and checks stock; on failure the cart is left active so the
+// customer can fix it and retry.
+func (h *CartHandler) handleCheckout(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid cart ID", http.StatusBadRequest)
+        return
+    }
+
+    // The body is optional; it carries a coupon code and the addresses to
+    // use instead of the customer's defaults.
+    var req checkoutRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+
+    if _, ok := h.loadCart(w, r, id); !ok {
+        return
+    }
+    if err := h.cartStore.BeginCheckout(r.Context(), id); err != nil {
+        writeStoreError(w, err)
+        return
+    }
+
+    // The items are read once the cart is locked, so that none added in
+    // the meantime are left out of the order.
+    cart, err := h.cartStore.GetCart(r.Context(), id)
+    if err != nil {
+        h.cartStore.AbortCheckout(r.Context(), id)
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if len(cart.Items) == 0 {
+        h.cartStore.AbortCheckout(r.Context(), id)
+        http.Error(w, "Cart is empty", http.StatusBadRequest)
+        return
+    }
+
+    order := models.Order{
+        CartID:            id,
+        Customer:          models.Customer{ID: cart.CustomerID},
+        CouponCode:        req.CouponCode,
+        ShippingAddressID: req.ShippingAddressID,
+        BillingAddressID:  req.BillingAddressID,
+    }
+    for _, item := range cart.Items {
+        order.Items = append(order.Items, models.OrderItem{
+            Book:     item.Book,
+            Quantity: item.Quantity,
+        })
+    }
+
+    // The order store closes the cart in the order's transaction.
+    createdOrder, err := h.orderStore.CreateOrder(r.Context(), order)
+    if err != nil {
+        h.cartStore.AbortCheckout(r.Context(), id)
+        writeStoreError(w, err)
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(createdOrder)
+}
+
+func (h *CartHandler) loadCart(w http.ResponseWriter, r *http.Request, id int) (models.Cart, bool) {
+    cart, err := h.cartStore.GetCart(r.Context(), id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return cart, false
+    }
+    if cart.Status == models.CartActive && !cart.ExpiresAt.After(time.Now()) {
+        writeStoreError(w, models.ErrCartExpired)
+        return cart, false
+    }
+    return cart, true
+}
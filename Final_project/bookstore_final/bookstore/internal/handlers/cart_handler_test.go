@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+// memoryCarts is a CartStore for checkouts.
+type memoryCarts struct {
+	interfaces.CartStore
+	carts map[int]*models.Cart
+}
+
+func (s *memoryCarts) GetCart(ctx context.Context, id int) (models.Cart, error) {
+	cart, ok := s.carts[id]
+	if !ok {
+		return models.Cart{}, fmt.Errorf("cart not found with id: %d", id)
+	}
+	return *cart, nil
+}
+
+func (s *memoryCarts) BeginCheckout(ctx context.Context, id int) error {
+	if s.carts[id].Status != models.CartActive {
+		return models.ErrCartNotActive
+	}
+	s.carts[id].Status = models.CartCheckingOut
+	return nil
+}
+
+func (s *memoryCarts) AbortCheckout(ctx context.Context, id int) error {
+	if s.carts[id].Status == models.CartCheckingOut {
+		s.carts[id].Status = models.CartActive
+	}
+	return nil
+}
+
+// checkoutOrders is an OrderStore that creates orders and closes their
+// cart together, or fails with err. beforeCreate runs first, standing in
+// for whatever happens while the order is being priced.
+type checkoutOrders struct {
+	interfaces.OrderStore
+	carts        *memoryCarts
+	err          error
+	beforeCreate func()
+	created      []models.Order
+}
+
+func (s *checkoutOrders) CreateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	if s.beforeCreate != nil {
+		s.beforeCreate()
+	}
+	if s.err != nil {
+		return order, s.err
+	}
+	cart := s.carts.carts[order.CartID]
+	if cart.Status != models.CartCheckingOut {
+		return order, fmt.Errorf("CreateOrder: %w: cart %d", models.ErrCartNotActive, order.CartID)
+	}
+	order.ID = len(s.created) + 1
+	cart.Status = models.CartCheckedOut
+	cart.OrderID = &order.ID
+	s.created = append(s.created, order)
+	return order, nil
+}
+
+func TestCheckout(t *testing.T) {
+	item := models.CartItem{Book: models.Book{ID: 1}, Quantity: 2}
+	tests := []struct {
+		name       string
+		status     string
+		items      []models.CartItem
+		expiresIn  time.Duration
+		err        error
+		released   bool
+		wantStatus int
+		wantCart   string
+		wantOrders int
+	}{
+		{"checked out", models.CartActive, []models.CartItem{item}, time.Hour, nil, false, http.StatusCreated, models.CartCheckedOut, 1},
+		{"empty cart", models.CartActive, nil, time.Hour, nil, false, http.StatusBadRequest, models.CartActive, 0},
+		{"expired cart", models.CartActive, []models.CartItem{item}, -time.Hour, nil, false, http.StatusGone, models.CartActive, 0},
+		{"already checked out", models.CartCheckedOut, []models.CartItem{item}, time.Hour, nil, false, http.StatusConflict, models.CartCheckedOut, 0},
+		{"out of stock", models.CartActive, []models.CartItem{item}, time.Hour, fmt.Errorf("%w for book: x", models.ErrInsufficientStock), false, http.StatusBadRequest, models.CartActive, 0},
+		{"invalid coupon", models.CartActive, []models.CartItem{item}, time.Hour, fmt.Errorf("%w: code", models.ErrInvalidCoupon), false, http.StatusBadRequest, models.CartActive, 0},
+		{"released as stale meanwhile", models.CartActive, []models.CartItem{item}, time.Hour, nil, true, http.StatusConflict, models.CartActive, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carts := &memoryCarts{carts: map[int]*models.Cart{
+				1: {ID: 1, CustomerID: 7, Status: tt.status, Items: tt.items, ExpiresAt: time.Now().Add(tt.expiresIn)},
+			}}
+			orders := &checkoutOrders{carts: carts, err: tt.err}
+			if tt.released {
+				orders.beforeCreate = func() { carts.carts[1].Status = models.CartActive }
+			}
+			router := mux.NewRouter()
+			NewCartHandler(carts, orders, nil).
+				RegisterRoutes(router, func(next http.Handler) http.Handler { return next })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/carts/1/checkout", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := carts.carts[1].Status; got != tt.wantCart {
+				t.Errorf("cart status = %q, want %q", got, tt.wantCart)
+			}
+			if len(orders.created) != tt.wantOrders {
+				t.Errorf("orders = %d, want %d", len(orders.created), tt.wantOrders)
+			}
+			for _, order := range orders.created {
+				if order.CartID != 1 || order.Customer.ID != 7 || len(order.Items) != 1 || order.Items[0].Quantity != 2 {
+					t.Errorf("order = %+v, want cart 1's items for customer 7", order)
+				}
+			}
+		})
+	}
+}
@@ -105,15 +105,21 @@ func (h *CustomerHandler) updateCustomer(w http.ResponseWriter, r *http.Request,
 
     updatedCustomer, err := h.customerStore.UpdateCustomer(r.Context(), id, customer)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeStoreError(w, err)
         return
     }
     json.NewEncoder(w).Encode(updatedCustomer)
 }
 
+// deleteCustomer only deletes customers without orders; the others are
+// erased through the privacy endpoints instead.
 func (h *CustomerHandler) deleteCustomer(w http.ResponseWriter, r *http.Request, id int) {
+    if _, err := h.customerStore.GetCustomer(r.Context(), id); err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
     if err := h.customerStore.DeleteCustomer(r.Context(), id); err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeStoreError(w, err)
         return
     }
     w.WriteHeader(http.StatusNoContent)
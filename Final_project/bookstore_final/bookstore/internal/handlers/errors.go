@@ -0,0 +1,45 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "bookstore/internal/models"
+)
+
+// writeStoreError maps the domain errors returned by the stores onto HTTP
+// status codes. Anything unrecognised is a 500.
+func writeStoreError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, models.ErrInsufficientStock),
+        errors.Is(err, models.ErrInvalidCoupon),
+        errors.Is(err, models.ErrInvalidReturn),
+        errors.Is(err, models.ErrUnknownAddress),
+        errors.Is(err, models.ErrUnknownWork),
+        errors.Is(err, models.ErrUnknownAuthor):
+        http.Error(w, err.Error(), http.StatusBadRequest)
+    case errors.Is(err, models.ErrBookNotFound),
+        errors.Is(err, models.ErrAuthorNotFound):
+        http.Error(w, err.Error(), http.StatusNotFound)
+    case errors.Is(err, models.ErrCartExpired),
+        errors.Is(err, models.ErrCustomerErased):
+        http.Error(w, err.Error(), http.StatusGone)
+    case errors.Is(err, models.ErrCartNotActive),
+        errors.Is(err, models.ErrOrderNotPayable),
+        errors.Is(err, models.ErrOrderNotReturnable),
+        errors.Is(err, models.ErrOrderHasReturns),
+        errors.Is(err, models.ErrReturnDecided),
+        errors.Is(err, models.ErrRefundClaimed),
+        errors.Is(err, models.ErrOrderNotCancellable),
+        errors.Is(err, models.ErrOrderCancelled),
+        errors.Is(err, models.ErrOrderStatusChange),
+        errors.Is(err, models.ErrOrderHasPayment),
+        errors.Is(err, models.ErrPaymentSettled),
+        errors.Is(err, models.ErrEmailTaken),
+        errors.Is(err, models.ErrCustomerHasOrders),
+        errors.Is(err, models.ErrISBNTaken):
+        http.Error(w, err.Error(), http.StatusConflict)
+    default:
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
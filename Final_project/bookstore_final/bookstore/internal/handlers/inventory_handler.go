@@ -0,0 +1,156 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+type InventoryHandler struct {
+    inventoryStore interfaces.InventoryStore
+}
+
+func NewInventoryHandler(inventoryStore interfaces.InventoryStore) *InventoryHandler {
+    return &InventoryHandler{inventoryStore: inventoryStore}
+}
+
+func (h *InventoryHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/books/{id:[0-9]+}/restock", mw(http.HandlerFunc(h.handleRestock))).
+        Methods("POST")
+
+    router.Handle("/books/{id:[0-9]+}/adjustments", mw(http.HandlerFunc(h.handleAdjustment))).
+        Methods("POST")
+
+    router.Handle("/books/{id:[0-9]+}/stock-movements", mw(http.HandlerFunc(h.handleMovements))).
+        Methods("GET")
+
+    router.Handle("/books/{id:[0-9]+}/reconcile", mw(http.HandlerFunc(h.handleReconcile))).
+        Methods("POST")
+
+    router.Handle("/inventory/low-stock", mw(http.HandlerFunc(h.handleLowStock))).
+        Methods("GET")
+}
+
+type stockRequest struct {
+    Quantity int    `json:"quantity"`
+    Change   int    `json:"change"`
+    Note     string `json:"note"`
+}
+
+func (h *InventoryHandler) handleRestock(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid book ID", http.StatusBadRequest)
+        return
+    }
+
+    var req stockRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    if req.Quantity <= 0 {
+        http.Error(w, "Restock quantity must be positive", http.StatusBadRequest)
+        return
+    }
+
+    h.recordMovement(w, r, models.StockMovement{
+        BookID: id,
+        Change: req.Quantity,
+        Reason: models.MovementRestock,
+        Note:   req.Note,
+    })
+}
+
+func (h *InventoryHandler) handleAdjustment(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid book ID", http.StatusBadRequest)
+        return
+    }
+
+    var req stockRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    if req.Change == 0 {
+        http.Error(w, "Adjustment change must not be zero", http.StatusBadRequest)
+        return
+    }
+    if req.Note == "" {
+        http.Error(w, "Adjustment note is required", http.StatusBadRequest)
+        return
+    }
+
+    h.recordMovement(w, r, models.StockMovement{
+        BookID: id,
+        Change: req.Change,
+        Reason: models.MovementAdjustment,
+        Note:   req.Note,
+    })
+}
+
+func (h *InventoryHandler) recordMovement(w http.ResponseWriter, r *http.Request, movement models.StockMovement) {
+    created, err := h.inventoryStore.RecordMovement(r.Context(), movement)
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(created)
+}
+
+func (h *InventoryHandler) handleMovements(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid book ID", http.StatusBadRequest)
+        return
+    }
+
+    movements, err := h.inventoryStore.ListMovements(r.Context(), id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(movements)
+}
+
+func (h *InventoryHandler) handleReconcile(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid book ID", http.StatusBadRequest)
+        return
+    }
+
+    book, err := h.inventoryStore.ReconcileStock(r.Context(), id)
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    json.NewEncoder(w).Encode(book)
+}
+
+func (h *InventoryHandler) handleLowStock(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    books, err := h.inventoryStore.ListLowStock(r.Context())
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(books)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+// memoryLedger is an InventoryStore that keeps stock levels and their
+// movements, refusing changes that would take stock below zero.
+type memoryLedger struct {
+	interfaces.InventoryStore
+	stock     map[int]int
+	movements []models.StockMovement
+}
+
+func (s *memoryLedger) RecordMovement(ctx context.Context, m models.StockMovement) (models.StockMovement, error) {
+	stock, ok := s.stock[m.BookID]
+	if !ok {
+		return m, fmt.Errorf("%w with id: %d", models.ErrBookNotFound, m.BookID)
+	}
+	m.Balance = stock + m.Change
+	if m.Balance < 0 {
+		return m, fmt.Errorf("%w for book: %d", models.ErrInsufficientStock, m.BookID)
+	}
+	s.stock[m.BookID] = m.Balance
+	m.ID = len(s.movements) + 1
+	s.movements = append(s.movements, m)
+	return m, nil
+}
+
+func TestStockMovements(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantStock  int
+		reason     string
+	}{
+		{"restock", "/books/1/restock", `{"quantity":20,"note":"delivery"}`, http.StatusCreated, 25, models.MovementRestock},
+		{"restock nothing", "/books/1/restock", `{"quantity":0}`, http.StatusBadRequest, 5, ""},
+		{"write off", "/books/1/adjustments", `{"change":-2,"note":"damaged"}`, http.StatusCreated, 3, models.MovementAdjustment},
+		{"write off more than held", "/books/1/adjustments", `{"change":-6,"note":"lost"}`, http.StatusBadRequest, 5, ""},
+		{"adjustment without note", "/books/1/adjustments", `{"change":2}`, http.StatusBadRequest, 5, ""},
+		{"zero adjustment", "/books/1/adjustments", `{"change":0,"note":"count"}`, http.StatusBadRequest, 5, ""},
+		{"unknown book", "/books/2/restock", `{"quantity":1}`, http.StatusNotFound, 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := &memoryLedger{stock: map[int]int{1: 5}}
+			router := mux.NewRouter()
+			NewInventoryHandler(ledger).
+				RegisterRoutes(router, func(next http.Handler) http.Handler { return next })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if ledger.stock[1] != tt.wantStock {
+				t.Errorf("stock = %d, want %d", ledger.stock[1], tt.wantStock)
+			}
+			if tt.reason == "" {
+				if len(ledger.movements) != 0 {
+					t.Errorf("movements = %d, want none", len(ledger.movements))
+				}
+				return
+			}
+			var m models.StockMovement
+			if err := json.NewDecoder(w.Body).Decode(&m); err != nil {
+				t.Fatal(err)
+			}
+			if m.Reason != tt.reason || m.Balance != tt.wantStock {
+				t.Errorf("movement = %s to %d, want %s to %d", m.Reason, m.Balance, tt.reason, tt.wantStock)
+			}
+		})
+	}
+}
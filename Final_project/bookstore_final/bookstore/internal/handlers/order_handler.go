@@ -81,28 +81,13 @@ func (h *OrderHandler) createOrder(w http.ResponseWriter, r *http.Request) {
     }
 
 
-    for _, item := range order.Items {
-        book, err := h.bookStore.GetBook(r.Context(), item.Book.ID)
-        if err != nil {
-            http.Error(w, "Book not found: "+err.Error(), http.StatusBadRequest)
-            return
-        }
-        if book.Stock < item.Quantity {
-            http.Error(w, "Insufficient stock for book: "+book.Title, http.StatusBadRequest)
-            return
-        }
-
-        book.Stock -= item.Quantity
-        _, err = h.bookStore.UpdateBook(r.Context(), book.ID, book)
-        if err != nil {
-            http.Error(w, "Failed to update book stock: "+err.Error(), http.StatusInternalServerError)
-            return
-        }
+    if !h.validateItems(w, r, order.Items) {
+        return
     }
 
     createdOrder, err := h.orderStore.CreateOrder(r.Context(), order)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeStoreError(w, err)
         return
     }
 
@@ -127,74 +112,30 @@ func (h *OrderHandler) updateOrder(w http.ResponseWriter, r *http.Request, id in
     }
 
 
-    existingOrder, err := h.orderStore.GetOrder(r.Context(), id)
-    if err != nil {
+    if _, err := h.orderStore.GetOrder(r.Context(), id); err != nil {
         http.Error(w, err.Error(), http.StatusNotFound)
         return
     }
-
-
-    existingItems := make(map[int]models.OrderItem)
-    for _, item := range existingOrder.Items {
-        existingItems[item.Book.ID] = item
-    }
-
-
-    for _, item := range order.Items {
-        book, err := h.bookStore.GetBook(r.Context(), item.Book.ID)
-        if err != nil {
-            http.Error(w, "Book not found: "+err.Error(), http.StatusBadRequest)
-            return
-        }
-        existingQty := 0
-        if existingItem, ok := existingItems[item.Book.ID]; ok {
-            existingQty = existingItem.Quantity
-        }
-        stockChange := item.Quantity - existingQty
-        if book.Stock < stockChange {
-            http.Error(w, "Insufficient stock for book: "+book.Title, http.StatusBadRequest)
-            return
-        }
-        book.Stock -= stockChange
-        _, err = h.bookStore.UpdateBook(r.Context(), book.ID, book)
-        if err != nil {
-            http.Error(w, "Failed to update book stock: "+err.Error(), http.StatusInternalServerError)
-            return
-        }
+    if !h.validateItems(w, r, order.Items) {
+        return
     }
 
     updatedOrder, err := h.orderStore.UpdateOrder(r.Context(), id, order)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeStoreError(w, err)
         return
     }
     json.NewEncoder(w).Encode(updatedOrder)
 }
 
 func (h *OrderHandler) deleteOrder(w http.ResponseWriter, r *http.Request, id int) {
-    order, err := h.orderStore.GetOrder(r.Context(), id)
-    if err != nil {
+    if _, err := h.orderStore.GetOrder(r.Context(), id); err != nil {
         http.Error(w, err.Error(), http.StatusNotFound)
         return
     }
 
-
-    for _, item := range order.Items {
-        book, err := h.bookStore.GetBook(r.Context(), item.Book.ID)
-        if err != nil {
-            http.Error(w, "Book not found: "+err.Error(), http.StatusInternalServerError)
-            return
-        }
-        book.Stock += item.Quantity
-        _, err = h.bookStore.UpdateBook(r.Context(), book.ID, book)
-        if err != nil {
-            http.Error(w, "Failed to restore book stock: "+err.Error(), http.StatusInternalServerError)
-            return
-        }
-    }
-
     if err := h.orderStore.DeleteOrder(r.Context(), id); err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeStoreError(w, err)
         return
     }
     w.WriteHeader(http.StatusNoContent)
@@ -208,3 +149,19 @@ func (h *OrderHandler) listOrders(w http.ResponseWriter, r *http.Request) {
     }
     json.NewEncoder(w).Encode(orders)
 }
+
+// validateItems checks that every ordered book exists. Stock itself is
+// checked and reserved atomically by the order store.
+func (h *OrderHandler) validateItems(w http.ResponseWriter, r *http.Request, items []models.OrderItem) bool {
+    for _, item := range items {
+        if item.Quantity <= 0 {
+            http.Error(w, "Invalid quantity for book: "+strconv.Itoa(item.Book.ID), http.StatusBadRequest)
+            return false
+        }
+        if _, err := h.bookStore.GetBook(r.Context(), item.Book.ID); err != nil {
+            http.Error(w, "Book not found: "+err.Error(), http.StatusBadRequest)
+            return false
+        }
+    }
+    return true
+}
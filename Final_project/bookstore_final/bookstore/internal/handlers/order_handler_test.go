@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+// refusingOrders is an OrderStore whose updates and deletes fail with err.
+type refusingOrders struct {
+	knownOrders
+	err error
+}
+
+func (s refusingOrders) UpdateOrder(ctx context.Context, id int, order models.Order) (models.Order, error) {
+	if s.err != nil {
+		return order, s.err
+	}
+	return order, nil
+}
+
+func (s refusingOrders) DeleteOrder(ctx context.Context, id int) error {
+	return s.err
+}
+
+// anyBook is a BookStore that finds every book.
+type anyBook struct {
+	interfaces.BookStore
+}
+
+func (anyBook) GetBook(ctx context.Context, id int) (models.Book, error) {
+	return models.Book{ID: id}, nil
+}
+
+func TestOrderGuards(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"update", "PUT", "/orders/1", `{"items":[{"book":{"id":1},"quantity":1}]}`, nil, http.StatusOK},
+		{"status change", "PUT", "/orders/1", `{"status":"paid"}`, fmt.Errorf("%w: order 1", models.ErrOrderStatusChange), http.StatusConflict},
+		{"items of paid order", "PUT", "/orders/1", `{"items":[{"book":{"id":1},"quantity":2}]}`, fmt.Errorf("%w: order 1", models.ErrOrderHasPayment), http.StatusConflict},
+		{"cancelled order", "PUT", "/orders/1", `{}`, fmt.Errorf("%w: order 1", models.ErrOrderCancelled), http.StatusConflict},
+		{"delete", "DELETE", "/orders/1", "", nil, http.StatusNoContent},
+		{"delete paid order", "DELETE", "/orders/1", "", fmt.Errorf("%w: order 1", models.ErrOrderHasPayment), http.StatusConflict},
+		{"delete order with returns", "DELETE", "/orders/1", "", fmt.Errorf("%w: order 1", models.ErrOrderHasReturns), http.StatusConflict},
+		{"unknown order", "DELETE", "/orders/2", "", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := refusingOrders{
+				knownOrders: knownOrders{orders: map[int]models.Order{1: {ID: 1}}},
+				err:         tt.err,
+			}
+			router := mux.NewRouter()
+			NewOrderHandler(orders, anyBook{}).
+				RegisterRoutes(router, func(next http.Handler) http.Handler { return next })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
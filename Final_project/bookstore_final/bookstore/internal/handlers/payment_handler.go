@@ -0,0 +1,258 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+    "bookstore/internal/payments"
+)
+
+type PaymentHandler struct {
+    paymentStore interfaces.PaymentStore
+    orderStore   interfaces.OrderStore
+    provider     payments.Provider
+    timeout      time.Duration
+}
+
+// NewPaymentHandler charges orders through provider. Each provider call is
+// given at most timeout to answer.
+func NewPaymentHandler(paymentStore interfaces.PaymentStore, orderStore interfaces.OrderStore, provider payments.Provider, timeout time.Duration) *PaymentHandler {
+    return &PaymentHandler{
+        paymentStore: paymentStore,
+        orderStore:   orderStore,
+        provider:     provider,
+        timeout:      timeout,
+    }
+}
+
+func (h *PaymentHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/orders/{id:[0-9]+}/pay", mw(http.HandlerFunc(h.handlePay))).
+        Methods("POST")
+
+    router.Handle("/orders/{id:[0-9]+}/payments", mw(http.HandlerFunc(h.handleListPayments))).
+        Methods("GET")
+
+    // Providers cannot present a JWT; webhooks are authenticated by the
+    // provider's own signature instead.
+    router.HandleFunc("/payments/webhook", h.handleWebhook).
+        Methods("POST")
+}
+
+type payRequest struct {
+    PaymentMethod string `json:"payment_method"`
+}
+
+// handlePay charges the order. Repeating the call for an order that is
+// already paid returns the captured payment instead of charging again, and
+// finishes a payment that was authorized but never recorded as captured.
+func (h *PaymentHandler) handlePay(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    orderID, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid order ID", http.StatusBadRequest)
+        return
+    }
+
+    var req payRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+
+    if _, err := h.orderStore.GetOrder(r.Context(), orderID); err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    payment, created, err := h.paymentStore.BeginPayment(r.Context(), orderID, h.provider.Name())
+    if err == nil && !created && payment.Status == models.PaymentPending {
+        // An attempt that stopped before recording the provider's answer
+        // leaves its payment pending. Once it is clearly dead, give up on it
+        // and start again.
+        abandoned, err := h.paymentStore.AbandonPayment(r.Context(), payment.ID, time.Now().Add(-2*h.timeout))
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        if abandoned {
+            payment, created, err = h.paymentStore.BeginPayment(r.Context(), orderID, h.provider.Name())
+            if err != nil {
+                writeStoreError(w, err)
+                return
+            }
+        }
+    }
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    if !created {
+        switch payment.Status {
+        case models.PaymentCaptured:
+            json.NewEncoder(w).Encode(payment)
+        case models.PaymentAuthorized:
+            h.capture(w, r, payment, http.StatusOK)
+        default:
+            http.Error(w, "A payment for this order is already in progress", http.StatusConflict)
+        }
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+    defer cancel()
+
+    auth, err := h.provider.Authorize(ctx, payments.AuthorizeRequest{
+        OrderID: orderID,
+        Amount:  payment.Amount,
+        Method:  req.PaymentMethod,
+    })
+    if err != nil {
+        // Nothing was captured, so the attempt can be failed even when the
+        // provider did not answer.
+        h.fail(w, r, payment, err)
+        return
+    }
+    authorized, err := h.paymentStore.UpdatePayment(r.Context(), payment.ID, models.PaymentAuthorized, auth.Reference, "")
+    if err != nil {
+        // Release the funds; the pending payment is abandoned later.
+        voidCtx, cancel := context.WithTimeout(r.Context(), h.timeout)
+        defer cancel()
+        h.provider.Void(voidCtx, auth.Reference)
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    h.capture(w, r, authorized, http.StatusCreated)
+}
+
+// capture captures an authorized payment and marks the order paid. A capture
+// that times out may still have gone through, so the payment is left
+// authorized for the provider's webhook or the next call to settle. One the
+// provider refuses has its authorization voided.
+func (h *PaymentHandler) capture(w http.ResponseWriter, r *http.Request, payment models.Payment, status int) {
+    ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+    defer cancel()
+
+    if _, err := h.provider.Capture(ctx, payment.Reference, payment.Amount); err != nil {
+        if errors.Is(err, payments.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+            http.Error(w, err.Error(), http.StatusGatewayTimeout)
+            return
+        }
+        h.void(w, r, payment, err)
+        return
+    }
+
+    // If this fails the payment stays authorized, and the captured webhook
+    // or the next call records the capture.
+    payment, err := h.paymentStore.CompletePayment(r.Context(), payment.ID, "")
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(payment)
+}
+
+// void cancels the authorization of a payment whose capture failed, then
+// records the failure. When the provider does not confirm the void, money
+// may have been taken, so the payment stays authorized.
+func (h *PaymentHandler) void(w http.ResponseWriter, r *http.Request, payment models.Payment, captureErr error) {
+    ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+    defer cancel()
+
+    if _, err := h.provider.Void(ctx, payment.Reference); err != nil {
+        http.Error(w, fmt.Sprintf("%v; void failed: %v", captureErr, err), http.StatusBadGateway)
+        return
+    }
+    h.fail(w, r, payment, captureErr)
+}
+
+// fail records a provider error on the payment, which frees the order for
+// another attempt, and reports it to the client.
+func (h *PaymentHandler) fail(w http.ResponseWriter, r *http.Request, payment models.Payment, providerErr error) {
+    if _, err := h.paymentStore.UpdatePayment(r.Context(), payment.ID, models.PaymentFailed, "", providerErr.Error()); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    switch {
+    case errors.Is(providerErr, payments.ErrDeclined):
+        http.Error(w, providerErr.Error(), http.StatusPaymentRequired)
+    case errors.Is(providerErr, payments.ErrTimeout):
+        http.Error(w, providerErr.Error(), http.StatusGatewayTimeout)
+    default:
+        http.Error(w, providerErr.Error(), http.StatusBadGateway)
+    }
+}
+
+func (h *PaymentHandler) handleListPayments(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    orderID, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid order ID", http.StatusBadRequest)
+        return
+    }
+
+    list, err := h.paymentStore.ListPayments(r.Context(), orderID)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(list)
+}
+
+// handleWebhook applies asynchronous status updates sent by the provider.
+// Events that do not change anything are acknowledged so the provider stops
+// retrying them.
+func (h *PaymentHandler) handleWebhook(w http.ResponseWriter, r *http.Request) {
+    event, err := h.provider.ParseWebhook(r)
+    if err != nil {
+        if errors.Is(err, payments.ErrInvalidSignature) {
+            http.Error(w, err.Error(), http.StatusUnauthorized)
+            return
+        }
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    payment, err := h.paymentStore.GetPaymentByReference(r.Context(), h.provider.Name(), event.Reference)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    switch event.Type {
+    case payments.EventCaptured:
+        if payment.Status == models.PaymentPending || payment.Status == models.PaymentAuthorized {
+            _, err = h.paymentStore.CompletePayment(r.Context(), payment.ID, "")
+        }
+    case payments.EventFailed:
+        if payment.Status == models.PaymentPending || payment.Status == models.PaymentAuthorized {
+            _, err = h.paymentStore.UpdatePayment(r.Context(), payment.ID, models.PaymentFailed, "", event.Reason)
+        }
+    case payments.EventRefunded:
+        if payment.Status == models.PaymentCaptured {
+            _, err = h.paymentStore.UpdatePayment(r.Context(), payment.ID, models.PaymentRefunded, "", "")
+        }
+    }
+    // Events that do not fit the payment's state are acknowledged without a
+    // change, so the provider stops retrying them.
+    if err != nil && !errors.Is(err, models.ErrPaymentSettled) {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+	"bookstore/internal/payments"
+)
+
+// memoryPayments is a PaymentStore that keeps the store's state rules:
+// one active payment per order, and completion only from pending or
+// authorized.
+type memoryPayments struct {
+	mu       sync.Mutex
+	payments []models.Payment
+	amount   float64
+}
+
+func (s *memoryPayments) BeginPayment(ctx context.Context, orderID int, provider string) (models.Payment, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.payments {
+		if p.OrderID == orderID && p.Status != models.PaymentFailed && p.Status != models.PaymentRefunded {
+			return p, false, nil
+		}
+	}
+	p := models.Payment{
+		ID:       len(s.payments) + 1,
+		OrderID:  orderID,
+		Provider: provider,
+		Amount:   s.amount,
+		Status:   models.PaymentPending,
+	}
+	s.payments = append(s.payments, p)
+	return p, true, nil
+}
+
+func (s *memoryPayments) UpdatePayment(ctx context.Context, id int, status, reference, reason string) (models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := &s.payments[id-1]
+	p.Status = status
+	if reference != "" {
+		p.Reference = reference
+	}
+	p.FailureReason = reason
+	return *p, nil
+}
+
+func (s *memoryPayments) CompletePayment(ctx context.Context, id int, reference string) (models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := &s.payments[id-1]
+	switch p.Status {
+	case models.PaymentPending, models.PaymentAuthorized:
+		p.Status = models.PaymentCaptured
+	case models.PaymentCaptured:
+	default:
+		return *p, fmt.Errorf("%w: payment %d is %s", models.ErrPaymentSettled, id, p.Status)
+	}
+	return *p, nil
+}
+
+func (s *memoryPayments) AbandonPayment(ctx context.Context, id int, before time.Time) (bool, error) {
+	return false, nil
+}
+
+func (s *memoryPayments) GetPayment(ctx context.Context, id int) (models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.payments[id-1], nil
+}
+
+func (s *memoryPayments) GetPaymentByReference(ctx context.Context, provider, reference string) (models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.payments {
+		if p.Provider == provider && p.Reference == reference {
+			return p, nil
+		}
+	}
+	return models.Payment{}, fmt.Errorf("payment not found with reference: %s", reference)
+}
+
+func (s *memoryPayments) ListPayments(ctx context.Context, orderID int) ([]models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list []models.Payment
+	for _, p := range s.payments {
+		if p.OrderID == orderID {
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+// knownOrders is an OrderStore that only finds orders by ID.
+type knownOrders struct {
+	interfaces.OrderStore
+	orders map[int]models.Order
+}
+
+func (s knownOrders) GetOrder(ctx context.Context, id int) (models.Order, error) {
+	order, ok := s.orders[id]
+	if !ok {
+		return order, fmt.Errorf("order not found with id: %d", id)
+	}
+	return order, nil
+}
+
+func newPaymentRouter(store *memoryPayments, provider payments.Provider) *mux.Router {
+	orders := knownOrders{orders: map[int]models.Order{1: {ID: 1, Status: models.OrderPending}}}
+	router := mux.NewRouter()
+	NewPaymentHandler(store, orders, provider, 50*time.Millisecond).
+		RegisterRoutes(router, func(next http.Handler) http.Handler { return next })
+	return router
+}
+
+func pay(router http.Handler, method string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"payment_method":"` + method + `"}`)
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/orders/1/pay", body))
+	return w
+}
+
+func TestPay(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		wantStatus int
+		payment    string
+	}{
+		{"captured", payments.FakeTokenSuccess, http.StatusCreated, models.PaymentCaptured},
+		{"declined", payments.FakeTokenDecline, http.StatusPaymentRequired, models.PaymentFailed},
+		{"provider timeout", payments.FakeTokenTimeout, http.StatusGatewayTimeout, models.PaymentFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryPayments{amount: 25}
+			w := pay(newPaymentRouter(store, payments.NewFakeProvider("secret")), tt.method)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := store.payments[0].Status; got != tt.payment {
+				t.Errorf("payment status = %q, want %q", got, tt.payment)
+			}
+		})
+	}
+}
+
+func TestPayRepeated(t *testing.T) {
+	store := &memoryPayments{amount: 25}
+	router := newPaymentRouter(store, payments.NewFakeProvider("secret"))
+
+	if w := pay(router, payments.FakeTokenSuccess); w.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if w := pay(router, payments.FakeTokenSuccess); w.Code != http.StatusOK {
+		t.Errorf("second status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if len(store.payments) != 1 {
+		t.Errorf("payments = %d, want 1", len(store.payments))
+	}
+
+	// A failed attempt frees the order for another one.
+	store = &memoryPayments{amount: 25}
+	router = newPaymentRouter(store, payments.NewFakeProvider("secret"))
+	pay(router, payments.FakeTokenDecline)
+	if w := pay(router, payments.FakeTokenSuccess); w.Code != http.StatusCreated {
+		t.Errorf("retry status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if len(store.payments) != 2 {
+		t.Errorf("payments = %d, want 2", len(store.payments))
+	}
+}
+
+func TestPayAuthorizedPayment(t *testing.T) {
+	provider := payments.NewFakeProvider("secret")
+	auth, err := provider.Authorize(context.Background(), payments.AuthorizeRequest{OrderID: 1, Amount: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &memoryPayments{payments: []models.Payment{
+		{ID: 1, OrderID: 1, Provider: "fake", Amount: 25, Status: models.PaymentAuthorized, Reference: auth.Reference},
+	}}
+
+	w := pay(newPaymentRouter(store, provider), payments.FakeTokenSuccess)
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if got := store.payments[0].Status; got != models.PaymentCaptured {
+		t.Errorf("payment status = %q, want %q", got, models.PaymentCaptured)
+	}
+}
+
+func TestPaymentWebhook(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		from  string
+		want  string
+	}{
+		{"captured while pending", payments.EventCaptured, models.PaymentPending, models.PaymentCaptured},
+		{"captured while authorized", payments.EventCaptured, models.PaymentAuthorized, models.PaymentCaptured},
+		{"captured again", payments.EventCaptured, models.PaymentCaptured, models.PaymentCaptured},
+		{"late capture of failed payment", payments.EventCaptured, models.PaymentFailed, models.PaymentFailed},
+		{"late capture of refunded payment", payments.EventCaptured, models.PaymentRefunded, models.PaymentRefunded},
+		{"failed while authorized", payments.EventFailed, models.PaymentAuthorized, models.PaymentFailed},
+		{"failed after capture", payments.EventFailed, models.PaymentCaptured, models.PaymentCaptured},
+		{"refunded after capture", payments.EventRefunded, models.PaymentCaptured, models.PaymentRefunded},
+		{"refunded while pending", payments.EventRefunded, models.PaymentPending, models.PaymentPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := payments.NewFakeProvider("secret")
+			store := &memoryPayments{payments: []models.Payment{
+				{ID: 1, OrderID: 1, Provider: "fake", Amount: 25, Status: tt.from, Reference: "ref_1"},
+			}}
+			body := `{"type":"` + tt.event + `","reference":"ref_1"}`
+			r := httptest.NewRequest("POST", "/payments/webhook", strings.NewReader(body))
+			r.Header.Set(payments.FakeSignatureHeader, provider.SignWebhook([]byte(body)))
+			w := httptest.NewRecorder()
+			newPaymentRouter(store, provider).ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			if got := store.payments[0].Status; got != tt.want {
+				t.Errorf("payment status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentWebhookSignature(t *testing.T) {
+	body := `{"type":"payment.captured","reference":"ref_1"}`
+	r := httptest.NewRequest("POST", "/payments/webhook", strings.NewReader(body))
+	r.Header.Set(payments.FakeSignatureHeader, "00")
+	w := httptest.NewRecorder()
+	newPaymentRouter(&memoryPayments{}, payments.NewFakeProvider("secret")).ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/auth"
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+// PrivacyHandler answers data protection requests: exporting everything held
+// about a customer, and erasing their personal data.
+type PrivacyHandler struct {
+    customerStore interfaces.CustomerStore
+    accountStore  interfaces.AccountStore
+    addressStore  interfaces.AddressStore
+    orderStore    interfaces.OrderStore
+    paymentStore  interfaces.PaymentStore
+    returnStore   interfaces.ReturnStore
+    auditStore    interfaces.AuditStore
+}
+
+func NewPrivacyHandler(
+    customerStore interfaces.CustomerStore,
+    accountStore interfaces.AccountStore,
+    addressStore interfaces.AddressStore,
+    orderStore interfaces.OrderStore,
+    paymentStore interfaces.PaymentStore,
+    returnStore interfaces.ReturnStore,
+    auditStore interfaces.AuditStore,
+) *PrivacyHandler {
+    return &PrivacyHandler{
+        customerStore: customerStore,
+        accountStore:  accountStore,
+        addressStore:  addressStore,
+        orderStore:    orderStore,
+        paymentStore:  paymentStore,
+        returnStore:   returnStore,
+        auditStore:    auditStore,
+    }
+}
+
+func (h *PrivacyHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/customers/{id:[0-9]+}/export", mw(http.HandlerFunc(h.exportCustomer))).
+        Methods("GET")
+
+    router.Handle("/customers/{id:[0-9]+}/erase", mw(http.HandlerFunc(h.eraseCustomer))).
+        Methods("POST")
+
+    router.Handle("/account/export", mw(forAccount(h.customerStore, h.export))).
+        Methods("GET")
+}
+
+func (h *PrivacyHandler) exportCustomer(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+        return
+    }
+    h.export(w, r, id)
+}
+
+// export writes the customer's data as a JSON attachment. The export is
+// recorded in the audit log first, so it appears in its own archive.
+func (h *PrivacyHandler) export(w http.ResponseWriter, r *http.Request, customerID int) {
+    ctx := r.Context()
+    customer, err := h.customerStore.GetCustomer(ctx, customerID)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    _, err = h.auditStore.RecordAudit(ctx, models.AuditEntry{
+        CustomerID: customerID,
+        Action:     models.AuditCustomerExported,
+        Actor:      auth.Username(ctx),
+    })
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    export := models.CustomerExport{
+        ExportedAt: time.Now(),
+        Customer:   customer,
+    }
+    // A customer created by staff has no account.
+    if account, err := h.accountStore.GetAccountByCustomer(ctx, customerID); err == nil {
+        export.Account = &account
+    }
+    if export.Addresses, err = h.addressStore.ListAddresses(ctx, customerID); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if export.Orders, err = h.orderStore.ListCustomerOrders(ctx, customerID); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    for _, order := range export.Orders {
+        payments, err := h.paymentStore.ListPayments(ctx, order.ID)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        export.Payments = append(export.Payments, payments...)
+
+        returns, err := h.returnStore.ListReturns(ctx, order.ID, "")
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        export.Returns = append(export.Returns, returns...)
+    }
+    if export.Audit, err = h.auditStore.ListAudit(ctx, customerID); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Disposition",
+        fmt.Sprintf(`attachment; filename="customer-%d-export.json"`, customerID))
+    json.NewEncoder(w).Encode(export)
+}
+
+// eraseCustomer anonymises a customer. Their orders stay, for accounting and
+// sales reports, but no longer say who placed them or where they went.
+func (h *PrivacyHandler) eraseCustomer(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+        return
+    }
+    if _, err := h.customerStore.GetCustomer(r.Context(), id); err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    customer, err := h.customerStore.EraseCustomer(r.Context(), id, auth.Username(r.Context()))
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    json.NewEncoder(w).Encode(customer)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+type PromotionHandler struct {
+    promotionStore interfaces.PromotionStore
+}
+
+func NewPromotionHandler(promotionStore interfaces.PromotionStore) *PromotionHandler {
+    return &PromotionHandler{promotionStore: promotionStore}
+}
+
+func (h *PromotionHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/promotions", mw(http.HandlerFunc(h.handlePromotions))).
+        Methods("GET", "POST")
+
+    router.Handle("/promotions/{id:[0-9]+}", mw(http.HandlerFunc(h.handlePromotionByID))).
+        Methods("GET", "PUT", "DELETE")
+}
+
+func (h *PromotionHandler) handlePromotions(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    switch r.Method {
+    case http.MethodGet:
+        h.listPromotions(w, r)
+    case http.MethodPost:
+        h.createPromotion(w, r)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *PromotionHandler) handlePromotionByID(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet:
+        h.getPromotion(w, r, id)
+    case http.MethodPut:
+        h.updatePromotion(w, r, id)
+    case http.MethodDelete:
+        h.deletePromotion(w, r, id)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *PromotionHandler) createPromotion(w http.ResponseWriter, r *http.Request) {
+    promotion := models.Promotion{Active: true}
+    if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    if err := validatePromotion(promotion); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    created, err := h.promotionStore.CreatePromotion(r.Context(), promotion)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(created)
+}
+
+func (h *PromotionHandler) getPromotion(w http.ResponseWriter, r *http.Request, id int) {
+    promotion, err := h.promotionStore.GetPromotion(r.Context(), id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    json.NewEncoder(w).Encode(promotion)
+}
+
+func (h *PromotionHandler) updatePromotion(w http.ResponseWriter, r *http.Request, id int) {
+    var promotion models.Promotion
+    if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return
+    }
+    if err := validatePromotion(promotion); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    updated, err := h.promotionStore.UpdatePromotion(r.Context(), id, promotion)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(updated)
+}
+
+func (h *PromotionHandler) deletePromotion(w http.ResponseWriter, r *http.Request, id int) {
+    if err := h.promotionStore.DeletePromotion(r.Context(), id); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PromotionHandler) listPromotions(w http.ResponseWriter, r *http.Request) {
+    promotions, err := h.promotionStore.ListPromotions(r.Context())
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(promotions)
+}
+
+func validatePromotion(p models.Promotion) error {
+    if p.Name == "" {
+        return errors.New("Promotion name is required")
+    }
+    switch p.Kind {
+    case models.PromotionPercentage:
+        if p.Value <= 0 || p.Value > 100 {
+            return errors.New("Percentage value must be between 0 and 100")
+        }
+    case models.PromotionFixed:
+        if p.Value <= 0 {
+            return errors.New("Fixed value must be positive")
+        }
+    default:
+        return errors.New("Promotion kind must be \"percentage\" or \"fixed\"")
+    }
+    if p.MinOrder < 0 || p.MaxUses < 0 || p.MaxUsesPerCustomer < 0 {
+        return errors.New("Limits must not be negative")
+    }
+    if p.StartsAt != nil && p.ExpiresAt != nil && !p.ExpiresAt.After(*p.StartsAt) {
+        return errors.New("expires_at must be after starts_at")
+    }
+    return nil
+}
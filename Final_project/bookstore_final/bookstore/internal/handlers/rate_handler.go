@@ -0,0 +1,180 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+// RateHandler manages the tax rule and shipping rate tables.
+type RateHandler struct {
+    rateStore interfaces.RateStore
+}
+
+func NewRateHandler(rateStore interfaces.RateStore) *RateHandler {
+    return &RateHandler{rateStore: rateStore}
+}
+
+func (h *RateHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/tax-rules", mw(http.HandlerFunc(h.handleTaxRules))).
+        Methods("GET", "POST")
+
+    router.Handle("/tax-rules/{id:[0-9]+}", mw(http.HandlerFunc(h.handleTaxRuleByID))).
+        Methods("PUT", "DELETE")
+
+    router.Handle("/shipping-rates", mw(http.HandlerFunc(h.handleShippingRates))).
+        Methods("GET", "POST")
+
+    router.Handle("/shipping-rates/{id:[0-9]+}", mw(http.HandlerFunc(h.handleShippingRateByID))).
+        Methods("PUT", "DELETE")
+}
+
+func (h *RateHandler) handleTaxRules(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    switch r.Method {
+    case http.MethodGet:
+        rules, err := h.rateStore.ListTaxRules(r.Context())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(rules)
+    case http.MethodPost:
+        var rule models.TaxRule
+        if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if rule.Country == "" || rule.Rate < 0 || rule.Rate >= 1 {
+            http.Error(w, "country is required and rate must be a fraction between 0 and 1", http.StatusBadRequest)
+            return
+        }
+        created, err := h.rateStore.CreateTaxRule(r.Context(), rule)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(created)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *RateHandler) handleTaxRuleByID(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid tax rule ID", http.StatusBadRequest)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodPut:
+        var rule models.TaxRule
+        if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if rule.Country == "" || rule.Rate < 0 || rule.Rate >= 1 {
+            http.Error(w, "country is required and rate must be a fraction between 0 and 1", http.StatusBadRequest)
+            return
+        }
+        updated, err := h.rateStore.UpdateTaxRule(r.Context(), id, rule)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(updated)
+    case http.MethodDelete:
+        if err := h.rateStore.DeleteTaxRule(r.Context(), id); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *RateHandler) handleShippingRates(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    switch r.Method {
+    case http.MethodGet:
+        rates, err := h.rateStore.ListShippingRates(r.Context())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(rates)
+    case http.MethodPost:
+        var rate models.ShippingRate
+        if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if !validShippingRate(rate) {
+            http.Error(w, "Shipping rate values must not be negative", http.StatusBadRequest)
+            return
+        }
+        created, err := h.rateStore.CreateShippingRate(r.Context(), rate)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(created)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *RateHandler) handleShippingRateByID(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid shipping rate ID", http.StatusBadRequest)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodPut:
+        var rate models.ShippingRate
+        if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if !validShippingRate(rate) {
+            http.Error(w, "Shipping rate values must not be negative", http.StatusBadRequest)
+            return
+        }
+        updated, err := h.rateStore.UpdateShippingRate(r.Context(), id, rate)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(updated)
+    case http.MethodDelete:
+        if err := h.rateStore.DeleteShippingRate(r.Context(), id); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func validShippingRate(rate models.ShippingRate) bool {
+    return rate.MaxWeightGrams >= 0 && rate.MaxItems >= 0 &&
+        rate.BaseFee >= 0 && rate.PerItem >= 0 && rate.PerKg >= 0 && rate.FreeOver >= 0
+}
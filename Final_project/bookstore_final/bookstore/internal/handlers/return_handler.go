@@ -0,0 +1,274 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+    "bookstore/internal/payments"
+)
+
+type ReturnHandler struct {
+    returnStore  interfaces.ReturnStore
+    paymentStore interfaces.PaymentStore
+    orderStore   interfaces.OrderStore
+    provider     payments.Provider
+    timeout      time.Duration
+}
+
+// NewReturnHandler manages return requests; refunds of orders paid through
+// provider are sent to it, waiting at most timeout for an answer.
+func NewReturnHandler(returnStore interfaces.ReturnStore, paymentStore interfaces.PaymentStore, orderStore interfaces.OrderStore, provider payments.Provider, timeout time.Duration) *ReturnHandler {
+    return &ReturnHandler{
+        returnStore:  returnStore,
+        paymentStore: paymentStore,
+        orderStore:   orderStore,
+        provider:     provider,
+        timeout:      timeout,
+    }
+}
+
+func (h *ReturnHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/orders/{id:[0-9]+}/returns", mw(http.HandlerFunc(h.handleOrderReturns))).
+        Methods("GET", "POST")
+
+    router.Handle("/orders/{id:[0-9]+}/refunds", mw(http.HandlerFunc(h.handleOrderRefunds))).
+        Methods("GET")
+
+    router.Handle("/returns", mw(http.HandlerFunc(h.handleListReturns))).
+        Methods("GET")
+
+    router.Handle("/returns/{id:[0-9]+}", mw(http.HandlerFunc(h.handleGetReturn))).
+        Methods("GET")
+
+    router.Handle("/returns/{id:[0-9]+}/approve", mw(http.HandlerFunc(h.handleApprove))).
+        Methods("POST")
+
+    router.Handle("/returns/{id:[0-9]+}/reject", mw(http.HandlerFunc(h.handleReject))).
+        Methods("POST")
+
+    router.Handle("/refunds/{id:[0-9]+}/retry", mw(http.HandlerFunc(h.handleRetryRefund))).
+        Methods("POST")
+}
+
+type decisionRequest struct {
+    Note string `json:"note"`
+}
+
+func (h *ReturnHandler) handleOrderReturns(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    orderID, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid order ID", http.StatusBadRequest)
+        return
+    }
+    if _, err := h.orderStore.GetOrder(r.Context(), orderID); err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet:
+        returns, err := h.returnStore.ListReturns(r.Context(), orderID, "")
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(returns)
+    case http.MethodPost:
+        var ret models.ReturnRequest
+        if err := json.NewDecoder(r.Body).Decode(&ret); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if len(ret.Items) == 0 {
+            http.Error(w, "At least one item is required", http.StatusBadRequest)
+            return
+        }
+        ret.OrderID = orderID
+        created, err := h.returnStore.CreateReturn(r.Context(), ret)
+        if err != nil {
+            writeStoreError(w, err)
+            return
+        }
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(created)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *ReturnHandler) handleOrderRefunds(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    orderID, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid order ID", http.StatusBadRequest)
+        return
+    }
+    refunds, err := h.returnStore.ListRefunds(r.Context(), orderID)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(refunds)
+}
+
+// handleListReturns lists return requests, e.g. ?status=requested for the
+// requests waiting for a decision.
+func (h *ReturnHandler) handleListReturns(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    returns, err := h.returnStore.ListReturns(r.Context(), 0, r.URL.Query().Get("status"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(returns)
+}
+
+func (h *ReturnHandler) handleGetReturn(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid return ID", http.StatusBadRequest)
+        return
+    }
+    ret, err := h.returnStore.GetReturn(r.Context(), id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    json.NewEncoder(w).Encode(ret)
+}
+
+// handleApprove restocks the returned books and refunds them. A refund the
+// provider fails to accept is reported on the return and can be retried.
+func (h *ReturnHandler) handleApprove(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, req, ok := h.decision(w, r)
+    if !ok {
+        return
+    }
+
+    ret, err := h.returnStore.ApproveReturn(r.Context(), id, req.Note)
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    if ret.Refund != nil && ret.Refund.Status == models.RefundPending {
+        refund, err := h.sendRefund(r.Context(), *ret.Refund)
+        if err != nil {
+            writeStoreError(w, err)
+            return
+        }
+        ret.Refund = &refund
+    }
+    json.NewEncoder(w).Encode(ret)
+}
+
+func (h *ReturnHandler) handleReject(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, req, ok := h.decision(w, r)
+    if !ok {
+        return
+    }
+
+    ret, err := h.returnStore.RejectReturn(r.Context(), id, req.Note)
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    json.NewEncoder(w).Encode(ret)
+}
+
+// decision reads the return ID and the optional staff note.
+func (h *ReturnHandler) decision(w http.ResponseWriter, r *http.Request) (int, decisionRequest, bool) {
+    var req decisionRequest
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid return ID", http.StatusBadRequest)
+        return 0, req, false
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        return 0, req, false
+    }
+    return id, req, true
+}
+
+func (h *ReturnHandler) handleRetryRefund(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid refund ID", http.StatusBadRequest)
+        return
+    }
+    refund, err := h.returnStore.GetRefund(r.Context(), id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    if refund.Status == models.RefundCompleted {
+        json.NewEncoder(w).Encode(refund)
+        return
+    }
+
+    refund, err = h.sendRefund(r.Context(), refund)
+    if err != nil {
+        writeStoreError(w, err)
+        return
+    }
+    json.NewEncoder(w).Encode(refund)
+}
+
+// sendRefund asks the provider to refund the payment and records the
+// outcome. The refund is claimed first, so concurrent calls cannot both
+// send it. Only errors claiming or recording it are returned; a refusal by
+// the provider is stored on the refund.
+//
+// A refund the provider did not answer for may have gone through, so it
+// stays sending. Once its claim is stale it can be sent again under the
+// same key, which the provider answers without refunding twice.
+func (h *ReturnHandler) sendRefund(ctx context.Context, refund models.Refund) (models.Refund, error) {
+    refund, err := h.returnStore.ClaimRefund(ctx, refund.ID, time.Now().Add(-2*h.timeout))
+    if err != nil {
+        return refund, err
+    }
+    if refund.PaymentID == nil {
+        return h.returnStore.CompleteRefund(ctx, refund.ID, "")
+    }
+    payment, err := h.paymentStore.GetPayment(ctx, *refund.PaymentID)
+    if err != nil {
+        return refund, err
+    }
+
+    providerCtx, cancel := context.WithTimeout(ctx, h.timeout)
+    defer cancel()
+    _, err = h.provider.Refund(providerCtx, payment.Reference, refundKey(refund), refund.Amount)
+    if errors.Is(err, payments.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+        return refund, nil
+    }
+    if err != nil {
+        return h.returnStore.FailRefund(ctx, refund.ID, err.Error())
+    }
+    return h.returnStore.CompleteRefund(ctx, refund.ID, payment.Reference)
+}
+
+// refundKey identifies a refund to the provider across retries.
+func refundKey(refund models.Refund) string {
+    return "refund_" + strconv.Itoa(refund.ID)
+}
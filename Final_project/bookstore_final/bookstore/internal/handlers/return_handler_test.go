@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+	"bookstore/internal/payments"
+)
+
+// memoryRefunds is a ReturnStore that only keeps refunds, with the
+// store's claiming rules.
+type memoryRefunds struct {
+	interfaces.ReturnStore
+	mu        sync.Mutex
+	refunds   map[int]models.Refund
+	claimedAt map[int]time.Time
+}
+
+func (s *memoryRefunds) GetRefund(ctx context.Context, id int) (models.Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refund, ok := s.refunds[id]
+	if !ok {
+		return refund, fmt.Errorf("refund not found with id: %d", id)
+	}
+	return refund, nil
+}
+
+func (s *memoryRefunds) ClaimRefund(ctx context.Context, id int, stale time.Time) (models.Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refund := s.refunds[id]
+	switch {
+	case refund.Status == models.RefundPending, refund.Status == models.RefundFailed:
+	case refund.Status == models.RefundSending && !s.claimedAt[id].After(stale):
+	default:
+		return refund, fmt.Errorf("%w: refund %d", models.ErrRefundClaimed, id)
+	}
+	refund.Status = models.RefundSending
+	s.refunds[id] = refund
+	s.claimedAt[id] = time.Now()
+	return refund, nil
+}
+
+func (s *memoryRefunds) CompleteRefund(ctx context.Context, id int, reference string) (models.Refund, error) {
+	return s.settle(id, models.RefundCompleted, reference, "")
+}
+
+func (s *memoryRefunds) FailRefund(ctx context.Context, id int, reason string) (models.Refund, error) {
+	return s.settle(id, models.RefundFailed, "", reason)
+}
+
+func (s *memoryRefunds) settle(id int, status, reference, reason string) (models.Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refund := s.refunds[id]
+	refund.Status = status
+	refund.Reference = reference
+	refund.FailureReason = reason
+	s.refunds[id] = refund
+	return refund, nil
+}
+
+// unansweredRefunds passes refunds on to the provider but loses the answer
+// to the first one, as a provider timeout would.
+type unansweredRefunds struct {
+	*payments.FakeProvider
+	calls int
+}
+
+func (p *unansweredRefunds) Refund(ctx context.Context, reference, key string, amount float64) (payments.Result, error) {
+	p.calls++
+	res, err := p.FakeProvider.Refund(ctx, reference, key, amount)
+	if p.calls == 1 {
+		return payments.Result{}, payments.ErrTimeout
+	}
+	return res, err
+}
+
+// capturedPayment sets up a provider holding a captured payment of 30 and
+// the store records for it.
+func capturedPayment(t *testing.T) (*payments.FakeProvider, *memoryPayments) {
+	provider := payments.NewFakeProvider("secret")
+	auth, err := provider.Authorize(context.Background(), payments.AuthorizeRequest{OrderID: 1, Amount: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Capture(context.Background(), auth.Reference, 30); err != nil {
+		t.Fatal(err)
+	}
+	return provider, &memoryPayments{payments: []models.Payment{
+		{ID: 1, OrderID: 1, Provider: "fake", Amount: 30, Status: models.PaymentCaptured, Reference: auth.Reference},
+	}}
+}
+
+func newRefundRouter(refunds *memoryRefunds, paymentStore *memoryPayments, provider payments.Provider) *mux.Router {
+	router := mux.NewRouter()
+	NewReturnHandler(refunds, paymentStore, knownOrders{}, provider, time.Second).
+		RegisterRoutes(router, func(next http.Handler) http.Handler { return next })
+	return router
+}
+
+func retryRefund(router http.Handler) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/refunds/1/retry", nil))
+	return w
+}
+
+func TestRetryRefund(t *testing.T) {
+	paymentID := 1
+	tests := []struct {
+		name       string
+		status     string
+		amount     float64
+		wantStatus int
+		want       string
+	}{
+		{"failed refund", models.RefundFailed, 10, http.StatusOK, models.RefundCompleted},
+		{"pending refund", models.RefundPending, 10, http.StatusOK, models.RefundCompleted},
+		{"completed refund", models.RefundCompleted, 10, http.StatusOK, models.RefundCompleted},
+		{"refund still sending", models.RefundSending, 10, http.StatusConflict, models.RefundSending},
+		{"refused by provider", models.RefundFailed, 40, http.StatusOK, models.RefundFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, paymentStore := capturedPayment(t)
+			refunds := &memoryRefunds{
+				refunds:   map[int]models.Refund{1: {ID: 1, OrderID: 1, PaymentID: &paymentID, Amount: tt.amount, Status: tt.status}},
+				claimedAt: map[int]time.Time{1: time.Now()},
+			}
+			w := retryRefund(newRefundRouter(refunds, paymentStore, provider))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := refunds.refunds[1].Status; got != tt.want {
+				t.Errorf("refund status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefundTimeout(t *testing.T) {
+	fake, paymentStore := capturedPayment(t)
+	provider := &unansweredRefunds{FakeProvider: fake}
+	paymentID := 1
+	refunds := &memoryRefunds{
+		refunds:   map[int]models.Refund{1: {ID: 1, OrderID: 1, PaymentID: &paymentID, Amount: 20, Status: models.RefundPending}},
+		claimedAt: map[int]time.Time{},
+	}
+	router := newRefundRouter(refunds, paymentStore, provider)
+
+	retryRefund(router)
+	if got := refunds.refunds[1].Status; got != models.RefundSending {
+		t.Fatalf("after timeout refund status = %q, want %q", got, models.RefundSending)
+	}
+	if w := retryRefund(router); w.Code != http.StatusConflict {
+		t.Errorf("early retry status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// Once the claim is stale the refund is asked about again. The first
+	// refund went through, so a second payout of 20 would exceed the 30
+	// captured and be declined.
+	refunds.claimedAt[1] = time.Now().Add(-time.Minute)
+	if w := retryRefund(router); w.Code != http.StatusOK {
+		t.Errorf("stale retry status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if got := refunds.refunds[1].Status; got != models.RefundCompleted {
+		t.Errorf("refund status = %q, want %q", got, models.RefundCompleted)
+	}
+	if provider.calls != 2 {
+		t.Errorf("provider calls = %d, want 2", provider.calls)
+	}
+}
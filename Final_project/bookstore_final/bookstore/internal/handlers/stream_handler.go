@@ -0,0 +1,165 @@
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/events"
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+const (
+    streamHeartbeat   = 15 * time.Second
+    streamReplayBatch = 500
+)
+
+// streamTypes are the events sent on the stream when the client does not
+// choose.
+var streamTypes = []string{
+    events.OrderCreated,
+    events.OrderStatusChanged,
+    events.StockChanged,
+    events.StockLow,
+    events.ReportGenerated,
+}
+
+type StreamHandler struct {
+    broker      *events.Broker
+    outboxStore interfaces.OutboxStore
+}
+
+func NewStreamHandler(broker *events.Broker, outboxStore interfaces.OutboxStore) *StreamHandler {
+    return &StreamHandler{broker: broker, outboxStore: outboxStore}
+}
+
+func (h *StreamHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/stream", mw(http.HandlerFunc(h.handleStream))).
+        Methods("GET")
+}
+
+// handleStream sends events as Server-Sent Events. The SSE id of each event
+// is its outbox ID, so a client reconnecting with Last-Event-ID (or
+// ?last_event_id=) first receives the events it missed. ?types= takes a
+// comma-separated list of event types.
+func (h *StreamHandler) handleStream(w http.ResponseWriter, r *http.Request) {
+    types, err := streamFilter(r.URL.Query().Get("types"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    lastID := r.Header.Get("Last-Event-ID")
+    if lastID == "" {
+        lastID = r.URL.Query().Get("last_event_id")
+    }
+    var after int64
+    if lastID != "" {
+        after, err = strconv.ParseInt(lastID, 10, 64)
+        if err != nil || after < 0 {
+            http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+            return
+        }
+    }
+
+    // Listen before replaying, so nothing published meanwhile is missed.
+    live, cancel := h.broker.Listen()
+    defer cancel()
+
+    // The stream outlives the server's write timeout.
+    rc := http.NewResponseController(w)
+    if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+        http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    w.Header().Set("X-Accel-Buffering", "no")
+    w.WriteHeader(http.StatusOK)
+
+    send := func(event models.Event) error {
+        if !types[event.Type] {
+            return nil
+        }
+        _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Payload)
+        return err
+    }
+
+    if lastID != "" {
+        for {
+            missed, err := h.outboxStore.EventsAfter(r.Context(), after, streamReplayBatch)
+            if err != nil {
+                fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+                return
+            }
+            for _, event := range missed {
+                if err := send(event); err != nil {
+                    return
+                }
+                after = event.ID
+            }
+            if len(missed) < streamReplayBatch {
+                break
+            }
+        }
+    }
+    if err := rc.Flush(); err != nil {
+        return
+    }
+
+    heartbeat := time.NewTicker(streamHeartbeat)
+    defer heartbeat.Stop()
+
+    for {
+        select {
+        case <-r.Context().Done():
+            return
+        case event, ok := <-live:
+            if !ok {
+                // Dropped for falling behind; the client reconnects and
+                // catches up with Last-Event-ID.
+                return
+            }
+            if event.ID <= after {
+                continue
+            }
+            if err := send(event); err != nil {
+                return
+            }
+            if err := rc.Flush(); err != nil {
+                return
+            }
+        case <-heartbeat.C:
+            if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+                return
+            }
+            if err := rc.Flush(); err != nil {
+                return
+            }
+        }
+    }
+}
+
+func streamFilter(param string) (map[string]bool, error) {
+    selected := streamTypes
+    if param != "" {
+        selected = strings.Split(param, ",")
+    }
+
+    types := make(map[string]bool)
+    for _, t := range selected {
+        t = strings.TrimSpace(t)
+        if !events.Known(t) {
+            return nil, fmt.Errorf("Unknown event type: %s", t)
+        }
+        types[t] = true
+    }
+    return types, nil
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/url"
+    "strconv"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/events"
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+type WebhookHandler struct {
+    webhookStore interfaces.WebhookStore
+}
+
+func NewWebhookHandler(webhookStore interfaces.WebhookStore) *WebhookHandler {
+    return &WebhookHandler{webhookStore: webhookStore}
+}
+
+func (h *WebhookHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/webhooks", mw(http.HandlerFunc(h.handleWebhooks))).
+        Methods("GET", "POST")
+
+    router.Handle("/webhooks/{id:[0-9]+}", mw(http.HandlerFunc(h.handleWebhookByID))).
+        Methods("GET", "PUT", "DELETE")
+
+    router.Handle("/webhooks/{id:[0-9]+}/deliveries", mw(http.HandlerFunc(h.handleDeliveries))).
+        Methods("GET")
+
+    router.Handle("/webhooks/{id:[0-9]+}/deliveries/{deliveryId:[0-9]+}/redeliver", mw(http.HandlerFunc(h.handleRedeliver))).
+        Methods("POST")
+}
+
+func (h *WebhookHandler) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    switch r.Method {
+    case http.MethodGet:
+        subs, err := h.webhookStore.ListSubscriptions(r.Context())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        for i := range subs {
+            subs[i].Secret = ""
+        }
+        json.NewEncoder(w).Encode(subs)
+    case http.MethodPost:
+        sub := models.WebhookSubscription{Active: true}
+        if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if err := validateSubscription(sub); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if sub.Secret == "" {
+            secret, err := newWebhookSecret()
+            if err != nil {
+                http.Error(w, err.Error(), http.StatusInternalServerError)
+                return
+            }
+            sub.Secret = secret
+        }
+
+        // The secret is returned this once so the receiver can be set up.
+        created, err := h.webhookStore.CreateSubscription(r.Context(), sub)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(created)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *WebhookHandler) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet:
+        sub, err := h.webhookStore.GetSubscription(r.Context(), id)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        sub.Secret = ""
+        json.NewEncoder(w).Encode(sub)
+    case http.MethodPut:
+        var sub models.WebhookSubscription
+        if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if err := validateSubscription(sub); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        updated, err := h.webhookStore.UpdateSubscription(r.Context(), id, sub)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        updated.Secret = ""
+        json.NewEncoder(w).Encode(updated)
+    case http.MethodDelete:
+        if err := h.webhookStore.DeleteSubscription(r.Context(), id); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+// handleDeliveries returns the delivery log of a subscription, newest first.
+func (h *WebhookHandler) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+        return
+    }
+    if _, err := h.webhookStore.GetSubscription(r.Context(), id); err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    deliveries, err := h.webhookStore.ListDeliveries(r.Context(), id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(deliveries)
+}
+
+func (h *WebhookHandler) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    vars := mux.Vars(r)
+    id, err := strconv.Atoi(vars["id"])
+    if err != nil {
+        http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+        return
+    }
+    deliveryID, err := strconv.Atoi(vars["deliveryId"])
+    if err != nil {
+        http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+        return
+    }
+
+    original, err := h.webhookStore.GetDelivery(r.Context(), deliveryID)
+    if err != nil || original.SubscriptionID != id {
+        http.Error(w, "webhook delivery not found with id: "+vars["deliveryId"], http.StatusNotFound)
+        return
+    }
+
+    delivery, err := h.webhookStore.Redeliver(r.Context(), deliveryID)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusAccepted)
+    json.NewEncoder(w).Encode(delivery)
+}
+
+func validateSubscription(sub models.WebhookSubscription) error {
+    u, err := url.Parse(sub.URL)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        return errors.New("url must be an absolute http or https URL")
+    }
+    if len(sub.EventTypes) == 0 {
+        return errors.New("At least one event type is required")
+    }
+    for _, t := range sub.EventTypes {
+        if t != events.All && !events.Known(t) {
+            return errors.New("Unknown event type: " + t)
+        }
+    }
+    return nil
+}
+
+func newWebhookSecret() (string, error) {
+    b := make([]byte, 32)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return "whsec_" + hex.EncodeToString(b), nil
+}
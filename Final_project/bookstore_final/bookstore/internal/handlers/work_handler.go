@@ -0,0 +1,119 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gorilla/mux"
+
+    "bookstore/internal/interfaces"
+    "bookstore/internal/models"
+)
+
+// WorkHandler manages works, which group the editions of a book. Books join
+// a work through their work_id.
+type WorkHandler struct {
+    workStore interfaces.WorkStore
+    bookStore interfaces.BookStore
+}
+
+func NewWorkHandler(workStore interfaces.WorkStore, bookStore interfaces.BookStore) *WorkHandler {
+    return &WorkHandler{
+        workStore: workStore,
+        bookStore: bookStore,
+    }
+}
+
+func (h *WorkHandler) RegisterRoutes(router *mux.Router, mw func(http.Handler) http.Handler) {
+    router.Handle("/works", mw(http.HandlerFunc(h.handleWorks))).
+        Methods("GET", "POST")
+
+    router.Handle("/works/{id:[0-9]+}", mw(http.HandlerFunc(h.handleWorkByID))).
+        Methods("GET", "PUT", "DELETE")
+}
+
+func (h *WorkHandler) handleWorks(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    switch r.Method {
+    case http.MethodGet:
+        works, err := h.workStore.ListWorks(r.Context())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(works)
+    case http.MethodPost:
+        var work models.Work
+        if err := json.NewDecoder(r.Body).Decode(&work); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if strings.TrimSpace(work.Title) == "" {
+            http.Error(w, "title is required", http.StatusBadRequest)
+            return
+        }
+
+        created, err := h.workStore.CreateWork(r.Context(), work)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(created)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *WorkHandler) handleWorkByID(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        http.Error(w, "Invalid work ID", http.StatusBadRequest)
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet:
+        work, err := h.workStore.GetWork(r.Context(), id)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        work.Editions, err = h.bookStore.SearchBooks(r.Context(), models.SearchCriteria{WorkID: id})
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(work)
+    case http.MethodPut:
+        var work models.Work
+        if err := json.NewDecoder(r.Body).Decode(&work); err != nil {
+            http.Error(w, "Invalid request body", http.StatusBadRequest)
+            return
+        }
+        if strings.TrimSpace(work.Title) == "" {
+            http.Error(w, "title is required", http.StatusBadRequest)
+            return
+        }
+
+        updated, err := h.workStore.UpdateWork(r.Context(), id, work)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        json.NewEncoder(w).Encode(updated)
+    case http.MethodDelete:
+        if err := h.workStore.DeleteWork(r.Context(), id); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
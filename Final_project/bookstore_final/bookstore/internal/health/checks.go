@@ -0,0 +1,47 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// DBCheck pings the database.
+func DBCheck(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// MigrationCheck verifies that the schema version recorded in the
+// schema_migrations table is the one this binary expects.
+func MigrationCheck(db *sql.DB, version int) Check {
+	return func(ctx context.Context) error {
+		var applied sql.NullInt64
+		err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+		if !applied.Valid {
+			return fmt.Errorf("no migrations applied, want version %d", version)
+		}
+		if int(applied.Int64) != version {
+			return fmt.Errorf("schema is at version %d, want %d", applied.Int64, version)
+		}
+		return nil
+	}
+}
+
+// DirWritableCheck verifies that a file can be created in dir.
+func DirWritableCheck(dir string) Check {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return err
+		}
+		name := f.Name()
+		f.Close()
+		return os.Remove(name)
+	}
+}
@@ -0,0 +1,162 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status values reported by the probes.
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusShutdown = "shutting_down"
+)
+
+// A Check reports whether one dependency is usable. It should return once
+// ctx is done.
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of one check in the readiness response.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the body of both probes.
+type Report struct {
+	Status string                 `json:"status"`
+	Uptime string                 `json:"uptime"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker runs the registered checks for the readiness probe.
+type Checker struct {
+	timeout      time.Duration
+	started      time.Time
+	shuttingDown atomic.Bool
+
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// NewChecker creates a Checker that gives each check timeout to complete.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		started: time.Now(),
+		checks:  make(map[string]Check),
+	}
+}
+
+// Register adds a readiness check under name.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// SetShuttingDown makes readiness fail from now on, so that load balancers
+// stop sending traffic while in-flight requests drain.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Run runs every check concurrently and reports whether all of them passed.
+func (c *Checker) Run(ctx context.Context) (map[string]CheckResult, bool) {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	ok := true
+	byName := make(map[string]CheckResult, len(names))
+	for i, name := range names {
+		byName[name] = results[i]
+		if results[i].Status != StatusOK {
+			ok = false
+		}
+	}
+	return byName, ok
+}
+
+func (c *Checker) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := CheckResult{Status: StatusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Liveness reports that the process is up and serving. It deliberately
+// checks no dependencies: a database outage should make the server unready,
+// not get it restarted.
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	c.write(w, http.StatusOK, Report{Status: StatusOK, Uptime: c.uptime()})
+}
+
+// Readiness runs every check and answers 503 if any fails or the server is
+// shutting down.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	checks, ok := c.Run(r.Context())
+	report := Report{Status: StatusOK, Uptime: c.uptime(), Checks: checks}
+	code := http.StatusOK
+	switch {
+	case c.shuttingDown.Load():
+		report.Status = StatusShutdown
+		code = http.StatusServiceUnavailable
+	case !ok:
+		report.Status = StatusFailing
+		code = http.StatusServiceUnavailable
+	}
+	c.write(w, code, report)
+}
+
+func (c *Checker) uptime() string {
+	return time.Since(c.started).Round(time.Second).String()
+}
+
+func (c *Checker) write(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
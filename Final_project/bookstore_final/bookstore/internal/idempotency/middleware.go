@@ -0,0 +1,149 @@
+// Package idempotency makes POST requests safe to retry. A client that sends
+// an Idempotency-Key header gets the stored response of the first request
+// with that key instead of having the request applied again.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bookstore/internal/auth"
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+const (
+	// Header is the request header carrying the client's key.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from a stored request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+type KeyMiddleware struct {
+	store interfaces.IdempotencyStore
+	ttl   time.Duration
+}
+
+// NewKeyMiddleware remembers responses for ttl. It must run after the auth
+// middleware, since keys are scoped to the authenticated user.
+func NewKeyMiddleware(store interfaces.IdempotencyStore, ttl time.Duration) *KeyMiddleware {
+	return &KeyMiddleware{store: store, ttl: ttl}
+}
+
+func (m *KeyMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			http.Error(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+
+		now := time.Now()
+		record := models.IdempotencyRecord{
+			Username:    auth.Username(r.Context()),
+			Key:         key,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: hex.EncodeToString(sum[:]),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(m.ttl),
+		}
+		existing, created, err := m.store.BeginRequest(r.Context(), record)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !created {
+			replay(w, existing, record)
+			return
+		}
+
+		// The outcome is recorded even if the client has gone away, since
+		// that is exactly when it will retry.
+		ctx := context.WithoutCancel(r.Context())
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if p := recover(); p != nil {
+				m.store.ReleaseRequest(ctx, record.Username, record.Key)
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+
+		// Server errors are not stored so that the request can be retried.
+		if rec.status >= http.StatusInternalServerError {
+			m.store.ReleaseRequest(ctx, record.Username, record.Key)
+			return
+		}
+		record.ResponseStatus = rec.status
+		record.ContentType = rec.Header().Get("Content-Type")
+		record.ResponseBody = rec.body.Bytes()
+		m.store.CompleteRequest(ctx, record)
+	})
+}
+
+// replay answers a repeated key with the stored response. The key must be
+// reused for the same request; a request still being processed gets a 409.
+func replay(w http.ResponseWriter, existing, record models.IdempotencyRecord) {
+	if existing.Method != record.Method || existing.Path != record.Path || existing.RequestHash != record.RequestHash {
+		http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+		return
+	}
+	if existing.Status != models.IdempotencyCompleted {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "A request with this Idempotency-Key is still being processed", http.StatusConflict)
+		return
+	}
+
+	if existing.ContentType != "" {
+		w.Header().Set("Content-Type", existing.ContentType)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(existing.ResponseBody)))
+	w.WriteHeader(existing.ResponseStatus)
+	w.Write(existing.ResponseBody)
+}
+
+// recorder passes the response through while keeping a copy of it.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status = code
+		rec.wroteHeader = true
+		rec.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (rec *recorder) Write(buf []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(buf)
+	return rec.ResponseWriter.Write(buf)
+}
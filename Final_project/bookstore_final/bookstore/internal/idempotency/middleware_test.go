@@ -0,0 +1,150 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bookstore/internal/models"
+)
+
+func TestReplay(t *testing.T) {
+	stored := models.IdempotencyRecord{
+		Method:         "POST",
+		Path:           "/api/orders",
+		RequestHash:    "abc",
+		Status:         models.IdempotencyCompleted,
+		ResponseStatus: http.StatusCreated,
+		ContentType:    "application/json",
+		ResponseBody:   []byte(`{"id":7}`),
+	}
+	inProgress := stored
+	inProgress.Status = models.IdempotencyInProgress
+
+	tests := []struct {
+		name       string
+		existing   models.IdempotencyRecord
+		method     string
+		path       string
+		hash       string
+		wantStatus int
+		wantBody   string
+		replayed   bool
+	}{
+		{"stored response", stored, "POST", "/api/orders", "abc", http.StatusCreated, `{"id":7}`, true},
+		{"still in progress", inProgress, "POST", "/api/orders", "abc", http.StatusConflict, "", false},
+		{"different body", stored, "POST", "/api/orders", "def", http.StatusUnprocessableEntity, "", false},
+		{"different path", stored, "POST", "/api/carts", "abc", http.StatusUnprocessableEntity, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			replay(w, tt.existing, models.IdempotencyRecord{Method: tt.method, Path: tt.path, RequestHash: tt.hash})
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get(ReplayedHeader) == "true"; got != tt.replayed {
+				t.Errorf("replayed header = %v, want %v", got, tt.replayed)
+			}
+		})
+	}
+}
+
+// memoryStore is an IdempotencyStore for tests.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyRecord
+}
+
+func (s *memoryStore) BeginRequest(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[record.Username+"\x00"+record.Key]; ok {
+		return existing, false, nil
+	}
+	record.Status = models.IdempotencyInProgress
+	s.records[record.Username+"\x00"+record.Key] = record
+	return record, true, nil
+}
+
+func (s *memoryStore) CompleteRequest(ctx context.Context, record models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record.Status = models.IdempotencyCompleted
+	s.records[record.Username+"\x00"+record.Key] = record
+	return nil
+}
+
+func (s *memoryStore) ReleaseRequest(ctx context.Context, username, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, username+"\x00"+key)
+	return nil
+}
+
+func (s *memoryStore) DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	store := &memoryStore{records: make(map[string]models.IdempotencyRecord)}
+	calls := 0
+	status := http.StatusCreated
+	handler := NewKeyMiddleware(store, time.Hour).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `}`))
+	}))
+
+	// The steps run in order against one store.
+	steps := []struct {
+		name       string
+		method     string
+		key        string
+		body       string
+		status     int // what the handler answers, if it runs
+		wantStatus int
+		wantCalls  int
+		wantBody   string
+	}{
+		{"no key", "POST", "", `{}`, http.StatusCreated, http.StatusCreated, 1, `{"call":1}`},
+		{"first request", "POST", "k1", `{"a":1}`, http.StatusCreated, http.StatusCreated, 2, `{"call":2}`},
+		{"retry is replayed", "POST", "k1", `{"a":1}`, http.StatusCreated, http.StatusCreated, 2, `{"call":2}`},
+		{"key reused for another body", "POST", "k1", `{"a":2}`, http.StatusCreated, http.StatusUnprocessableEntity, 2, ""},
+		{"server error is not stored", "POST", "k2", `{}`, http.StatusInternalServerError, http.StatusInternalServerError, 3, ""},
+		{"retry after a server error runs", "POST", "k2", `{}`, http.StatusCreated, http.StatusCreated, 4, `{"call":4}`},
+		{"client error is stored", "POST", "k3", `{}`, http.StatusBadRequest, http.StatusBadRequest, 5, ""},
+		{"retry of a client error is replayed", "POST", "k3", `{}`, http.StatusCreated, http.StatusBadRequest, 5, ""},
+		{"GET ignores the key", "GET", "k1", "", http.StatusOK, http.StatusOK, 6, ""},
+		{"key too long", "POST", strings.Repeat("k", 256), `{}`, http.StatusCreated, http.StatusBadRequest, 6, ""},
+	}
+	for _, s := range steps {
+		status = s.status
+		r := httptest.NewRequest(s.method, "/api/orders", strings.NewReader(s.body))
+		if s.key != "" {
+			r.Header.Set(Header, s.key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != s.wantStatus {
+			t.Errorf("%s: status = %d, want %d", s.name, w.Code, s.wantStatus)
+		}
+		if calls != s.wantCalls {
+			t.Errorf("%s: handler ran %d times, want %d", s.name, calls, s.wantCalls)
+		}
+		if s.wantBody != "" && w.Body.String() != s.wantBody {
+			t.Errorf("%s: body = %q, want %q", s.name, w.Body.String(), s.wantBody)
+		}
+	}
+}
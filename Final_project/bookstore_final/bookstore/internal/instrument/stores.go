@@ -0,0 +1,1049 @@
+// Package instrument wraps the stores so that every call is timed for
+// /metrics, traced as a span named after the interface and method when it
+// is part of a traced request, and logged at debug level, or as a warning
+// if it fails.
+package instrument
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/metrics"
+	"bookstore/internal/models"
+	"bookstore/internal/tracing"
+	"bookstore/pkg/utils"
+)
+
+// op is one store call in progress.
+type op struct {
+	logger    *utils.Logger
+	ctx       context.Context
+	store     string
+	operation string
+	start     time.Time
+	span      trace.Span
+}
+
+// begin starts timing a call. store labels the metrics and logs; iface
+// names the span.
+func begin(ctx context.Context, logger *utils.Logger, store, iface, operation string) (context.Context, *op) {
+	ctx, span := tracing.StartChild(ctx, iface+"."+operation)
+	return ctx, &op{logger: logger, ctx: ctx, store: store, operation: operation, start: time.Now(), span: span}
+}
+
+func (o *op) end(err error) {
+	duration := time.Since(o.start)
+	metrics.ObserveStore(o.store, o.operation, duration, err)
+	tracing.End(o.span, err)
+	o.logger.LogDatabaseOperation(o.ctx, o.operation, o.store, duration, err)
+}
+
+func BookStore(s interfaces.BookStore, logger *utils.Logger) interfaces.BookStore {
+	return &bookStore{next: s, logger: logger}
+}
+
+type bookStore struct {
+	next   interfaces.BookStore
+	logger *utils.Logger
+}
+
+func (s *bookStore) CreateBook(ctx context.Context, book models.Book) (models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "book", "BookStore", "CreateBook")
+	created, err := s.next.CreateBook(ctx, book)
+	op.end(err)
+	return created, err
+}
+
+func (s *bookStore) GetBook(ctx context.Context, id int) (models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "book", "BookStore", "GetBook")
+	book, err := s.next.GetBook(ctx, id)
+	op.end(err)
+	return book, err
+}
+
+func (s *bookStore) UpdateBook(ctx context.Context, id int, book models.Book) (models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "book", "BookStore", "UpdateBook")
+	updated, err := s.next.UpdateBook(ctx, id, book)
+	op.end(err)
+	return updated, err
+}
+
+func (s *bookStore) AdjustPrice(ctx context.Context, id int, factor float64) (models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "book", "BookStore", "AdjustPrice")
+	book, err := s.next.AdjustPrice(ctx, id, factor)
+	op.end(err)
+	return book, err
+}
+
+func (s *bookStore) DeleteBook(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "book", "BookStore", "DeleteBook")
+	err := s.next.DeleteBook(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *bookStore) SearchBooks(ctx context.Context, criteria models.SearchCriteria) ([]models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "book", "BookStore", "SearchBooks")
+	books, err := s.next.SearchBooks(ctx, criteria)
+	op.end(err)
+	return books, err
+}
+
+func (s *bookStore) ListBooks(ctx context.Context) ([]models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "book", "BookStore", "ListBooks")
+	books, err := s.next.ListBooks(ctx)
+	op.end(err)
+	return books, err
+}
+
+func (s *bookStore) ListBooksByAuthor(ctx context.Context, authorID int) ([]models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "book", "BookStore", "ListBooksByAuthor")
+	books, err := s.next.ListBooksByAuthor(ctx, authorID)
+	op.end(err)
+	return books, err
+}
+
+func AuthorStore(s interfaces.AuthorStore, logger *utils.Logger) interfaces.AuthorStore {
+	return &authorStore{next: s, logger: logger}
+}
+
+type authorStore struct {
+	next   interfaces.AuthorStore
+	logger *utils.Logger
+}
+
+func (s *authorStore) CreateAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	ctx, op := begin(ctx, s.logger, "author", "AuthorStore", "CreateAuthor")
+	created, err := s.next.CreateAuthor(ctx, author)
+	op.end(err)
+	return created, err
+}
+
+func (s *authorStore) GetAuthor(ctx context.Context, id int) (models.Author, error) {
+	ctx, op := begin(ctx, s.logger, "author", "AuthorStore", "GetAuthor")
+	author, err := s.next.GetAuthor(ctx, id)
+	op.end(err)
+	return author, err
+}
+
+func (s *authorStore) UpdateAuthor(ctx context.Context, id int, author models.Author) (models.Author, error) {
+	ctx, op := begin(ctx, s.logger, "author", "AuthorStore", "UpdateAuthor")
+	updated, err := s.next.UpdateAuthor(ctx, id, author)
+	op.end(err)
+	return updated, err
+}
+
+func (s *authorStore) DeleteAuthor(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "author", "AuthorStore", "DeleteAuthor")
+	err := s.next.DeleteAuthor(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *authorStore) ListAuthors(ctx context.Context) ([]models.Author, error) {
+	ctx, op := begin(ctx, s.logger, "author", "AuthorStore", "ListAuthors")
+	authors, err := s.next.ListAuthors(ctx)
+	op.end(err)
+	return authors, err
+}
+
+func (s *authorStore) GetAuthorSummary(ctx context.Context, id int, start, end time.Time) (models.AuthorSummary, error) {
+	ctx, op := begin(ctx, s.logger, "author", "AuthorStore", "GetAuthorSummary")
+	authorSummary, err := s.next.GetAuthorSummary(ctx, id, start, end)
+	op.end(err)
+	return authorSummary, err
+}
+
+func CustomerStore(s interfaces.CustomerStore, logger *utils.Logger) interfaces.CustomerStore {
+	return &customerStore{next: s, logger: logger}
+}
+
+type customerStore struct {
+	next   interfaces.CustomerStore
+	logger *utils.Logger
+}
+
+func (s *customerStore) CreateCustomer(ctx context.Context, customer models.Customer) (models.Customer, error) {
+	ctx, op := begin(ctx, s.logger, "customer", "CustomerStore", "CreateCustomer")
+	created, err := s.next.CreateCustomer(ctx, customer)
+	op.end(err)
+	return created, err
+}
+
+func (s *customerStore) GetCustomer(ctx context.Context, id int) (models.Customer, error) {
+	ctx, op := begin(ctx, s.logger, "customer", "CustomerStore", "GetCustomer")
+	customer, err := s.next.GetCustomer(ctx, id)
+	op.end(err)
+	return customer, err
+}
+
+func (s *customerStore) UpdateCustomer(ctx context.Context, id int, customer models.Customer) (models.Customer, error) {
+	ctx, op := begin(ctx, s.logger, "customer", "CustomerStore", "UpdateCustomer")
+	updated, err := s.next.UpdateCustomer(ctx, id, customer)
+	op.end(err)
+	return updated, err
+}
+
+func (s *customerStore) DeleteCustomer(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "customer", "CustomerStore", "DeleteCustomer")
+	err := s.next.DeleteCustomer(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *customerStore) ListCustomers(ctx context.Context) ([]models.Customer, error) {
+	ctx, op := begin(ctx, s.logger, "customer", "CustomerStore", "ListCustomers")
+	customers, err := s.next.ListCustomers(ctx)
+	op.end(err)
+	return customers, err
+}
+
+func (s *customerStore) EraseCustomer(ctx context.Context, id int, actor string) (models.Customer, error) {
+	ctx, op := begin(ctx, s.logger, "customer", "CustomerStore", "EraseCustomer")
+	customer, err := s.next.EraseCustomer(ctx, id, actor)
+	op.end(err)
+	return customer, err
+}
+
+func OrderStore(s interfaces.OrderStore, logger *utils.Logger) interfaces.OrderStore {
+	return &orderStore{next: s, logger: logger}
+}
+
+type orderStore struct {
+	next   interfaces.OrderStore
+	logger *utils.Logger
+}
+
+func (s *orderStore) CreateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	ctx, op := begin(ctx, s.logger, "order", "OrderStore", "CreateOrder")
+	created, err := s.next.CreateOrder(ctx, order)
+	op.end(err)
+	if err == nil {
+		metrics.RecordOrder(created.TotalPrice)
+	}
+	return created, err
+}
+
+func (s *orderStore) GetOrder(ctx context.Context, id int) (models.Order, error) {
+	ctx, op := begin(ctx, s.logger, "order", "OrderStore", "GetOrder")
+	order, err := s.next.GetOrder(ctx, id)
+	op.end(err)
+	return order, err
+}
+
+func (s *orderStore) UpdateOrder(ctx context.Context, id int, order models.Order) (models.Order, error) {
+	ctx, op := begin(ctx, s.logger, "order", "OrderStore", "UpdateOrder")
+	updated, err := s.next.UpdateOrder(ctx, id, order)
+	op.end(err)
+	return updated, err
+}
+
+func (s *orderStore) DeleteOrder(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "order", "OrderStore", "DeleteOrder")
+	err := s.next.DeleteOrder(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *orderStore) ListOrders(ctx context.Context) ([]models.Order, error) {
+	ctx, op := begin(ctx, s.logger, "order", "OrderStore", "ListOrders")
+	orders, err := s.next.ListOrders(ctx)
+	op.end(err)
+	return orders, err
+}
+
+func (s *orderStore) GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]models.Order, error) {
+	ctx, op := begin(ctx, s.logger, "order", "OrderStore", "GetOrdersInTimeRange")
+	orders, err := s.next.GetOrdersInTimeRange(ctx, start, end)
+	op.end(err)
+	return orders, err
+}
+
+func (s *orderStore) ListCustomerOrders(ctx context.Context, customerID int) ([]models.Order, error) {
+	ctx, op := begin(ctx, s.logger, "order", "OrderStore", "ListCustomerOrders")
+	orders, err := s.next.ListCustomerOrders(ctx, customerID)
+	op.end(err)
+	return orders, err
+}
+
+func (s *orderStore) CancelOrder(ctx context.Context, id int) (models.Order, error) {
+	ctx, op := begin(ctx, s.logger, "order", "OrderStore", "CancelOrder")
+	order, err := s.next.CancelOrder(ctx, id)
+	op.end(err)
+	return order, err
+}
+
+func ReportStore(s interfaces.ReportStore, logger *utils.Logger) interfaces.ReportStore {
+	return &reportStore{next: s, logger: logger}
+}
+
+type reportStore struct {
+	next   interfaces.ReportStore
+	logger *utils.Logger
+}
+
+func (s *reportStore) SaveReport(ctx context.Context, report models.SalesReport) error {
+	ctx, op := begin(ctx, s.logger, "report", "ReportStore", "SaveReport")
+	err := s.next.SaveReport(ctx, report)
+	op.end(err)
+	return err
+}
+
+func (s *reportStore) GetReports(ctx context.Context, start, end time.Time) ([]models.SalesReport, error) {
+	ctx, op := begin(ctx, s.logger, "report", "ReportStore", "GetReports")
+	salesReports, err := s.next.GetReports(ctx, start, end)
+	op.end(err)
+	return salesReports, err
+}
+
+func InventoryStore(s interfaces.InventoryStore, logger *utils.Logger) interfaces.InventoryStore {
+	return &inventoryStore{next: s, logger: logger}
+}
+
+type inventoryStore struct {
+	next   interfaces.InventoryStore
+	logger *utils.Logger
+}
+
+func (s *inventoryStore) RecordMovement(ctx context.Context, movement models.StockMovement) (models.StockMovement, error) {
+	ctx, op := begin(ctx, s.logger, "inventory", "InventoryStore", "RecordMovement")
+	stockMovement, err := s.next.RecordMovement(ctx, movement)
+	op.end(err)
+	return stockMovement, err
+}
+
+func (s *inventoryStore) ListMovements(ctx context.Context, bookID int) ([]models.StockMovement, error) {
+	ctx, op := begin(ctx, s.logger, "inventory", "InventoryStore", "ListMovements")
+	stockMovements, err := s.next.ListMovements(ctx, bookID)
+	op.end(err)
+	return stockMovements, err
+}
+
+func (s *inventoryStore) ReconcileStock(ctx context.Context, bookID int) (models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "inventory", "InventoryStore", "ReconcileStock")
+	book, err := s.next.ReconcileStock(ctx, bookID)
+	op.end(err)
+	return book, err
+}
+
+func (s *inventoryStore) ListLowStock(ctx context.Context) ([]models.Book, error) {
+	ctx, op := begin(ctx, s.logger, "inventory", "InventoryStore", "ListLowStock")
+	books, err := s.next.ListLowStock(ctx)
+	op.end(err)
+	return books, err
+}
+
+func (s *inventoryStore) CountStockAlerts(ctx context.Context) (outOfStock, lowStock int, err error) {
+	ctx, op := begin(ctx, s.logger, "inventory", "InventoryStore", "CountStockAlerts")
+	outOfStock, lowStock, err = s.next.CountStockAlerts(ctx)
+	op.end(err)
+	return outOfStock, lowStock, err
+}
+
+func CartStore(s interfaces.CartStore, logger *utils.Logger) interfaces.CartStore {
+	return &cartStore{next: s, logger: logger}
+}
+
+type cartStore struct {
+	next   interfaces.CartStore
+	logger *utils.Logger
+}
+
+func (s *cartStore) GetOrCreateCart(ctx context.Context, customerID int) (models.Cart, error) {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "GetOrCreateCart")
+	cart, err := s.next.GetOrCreateCart(ctx, customerID)
+	op.end(err)
+	return cart, err
+}
+
+func (s *cartStore) GetCart(ctx context.Context, id int) (models.Cart, error) {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "GetCart")
+	cart, err := s.next.GetCart(ctx, id)
+	op.end(err)
+	return cart, err
+}
+
+func (s *cartStore) AddItem(ctx context.Context, cartID, bookID, quantity int) (models.Cart, error) {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "AddItem")
+	cart, err := s.next.AddItem(ctx, cartID, bookID, quantity)
+	op.end(err)
+	return cart, err
+}
+
+func (s *cartStore) SetItemQuantity(ctx context.Context, cartID, bookID, quantity int) (models.Cart, error) {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "SetItemQuantity")
+	cart, err := s.next.SetItemQuantity(ctx, cartID, bookID, quantity)
+	op.end(err)
+	return cart, err
+}
+
+func (s *cartStore) RemoveItem(ctx context.Context, cartID, bookID int) (models.Cart, error) {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "RemoveItem")
+	cart, err := s.next.RemoveItem(ctx, cartID, bookID)
+	op.end(err)
+	return cart, err
+}
+
+func (s *cartStore) DeleteCart(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "DeleteCart")
+	err := s.next.DeleteCart(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *cartStore) BeginCheckout(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "BeginCheckout")
+	err := s.next.BeginCheckout(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *cartStore) AbortCheckout(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "AbortCheckout")
+	err := s.next.AbortCheckout(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *cartStore) ReleaseStaleCheckouts(ctx context.Context, before time.Time) (int64, error) {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "ReleaseStaleCheckouts")
+	n, err := s.next.ReleaseStaleCheckouts(ctx, before)
+	op.end(err)
+	return n, err
+}
+
+func (s *cartStore) DeleteExpiredCarts(ctx context.Context, before time.Time) (int64, error) {
+	ctx, op := begin(ctx, s.logger, "cart", "CartStore", "DeleteExpiredCarts")
+	n, err := s.next.DeleteExpiredCarts(ctx, before)
+	op.end(err)
+	return n, err
+}
+
+func PromotionStore(s interfaces.PromotionStore, logger *utils.Logger) interfaces.PromotionStore {
+	return &promotionStore{next: s, logger: logger}
+}
+
+type promotionStore struct {
+	next   interfaces.PromotionStore
+	logger *utils.Logger
+}
+
+func (s *promotionStore) CreatePromotion(ctx context.Context, promotion models.Promotion) (models.Promotion, error) {
+	ctx, op := begin(ctx, s.logger, "promotion", "PromotionStore", "CreatePromotion")
+	created, err := s.next.CreatePromotion(ctx, promotion)
+	op.end(err)
+	return created, err
+}
+
+func (s *promotionStore) GetPromotion(ctx context.Context, id int) (models.Promotion, error) {
+	ctx, op := begin(ctx, s.logger, "promotion", "PromotionStore", "GetPromotion")
+	promotion, err := s.next.GetPromotion(ctx, id)
+	op.end(err)
+	return promotion, err
+}
+
+func (s *promotionStore) UpdatePromotion(ctx context.Context, id int, promotion models.Promotion) (models.Promotion, error) {
+	ctx, op := begin(ctx, s.logger, "promotion", "PromotionStore", "UpdatePromotion")
+	updated, err := s.next.UpdatePromotion(ctx, id, promotion)
+	op.end(err)
+	return updated, err
+}
+
+func (s *promotionStore) DeletePromotion(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "promotion", "PromotionStore", "DeletePromotion")
+	err := s.next.DeletePromotion(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *promotionStore) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	ctx, op := begin(ctx, s.logger, "promotion", "PromotionStore", "ListPromotions")
+	promotions, err := s.next.ListPromotions(ctx)
+	op.end(err)
+	return promotions, err
+}
+
+func RateStore(s interfaces.RateStore, logger *utils.Logger) interfaces.RateStore {
+	return &rateStore{next: s, logger: logger}
+}
+
+type rateStore struct {
+	next   interfaces.RateStore
+	logger *utils.Logger
+}
+
+func (s *rateStore) CreateTaxRule(ctx context.Context, rule models.TaxRule) (models.TaxRule, error) {
+	ctx, op := begin(ctx, s.logger, "rate", "RateStore", "CreateTaxRule")
+	taxRule, err := s.next.CreateTaxRule(ctx, rule)
+	op.end(err)
+	return taxRule, err
+}
+
+func (s *rateStore) UpdateTaxRule(ctx context.Context, id int, rule models.TaxRule) (models.TaxRule, error) {
+	ctx, op := begin(ctx, s.logger, "rate", "RateStore", "UpdateTaxRule")
+	taxRule, err := s.next.UpdateTaxRule(ctx, id, rule)
+	op.end(err)
+	return taxRule, err
+}
+
+func (s *rateStore) DeleteTaxRule(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "rate", "RateStore", "DeleteTaxRule")
+	err := s.next.DeleteTaxRule(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *rateStore) ListTaxRules(ctx context.Context) ([]models.TaxRule, error) {
+	ctx, op := begin(ctx, s.logger, "rate", "RateStore", "ListTaxRules")
+	taxRules, err := s.next.ListTaxRules(ctx)
+	op.end(err)
+	return taxRules, err
+}
+
+func (s *rateStore) CreateShippingRate(ctx context.Context, rate models.ShippingRate) (models.ShippingRate, error) {
+	ctx, op := begin(ctx, s.logger, "rate", "RateStore", "CreateShippingRate")
+	shippingRate, err := s.next.CreateShippingRate(ctx, rate)
+	op.end(err)
+	return shippingRate, err
+}
+
+func (s *rateStore) UpdateShippingRate(ctx context.Context, id int, rate models.ShippingRate) (models.ShippingRate, error) {
+	ctx, op := begin(ctx, s.logger, "rate", "RateStore", "UpdateShippingRate")
+	shippingRate, err := s.next.UpdateShippingRate(ctx, id, rate)
+	op.end(err)
+	return shippingRate, err
+}
+
+func (s *rateStore) DeleteShippingRate(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "rate", "RateStore", "DeleteShippingRate")
+	err := s.next.DeleteShippingRate(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *rateStore) ListShippingRates(ctx context.Context) ([]models.ShippingRate, error) {
+	ctx, op := begin(ctx, s.logger, "rate", "RateStore", "ListShippingRates")
+	shippingRates, err := s.next.ListShippingRates(ctx)
+	op.end(err)
+	return shippingRates, err
+}
+
+func PaymentStore(s interfaces.PaymentStore, logger *utils.Logger) interfaces.PaymentStore {
+	return &paymentStore{next: s, logger: logger}
+}
+
+type paymentStore struct {
+	next   interfaces.PaymentStore
+	logger *utils.Logger
+}
+
+func (s *paymentStore) BeginPayment(ctx context.Context, orderID int, provider string) (payment models.Payment, created bool, err error) {
+	ctx, op := begin(ctx, s.logger, "payment", "PaymentStore", "BeginPayment")
+	payment, created, err = s.next.BeginPayment(ctx, orderID, provider)
+	op.end(err)
+	return payment, created, err
+}
+
+func (s *paymentStore) UpdatePayment(ctx context.Context, id int, status, reference, reason string) (models.Payment, error) {
+	ctx, op := begin(ctx, s.logger, "payment", "PaymentStore", "UpdatePayment")
+	payment, err := s.next.UpdatePayment(ctx, id, status, reference, reason)
+	op.end(err)
+	return payment, err
+}
+
+func (s *paymentStore) CompletePayment(ctx context.Context, id int, reference string) (models.Payment, error) {
+	ctx, op := begin(ctx, s.logger, "payment", "PaymentStore", "CompletePayment")
+	payment, err := s.next.CompletePayment(ctx, id, reference)
+	op.end(err)
+	return payment, err
+}
+
+func (s *paymentStore) AbandonPayment(ctx context.Context, id int, before time.Time) (bool, error) {
+	ctx, op := begin(ctx, s.logger, "payment", "PaymentStore", "AbandonPayment")
+	ok, err := s.next.AbandonPayment(ctx, id, before)
+	op.end(err)
+	return ok, err
+}
+
+func (s *paymentStore) GetPayment(ctx context.Context, id int) (models.Payment, error) {
+	ctx, op := begin(ctx, s.logger, "payment", "PaymentStore", "GetPayment")
+	payment, err := s.next.GetPayment(ctx, id)
+	op.end(err)
+	return payment, err
+}
+
+func (s *paymentStore) GetPaymentByReference(ctx context.Context, provider, reference string) (models.Payment, error) {
+	ctx, op := begin(ctx, s.logger, "payment", "PaymentStore", "GetPaymentByReference")
+	payment, err := s.next.GetPaymentByReference(ctx, provider, reference)
+	op.end(err)
+	return payment, err
+}
+
+func (s *paymentStore) ListPayments(ctx context.Context, orderID int) ([]models.Payment, error) {
+	ctx, op := begin(ctx, s.logger, "payment", "PaymentStore", "ListPayments")
+	payments, err := s.next.ListPayments(ctx, orderID)
+	op.end(err)
+	return payments, err
+}
+
+func ReturnStore(s interfaces.ReturnStore, logger *utils.Logger) interfaces.ReturnStore {
+	return &returnStore{next: s, logger: logger}
+}
+
+type returnStore struct {
+	next   interfaces.ReturnStore
+	logger *utils.Logger
+}
+
+func (s *returnStore) CreateReturn(ctx context.Context, ret models.ReturnRequest) (models.ReturnRequest, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "CreateReturn")
+	returnRequest, err := s.next.CreateReturn(ctx, ret)
+	op.end(err)
+	return returnRequest, err
+}
+
+func (s *returnStore) GetReturn(ctx context.Context, id int) (models.ReturnRequest, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "GetReturn")
+	returnRequest, err := s.next.GetReturn(ctx, id)
+	op.end(err)
+	return returnRequest, err
+}
+
+func (s *returnStore) ListReturns(ctx context.Context, orderID int, status string) ([]models.ReturnRequest, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "ListReturns")
+	returnRequests, err := s.next.ListReturns(ctx, orderID, status)
+	op.end(err)
+	return returnRequests, err
+}
+
+func (s *returnStore) ApproveReturn(ctx context.Context, id int, note string) (models.ReturnRequest, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "ApproveReturn")
+	returnRequest, err := s.next.ApproveReturn(ctx, id, note)
+	op.end(err)
+	return returnRequest, err
+}
+
+func (s *returnStore) RejectReturn(ctx context.Context, id int, note string) (models.ReturnRequest, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "RejectReturn")
+	returnRequest, err := s.next.RejectReturn(ctx, id, note)
+	op.end(err)
+	return returnRequest, err
+}
+
+func (s *returnStore) GetRefund(ctx context.Context, id int) (models.Refund, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "GetRefund")
+	refund, err := s.next.GetRefund(ctx, id)
+	op.end(err)
+	return refund, err
+}
+
+func (s *returnStore) ListRefunds(ctx context.Context, orderID int) ([]models.Refund, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "ListRefunds")
+	refunds, err := s.next.ListRefunds(ctx, orderID)
+	op.end(err)
+	return refunds, err
+}
+
+func (s *returnStore) ClaimRefund(ctx context.Context, id int, stale time.Time) (models.Refund, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "ClaimRefund")
+	refund, err := s.next.ClaimRefund(ctx, id, stale)
+	op.end(err)
+	return refund, err
+}
+
+func (s *returnStore) CompleteRefund(ctx context.Context, id int, reference string) (models.Refund, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "CompleteRefund")
+	refund, err := s.next.CompleteRefund(ctx, id, reference)
+	op.end(err)
+	return refund, err
+}
+
+func (s *returnStore) FailRefund(ctx context.Context, id int, reason string) (models.Refund, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "FailRefund")
+	refund, err := s.next.FailRefund(ctx, id, reason)
+	op.end(err)
+	return refund, err
+}
+
+func (s *returnStore) GetRefundsInTimeRange(ctx context.Context, start, end time.Time) ([]models.Refund, error) {
+	ctx, op := begin(ctx, s.logger, "return", "ReturnStore", "GetRefundsInTimeRange")
+	refunds, err := s.next.GetRefundsInTimeRange(ctx, start, end)
+	op.end(err)
+	return refunds, err
+}
+
+func IdempotencyStore(s interfaces.IdempotencyStore, logger *utils.Logger) interfaces.IdempotencyStore {
+	return &idempotencyStore{next: s, logger: logger}
+}
+
+type idempotencyStore struct {
+	next   interfaces.IdempotencyStore
+	logger *utils.Logger
+}
+
+func (s *idempotencyStore) BeginRequest(ctx context.Context, record models.IdempotencyRecord) (existing models.IdempotencyRecord, created bool, err error) {
+	ctx, op := begin(ctx, s.logger, "idempotency", "IdempotencyStore", "BeginRequest")
+	existing, created, err = s.next.BeginRequest(ctx, record)
+	op.end(err)
+	return existing, created, err
+}
+
+func (s *idempotencyStore) CompleteRequest(ctx context.Context, record models.IdempotencyRecord) error {
+	ctx, op := begin(ctx, s.logger, "idempotency", "IdempotencyStore", "CompleteRequest")
+	err := s.next.CompleteRequest(ctx, record)
+	op.end(err)
+	return err
+}
+
+func (s *idempotencyStore) ReleaseRequest(ctx context.Context, username, key string) error {
+	ctx, op := begin(ctx, s.logger, "idempotency", "IdempotencyStore", "ReleaseRequest")
+	err := s.next.ReleaseRequest(ctx, username, key)
+	op.end(err)
+	return err
+}
+
+func (s *idempotencyStore) DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error) {
+	ctx, op := begin(ctx, s.logger, "idempotency", "IdempotencyStore", "DeleteExpiredKeys")
+	n, err := s.next.DeleteExpiredKeys(ctx, before)
+	op.end(err)
+	return n, err
+}
+
+func OutboxStore(s interfaces.OutboxStore, logger *utils.Logger) interfaces.OutboxStore {
+	return &outboxStore{next: s, logger: logger}
+}
+
+type outboxStore struct {
+	next   interfaces.OutboxStore
+	logger *utils.Logger
+}
+
+func (s *outboxStore) Enqueue(ctx context.Context, event interface{}) error {
+	ctx, op := begin(ctx, s.logger, "outbox", "OutboxStore", "Enqueue")
+	err := s.next.Enqueue(ctx, event)
+	op.end(err)
+	return err
+}
+
+func (s *outboxStore) EventsAfter(ctx context.Context, afterID int64, limit int) ([]models.Event, error) {
+	ctx, op := begin(ctx, s.logger, "outbox", "OutboxStore", "EventsAfter")
+	events, err := s.next.EventsAfter(ctx, afterID, limit)
+	op.end(err)
+	return events, err
+}
+
+func (s *outboxStore) PendingEvents(ctx context.Context, now time.Time, limit int) ([]models.Event, error) {
+	ctx, op := begin(ctx, s.logger, "outbox", "OutboxStore", "PendingEvents")
+	events, err := s.next.PendingEvents(ctx, now, limit)
+	op.end(err)
+	return events, err
+}
+
+func (s *outboxStore) MarkEventDispatched(ctx context.Context, id int64) error {
+	ctx, op := begin(ctx, s.logger, "outbox", "OutboxStore", "MarkEventDispatched")
+	err := s.next.MarkEventDispatched(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *outboxStore) MarkEventFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	ctx, op := begin(ctx, s.logger, "outbox", "OutboxStore", "MarkEventFailed")
+	err := s.next.MarkEventFailed(ctx, id, reason, retryAt)
+	op.end(err)
+	return err
+}
+
+func (s *outboxStore) DeleteDispatchedEvents(ctx context.Context, before time.Time) (int64, error) {
+	ctx, op := begin(ctx, s.logger, "outbox", "OutboxStore", "DeleteDispatchedEvents")
+	n, err := s.next.DeleteDispatchedEvents(ctx, before)
+	op.end(err)
+	return n, err
+}
+
+func WebhookStore(s interfaces.WebhookStore, logger *utils.Logger) interfaces.WebhookStore {
+	return &webhookStore{next: s, logger: logger}
+}
+
+type webhookStore struct {
+	next   interfaces.WebhookStore
+	logger *utils.Logger
+}
+
+func (s *webhookStore) CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "CreateSubscription")
+	webhookSubscription, err := s.next.CreateSubscription(ctx, sub)
+	op.end(err)
+	return webhookSubscription, err
+}
+
+func (s *webhookStore) GetSubscription(ctx context.Context, id int) (models.WebhookSubscription, error) {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "GetSubscription")
+	webhookSubscription, err := s.next.GetSubscription(ctx, id)
+	op.end(err)
+	return webhookSubscription, err
+}
+
+func (s *webhookStore) UpdateSubscription(ctx context.Context, id int, sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "UpdateSubscription")
+	webhookSubscription, err := s.next.UpdateSubscription(ctx, id, sub)
+	op.end(err)
+	return webhookSubscription, err
+}
+
+func (s *webhookStore) DeleteSubscription(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "DeleteSubscription")
+	err := s.next.DeleteSubscription(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *webhookStore) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "ListSubscriptions")
+	webhookSubscriptions, err := s.next.ListSubscriptions(ctx)
+	op.end(err)
+	return webhookSubscriptions, err
+}
+
+func (s *webhookStore) EnqueueDeliveries(ctx context.Context, event models.Event) error {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "EnqueueDeliveries")
+	err := s.next.EnqueueDeliveries(ctx, event)
+	op.end(err)
+	return err
+}
+
+func (s *webhookStore) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "DueDeliveries")
+	webhookDeliveries, err := s.next.DueDeliveries(ctx, now, limit)
+	op.end(err)
+	return webhookDeliveries, err
+}
+
+func (s *webhookStore) RecordAttempt(ctx context.Context, delivery models.WebhookDelivery) error {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "RecordAttempt")
+	err := s.next.RecordAttempt(ctx, delivery)
+	op.end(err)
+	return err
+}
+
+func (s *webhookStore) GetDelivery(ctx context.Context, id int) (models.WebhookDelivery, error) {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "GetDelivery")
+	webhookDelivery, err := s.next.GetDelivery(ctx, id)
+	op.end(err)
+	return webhookDelivery, err
+}
+
+func (s *webhookStore) ListDeliveries(ctx context.Context, subscriptionID int) ([]models.WebhookDelivery, error) {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "ListDeliveries")
+	webhookDeliveries, err := s.next.ListDeliveries(ctx, subscriptionID)
+	op.end(err)
+	return webhookDeliveries, err
+}
+
+func (s *webhookStore) Redeliver(ctx context.Context, deliveryID int) (models.WebhookDelivery, error) {
+	ctx, op := begin(ctx, s.logger, "webhook", "WebhookStore", "Redeliver")
+	webhookDelivery, err := s.next.Redeliver(ctx, deliveryID)
+	op.end(err)
+	return webhookDelivery, err
+}
+
+func APIKeyStore(s interfaces.APIKeyStore, logger *utils.Logger) interfaces.APIKeyStore {
+	return &apiKeyStore{next: s, logger: logger}
+}
+
+type apiKeyStore struct {
+	next   interfaces.APIKeyStore
+	logger *utils.Logger
+}
+
+func (s *apiKeyStore) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	ctx, op := begin(ctx, s.logger, "apiKey", "APIKeyStore", "CreateAPIKey")
+	aPIKey, err := s.next.CreateAPIKey(ctx, key)
+	op.end(err)
+	return aPIKey, err
+}
+
+func (s *apiKeyStore) GetAPIKey(ctx context.Context, id int) (models.APIKey, error) {
+	ctx, op := begin(ctx, s.logger, "apiKey", "APIKeyStore", "GetAPIKey")
+	aPIKey, err := s.next.GetAPIKey(ctx, id)
+	op.end(err)
+	return aPIKey, err
+}
+
+func (s *apiKeyStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	ctx, op := begin(ctx, s.logger, "apiKey", "APIKeyStore", "GetAPIKeyByPrefix")
+	aPIKey, err := s.next.GetAPIKeyByPrefix(ctx, prefix)
+	op.end(err)
+	return aPIKey, err
+}
+
+func (s *apiKeyStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	ctx, op := begin(ctx, s.logger, "apiKey", "APIKeyStore", "ListAPIKeys")
+	aPIKeies, err := s.next.ListAPIKeys(ctx)
+	op.end(err)
+	return aPIKeies, err
+}
+
+func (s *apiKeyStore) RevokeAPIKey(ctx context.Context, id int) (models.APIKey, error) {
+	ctx, op := begin(ctx, s.logger, "apiKey", "APIKeyStore", "RevokeAPIKey")
+	aPIKey, err := s.next.RevokeAPIKey(ctx, id)
+	op.end(err)
+	return aPIKey, err
+}
+
+func (s *apiKeyStore) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	ctx, op := begin(ctx, s.logger, "apiKey", "APIKeyStore", "TouchAPIKey")
+	err := s.next.TouchAPIKey(ctx, id, at)
+	op.end(err)
+	return err
+}
+
+func AccountStore(s interfaces.AccountStore, logger *utils.Logger) interfaces.AccountStore {
+	return &accountStore{next: s, logger: logger}
+}
+
+type accountStore struct {
+	next   interfaces.AccountStore
+	logger *utils.Logger
+}
+
+func (s *accountStore) CreateAccount(ctx context.Context, account models.Account, customer models.Customer) (models.Account, models.Customer, error) {
+	ctx, op := begin(ctx, s.logger, "account", "AccountStore", "CreateAccount")
+	created, createdCustomer, err := s.next.CreateAccount(ctx, account, customer)
+	op.end(err)
+	return created, createdCustomer, err
+}
+
+func (s *accountStore) GetAccountByEmail(ctx context.Context, email string) (models.Account, error) {
+	ctx, op := begin(ctx, s.logger, "account", "AccountStore", "GetAccountByEmail")
+	account, err := s.next.GetAccountByEmail(ctx, email)
+	op.end(err)
+	return account, err
+}
+
+func (s *accountStore) GetAccountByCustomer(ctx context.Context, customerID int) (models.Account, error) {
+	ctx, op := begin(ctx, s.logger, "account", "AccountStore", "GetAccountByCustomer")
+	account, err := s.next.GetAccountByCustomer(ctx, customerID)
+	op.end(err)
+	return account, err
+}
+
+func (s *accountStore) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	ctx, op := begin(ctx, s.logger, "account", "AccountStore", "UpdatePassword")
+	err := s.next.UpdatePassword(ctx, id, passwordHash)
+	op.end(err)
+	return err
+}
+
+func AddressStore(s interfaces.AddressStore, logger *utils.Logger) interfaces.AddressStore {
+	return &addressStore{next: s, logger: logger}
+}
+
+type addressStore struct {
+	next   interfaces.AddressStore
+	logger *utils.Logger
+}
+
+func (s *addressStore) CreateAddress(ctx context.Context, address models.CustomerAddress) (models.CustomerAddress, error) {
+	ctx, op := begin(ctx, s.logger, "address", "AddressStore", "CreateAddress")
+	customerAddress, err := s.next.CreateAddress(ctx, address)
+	op.end(err)
+	return customerAddress, err
+}
+
+func (s *addressStore) GetAddress(ctx context.Context, customerID, id int) (models.CustomerAddress, error) {
+	ctx, op := begin(ctx, s.logger, "address", "AddressStore", "GetAddress")
+	customerAddress, err := s.next.GetAddress(ctx, customerID, id)
+	op.end(err)
+	return customerAddress, err
+}
+
+func (s *addressStore) UpdateAddress(ctx context.Context, customerID, id int, address models.CustomerAddress) (models.CustomerAddress, error) {
+	ctx, op := begin(ctx, s.logger, "address", "AddressStore", "UpdateAddress")
+	customerAddress, err := s.next.UpdateAddress(ctx, customerID, id, address)
+	op.end(err)
+	return customerAddress, err
+}
+
+func (s *addressStore) DeleteAddress(ctx context.Context, customerID, id int) error {
+	ctx, op := begin(ctx, s.logger, "address", "AddressStore", "DeleteAddress")
+	err := s.next.DeleteAddress(ctx, customerID, id)
+	op.end(err)
+	return err
+}
+
+func (s *addressStore) ListAddresses(ctx context.Context, customerID int) ([]models.CustomerAddress, error) {
+	ctx, op := begin(ctx, s.logger, "address", "AddressStore", "ListAddresses")
+	customerAddresss, err := s.next.ListAddresses(ctx, customerID)
+	op.end(err)
+	return customerAddresss, err
+}
+
+func AuditStore(s interfaces.AuditStore, logger *utils.Logger) interfaces.AuditStore {
+	return &auditStore{next: s, logger: logger}
+}
+
+type auditStore struct {
+	next   interfaces.AuditStore
+	logger *utils.Logger
+}
+
+func (s *auditStore) RecordAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	ctx, op := begin(ctx, s.logger, "audit", "AuditStore", "RecordAudit")
+	auditEntry, err := s.next.RecordAudit(ctx, entry)
+	op.end(err)
+	return auditEntry, err
+}
+
+func (s *auditStore) ListAudit(ctx context.Context, customerID int) ([]models.AuditEntry, error) {
+	ctx, op := begin(ctx, s.logger, "audit", "AuditStore", "ListAudit")
+	auditEntries, err := s.next.ListAudit(ctx, customerID)
+	op.end(err)
+	return auditEntries, err
+}
+
+func WorkStore(s interfaces.WorkStore, logger *utils.Logger) interfaces.WorkStore {
+	return &workStore{next: s, logger: logger}
+}
+
+type workStore struct {
+	next   interfaces.WorkStore
+	logger *utils.Logger
+}
+
+func (s *workStore) CreateWork(ctx context.Context, work models.Work) (models.Work, error) {
+	ctx, op := begin(ctx, s.logger, "work", "WorkStore", "CreateWork")
+	created, err := s.next.CreateWork(ctx, work)
+	op.end(err)
+	return created, err
+}
+
+func (s *workStore) GetWork(ctx context.Context, id int) (models.Work, error) {
+	ctx, op := begin(ctx, s.logger, "work", "WorkStore", "GetWork")
+	work, err := s.next.GetWork(ctx, id)
+	op.end(err)
+	return work, err
+}
+
+func (s *workStore) UpdateWork(ctx context.Context, id int, work models.Work) (models.Work, error) {
+	ctx, op := begin(ctx, s.logger, "work", "WorkStore", "UpdateWork")
+	updated, err := s.next.UpdateWork(ctx, id, work)
+	op.end(err)
+	return updated, err
+}
+
+func (s *workStore) DeleteWork(ctx context.Context, id int) error {
+	ctx, op := begin(ctx, s.logger, "work", "WorkStore", "DeleteWork")
+	err := s.next.DeleteWork(ctx, id)
+	op.end(err)
+	return err
+}
+
+func (s *workStore) ListWorks(ctx context.Context) ([]models.Work, error) {
+	ctx, op := begin(ctx, s.logger, "work", "WorkStore", "ListWorks")
+	works, err := s.next.ListWorks(ctx)
+	op.end(err)
+	return works, err
+}
@@ -12,10 +12,18 @@ import (
 type BookStore interface {
 	CreateBook(ctx context.Context, book models.Book) (models.Book, error)
 	GetBook(ctx context.Context, id int) (models.Book, error)
+	// UpdateBook replaces a book's fields. A changed stock level is booked
+	// as a manual adjustment in the stock ledger.
 	UpdateBook(ctx context.Context, id int, book models.Book) (models.Book, error)
+	// AdjustPrice multiplies a book's price by factor without touching
+	// anything else.
+	AdjustPrice(ctx context.Context, id int, factor float64) (models.Book, error)
 	DeleteBook(ctx context.Context, id int) error
 	SearchBooks(ctx context.Context, criteria models.SearchCriteria) ([]models.Book, error)
 	ListBooks(ctx context.Context) ([]models.Book, error)
+	// ListBooksByAuthor lists the books an author is credited on, in any
+	// role.
+	ListBooksByAuthor(ctx context.Context, authorID int) ([]models.Book, error)
 }
 
 type AuthorStore interface {
@@ -24,14 +32,25 @@ type AuthorStore interface {
 	UpdateAuthor(ctx context.Context, id int, author models.Author) (models.Author, error)
 	DeleteAuthor(ctx context.Context, id int) error
 	ListAuthors(ctx context.Context) ([]models.Author, error)
+	// GetAuthorSummary counts the author's titles and the sales of orders
+	// placed between start and end. An unknown author gives ErrAuthorNotFound.
+	GetAuthorSummary(ctx context.Context, id int, start, end time.Time) (models.AuthorSummary, error)
 }
 
 type CustomerStore interface {
 	CreateCustomer(ctx context.Context, customer models.Customer) (models.Customer, error)
 	GetCustomer(ctx context.Context, id int) (models.Customer, error)
+	// UpdateCustomer refuses customers whose data was erased.
 	UpdateCustomer(ctx context.Context, id int, customer models.Customer) (models.Customer, error)
+	// DeleteCustomer fails with models.ErrCustomerHasOrders for a customer
+	// with orders; those are erased instead.
 	DeleteCustomer(ctx context.Context, id int) error
 	ListCustomers(ctx context.Context) ([]models.Customer, error)
+	// EraseCustomer anonymizes the customer's personal data and deletes
+	// their account, addresses and active cart. Orders are kept, with only
+	// the state and country of their addresses, for accounting and reports.
+	// The erasure is recorded in the audit log as done by actor.
+	EraseCustomer(ctx context.Context, id int, actor string) (models.Customer, error)
 }
 
 type OrderStore interface {
@@ -41,9 +60,187 @@ type OrderStore interface {
 	DeleteOrder(ctx context.Context, id int) error
 	ListOrders(ctx context.Context) ([]models.Order, error)
 	GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]models.Order, error)
+	// ListCustomerOrders returns a customer's orders, newest first.
+	ListCustomerOrders(ctx context.Context, customerID int) ([]models.Order, error)
+	// CancelOrder cancels a pending order and puts its items back into
+	// stock. It fails with models.ErrOrderNotCancellable otherwise.
+	CancelOrder(ctx context.Context, id int) (models.Order, error)
 }
 
 type ReportStore interface {
 	SaveReport(ctx context.Context, report models.SalesReport) error
 	GetReports(ctx context.Context, start, end time.Time) ([]models.SalesReport, error)
-}
\ No newline at end of file
+}
+
+type InventoryStore interface {
+	RecordMovement(ctx context.Context, movement models.StockMovement) (models.StockMovement, error)
+	ListMovements(ctx context.Context, bookID int) ([]models.StockMovement, error)
+	ReconcileStock(ctx context.Context, bookID int) (models.Book, error)
+	ListLowStock(ctx context.Context) ([]models.Book, error)
+	// CountStockAlerts counts the books out of stock and the books at or
+	// below their low-stock threshold.
+	CountStockAlerts(ctx context.Context) (outOfStock, lowStock int, err error)
+}
+
+type CartStore interface {
+	GetOrCreateCart(ctx context.Context, customerID int) (models.Cart, error)
+	GetCart(ctx context.Context, id int) (models.Cart, error)
+	AddItem(ctx context.Context, cartID, bookID, quantity int) (models.Cart, error)
+	SetItemQuantity(ctx context.Context, cartID, bookID, quantity int) (models.Cart, error)
+	RemoveItem(ctx context.Context, cartID, bookID int) (models.Cart, error)
+	DeleteCart(ctx context.Context, id int) error
+	BeginCheckout(ctx context.Context, id int) error
+	AbortCheckout(ctx context.Context, id int) error
+	// ReleaseStaleCheckouts returns carts whose checkout began before
+	// before, and never completed or aborted, to active.
+	ReleaseStaleCheckouts(ctx context.Context, before time.Time) (int64, error)
+	DeleteExpiredCarts(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PromotionStore interface {
+	CreatePromotion(ctx context.Context, promotion models.Promotion) (models.Promotion, error)
+	GetPromotion(ctx context.Context, id int) (models.Promotion, error)
+	UpdatePromotion(ctx context.Context, id int, promotion models.Promotion) (models.Promotion, error)
+	DeletePromotion(ctx context.Context, id int) error
+	ListPromotions(ctx context.Context) ([]models.Promotion, error)
+}
+
+type RateStore interface {
+	CreateTaxRule(ctx context.Context, rule models.TaxRule) (models.TaxRule, error)
+	UpdateTaxRule(ctx context.Context, id int, rule models.TaxRule) (models.TaxRule, error)
+	DeleteTaxRule(ctx context.Context, id int) error
+	ListTaxRules(ctx context.Context) ([]models.TaxRule, error)
+	CreateShippingRate(ctx context.Context, rate models.ShippingRate) (models.ShippingRate, error)
+	UpdateShippingRate(ctx context.Context, id int, rate models.ShippingRate) (models.ShippingRate, error)
+	DeleteShippingRate(ctx context.Context, id int) error
+	ListShippingRates(ctx context.Context) ([]models.ShippingRate, error)
+}
+
+type PaymentStore interface {
+	// BeginPayment starts a payment for the order's total. When the order
+	// already has an active payment, that payment is returned instead and
+	// created is false.
+	BeginPayment(ctx context.Context, orderID int, provider string) (payment models.Payment, created bool, err error)
+	UpdatePayment(ctx context.Context, id int, status, reference, reason string) (models.Payment, error)
+	// CompletePayment marks a pending or authorized payment captured and its
+	// order paid. A captured payment is returned as it is; a failed or
+	// refunded one gives ErrPaymentSettled.
+	CompletePayment(ctx context.Context, id int, reference string) (models.Payment, error)
+	// AbandonPayment fails a payment still pending since before, which no
+	// provider answer was ever recorded for, and reports whether it did.
+	AbandonPayment(ctx context.Context, id int, before time.Time) (bool, error)
+	GetPayment(ctx context.Context, id int) (models.Payment, error)
+	GetPaymentByReference(ctx context.Context, provider, reference string) (models.Payment, error)
+	ListPayments(ctx context.Context, orderID int) ([]models.Payment, error)
+}
+
+type ReturnStore interface {
+	CreateReturn(ctx context.Context, ret models.ReturnRequest) (models.ReturnRequest, error)
+	GetReturn(ctx context.Context, id int) (models.ReturnRequest, error)
+	// ListReturns lists return requests, optionally only those of one order
+	// or with one status; zero values match everything.
+	ListReturns(ctx context.Context, orderID int, status string) ([]models.ReturnRequest, error)
+	// ApproveReturn restocks the returned books and records the refund.
+	ApproveReturn(ctx context.Context, id int, note string) (models.ReturnRequest, error)
+	RejectReturn(ctx context.Context, id int, note string) (models.ReturnRequest, error)
+	GetRefund(ctx context.Context, id int) (models.Refund, error)
+	ListRefunds(ctx context.Context, orderID int) ([]models.Refund, error)
+	// ClaimRefund marks a pending or failed refund as sending, so that only
+	// one caller hands it to the provider. A refund claimed before stale,
+	// whose outcome was never recorded, can be claimed again.
+	ClaimRefund(ctx context.Context, id int, stale time.Time) (models.Refund, error)
+	CompleteRefund(ctx context.Context, id int, reference string) (models.Refund, error)
+	FailRefund(ctx context.Context, id int, reason string) (models.Refund, error)
+	GetRefundsInTimeRange(ctx context.Context, start, end time.Time) ([]models.Refund, error)
+}
+
+type IdempotencyStore interface {
+	// BeginRequest claims the record's key. If the key is already claimed
+	// and has not expired, the existing record is returned and created is
+	// false.
+	BeginRequest(ctx context.Context, record models.IdempotencyRecord) (existing models.IdempotencyRecord, created bool, err error)
+	CompleteRequest(ctx context.Context, record models.IdempotencyRecord) error
+	// ReleaseRequest forgets a key so that the request can be retried.
+	ReleaseRequest(ctx context.Context, username, key string) error
+	DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OutboxStore interface {
+	// Enqueue writes an event outside of any other change, for events that
+	// report on something rather than record it.
+	Enqueue(ctx context.Context, event interface{}) error
+	EventsAfter(ctx context.Context, afterID int64, limit int) ([]models.Event, error)
+	// PendingEvents returns undispatched events due for delivery at now,
+	// oldest first.
+	PendingEvents(ctx context.Context, now time.Time, limit int) ([]models.Event, error)
+	MarkEventDispatched(ctx context.Context, id int64) error
+	MarkEventFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	DeleteDispatchedEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookStore interface {
+	CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int) (models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, id int, sub models.WebhookSubscription) (models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int) error
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	// EnqueueDeliveries creates a delivery of the event for every active
+	// subscription to its type. Enqueuing the same event twice is a no-op.
+	EnqueueDeliveries(ctx context.Context, event models.Event) error
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// RecordAttempt saves the outcome of sending a delivery.
+	RecordAttempt(ctx context.Context, delivery models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id int) (models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, subscriptionID int) ([]models.WebhookDelivery, error)
+	// Redeliver queues a new delivery of the same event.
+	Redeliver(ctx context.Context, deliveryID int) (models.WebhookDelivery, error)
+}
+
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	GetAPIKey(ctx context.Context, id int) (models.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	// RevokeAPIKey stops the key from being accepted. It stays listed.
+	RevokeAPIKey(ctx context.Context, id int) (models.APIKey, error)
+	// TouchAPIKey records that the key was used at the given time.
+	TouchAPIKey(ctx context.Context, id int, at time.Time) error
+}
+
+type AccountStore interface {
+	// CreateAccount registers a new customer together with the account
+	// they sign in with. A taken email gives models.ErrEmailTaken.
+	CreateAccount(ctx context.Context, account models.Account, customer models.Customer) (models.Account, models.Customer, error)
+	GetAccountByEmail(ctx context.Context, email string) (models.Account, error)
+	GetAccountByCustomer(ctx context.Context, customerID int) (models.Account, error)
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+}
+
+// AddressStore keeps customers' saved addresses. Every method is scoped to
+// a customer, so an address of another customer is not found.
+type AddressStore interface {
+	// CreateAddress saves an address. The customer's first address becomes
+	// their default for shipping and billing.
+	CreateAddress(ctx context.Context, address models.CustomerAddress) (models.CustomerAddress, error)
+	GetAddress(ctx context.Context, customerID, id int) (models.CustomerAddress, error)
+	// UpdateAddress replaces an address. Making it a default takes the
+	// flag away from the customer's previous default.
+	UpdateAddress(ctx context.Context, customerID, id int, address models.CustomerAddress) (models.CustomerAddress, error)
+	DeleteAddress(ctx context.Context, customerID, id int) error
+	ListAddresses(ctx context.Context, customerID int) ([]models.CustomerAddress, error)
+}
+
+type AuditStore interface {
+	RecordAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error)
+	// ListAudit returns a customer's audit entries, oldest first.
+	ListAudit(ctx context.Context, customerID int) ([]models.AuditEntry, error)
+}
+
+type WorkStore interface {
+	CreateWork(ctx context.Context, work models.Work) (models.Work, error)
+	GetWork(ctx context.Context, id int) (models.Work, error)
+	UpdateWork(ctx context.Context, id int, work models.Work) (models.Work, error)
+	// DeleteWork deletes a work; its editions remain as ungrouped books.
+	DeleteWork(ctx context.Context, id int) error
+	ListWorks(ctx context.Context) ([]models.Work, error)
+}
@@ -0,0 +1,89 @@
+// Package isbn validates International Standard Book Numbers.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrFormat   = errors.New("isbn must have 10 or 13 digits")
+	ErrPrefix   = errors.New("isbn-13 must start with 978 or 979")
+	ErrChecksum = errors.New("isbn check digit is wrong")
+)
+
+// Normalize validates an ISBN-10 or ISBN-13, ignoring hyphens and spaces,
+// and returns it as a bare ISBN-13. An ISBN-10 and the ISBN-13 it was
+// converted to name the same book, so they normalize to the same string.
+func Normalize(s string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(s)))
+
+	switch len(digits) {
+	case 10:
+		if !allDigits(digits[:9]) {
+			return "", ErrFormat
+		}
+		if !valid10(digits) {
+			return "", ErrChecksum
+		}
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(check13(isbn13)), nil
+	case 13:
+		if !allDigits(digits) {
+			return "", ErrFormat
+		}
+		if !strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979") {
+			return "", ErrPrefix
+		}
+		if check13(digits[:12]) != digits[12] {
+			return "", ErrChecksum
+		}
+		return digits, nil
+	default:
+		return "", ErrFormat
+	}
+}
+
+// valid10 checks the check digit of an ISBN-10, which may be X for ten.
+func valid10(s string) bool {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(s[i]-'0') * (10 - i)
+	}
+	switch c := s[9]; {
+	case c == 'X':
+		sum += 10
+	case c >= '0' && c <= '9':
+		sum += int(c - '0')
+	default:
+		return false
+	}
+	return sum%11 == 0
+}
+
+// check13 returns the check digit for the first twelve digits of an ISBN-13.
+func check13(s string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(s[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
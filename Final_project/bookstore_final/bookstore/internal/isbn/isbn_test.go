@@ -0,0 +1,40 @@
+package isbn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr error
+	}{
+		{"isbn-13", "9780306406157", "9780306406157", nil},
+		{"isbn-13 with hyphens", "978-0-306-40615-7", "9780306406157", nil},
+		{"isbn-13 with spaces", " 978 0 306 40615 7 ", "9780306406157", nil},
+		{"979 prefix", "9791090636071", "9791090636071", nil},
+		{"isbn-10 converted", "0306406152", "9780306406157", nil},
+		{"isbn-10 with hyphens", "0-306-40615-2", "9780306406157", nil},
+		{"isbn-10 check digit X", "080442957X", "9780804429573", nil},
+		{"isbn-10 lower-case x", "080442957x", "9780804429573", nil},
+		{"isbn-13 check digit zero", "9780000000040", "9780000000040", nil},
+		{"isbn-13 wrong check digit", "9780306406158", "", ErrChecksum},
+		{"isbn-10 wrong check digit", "0306406153", "", ErrChecksum},
+		{"X only as isbn-10 check digit", "03064061X2", "", ErrFormat},
+		{"bad prefix", "9770306406150", "", ErrPrefix},
+		{"letters", "978030640615A", "", ErrFormat},
+		{"too short", "978030640615", "", ErrFormat},
+		{"empty", "", "", ErrFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.in)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Normalize(%q) = %q, %v; want %q, %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
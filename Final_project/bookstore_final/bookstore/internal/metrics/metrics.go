@@ -0,0 +1,192 @@
+// Package metrics exposes Prometheus metrics for the HTTP API, the stores,
+// the database pool, sales and the sales reporter.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"bookstore/internal/interfaces"
+)
+
+const namespace = "bookstore"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	storeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_operation_duration_seconds",
+		Help:      "Latency of store operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"store", "operation"})
+
+	storeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_operation_errors_total",
+		Help:      "Store operations that returned an error.",
+	}, []string{"store", "operation"})
+
+	ordersCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_created_total",
+		Help:      "Orders created.",
+	})
+
+	revenue = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "order_revenue_total",
+		Help:      "Sum of the totals of created orders.",
+	})
+
+	reportDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sales_report_duration_seconds",
+		Help:      "Duration of sales report runs.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	reportFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sales_report_failures_total",
+		Help:      "Sales report runs that failed.",
+	})
+
+	reportLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sales_report_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful sales report.",
+	})
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDB exports the connection pool statistics of db and the stock-out
+// gauges, which are read from the inventory store at scrape time.
+func RegisterDB(db *sql.DB, inventoryStore interfaces.InventoryStore) {
+	prometheus.MustRegister(
+		collectors.NewDBStatsCollector(db, namespace),
+		&stockCollector{inventoryStore: inventoryStore},
+	)
+}
+
+// Middleware records request counts and latencies. It must be installed
+// with Router.Use so that the matched route template is known; labelling by
+// template rather than path keeps IDs out of the label values.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		status := strconv.Itoa(rec.status)
+		httpRequests.WithLabelValues(r.Method, route, status).Inc()
+		httpDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveReport records one sales reporter run.
+func ObserveReport(duration time.Duration, err error) {
+	reportDuration.Observe(duration.Seconds())
+	if err != nil {
+		reportFailures.Inc()
+		return
+	}
+	reportLastSuccess.SetToCurrentTime()
+}
+
+// ObserveStore records one store call.
+func ObserveStore(store, operation string, duration time.Duration, err error) {
+	storeDuration.WithLabelValues(store, operation).Observe(duration.Seconds())
+	if err != nil {
+		storeErrors.WithLabelValues(store, operation).Inc()
+	}
+}
+
+// RecordOrder counts a created order and its total.
+func RecordOrder(total float64) {
+	ordersCreated.Inc()
+	revenue.Add(total)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status = code
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(buf []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.ResponseWriter.Write(buf)
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+var (
+	outOfStockDesc = prometheus.NewDesc(namespace+"_books_out_of_stock",
+		"Books with no stock left.", nil, nil)
+	lowStockDesc = prometheus.NewDesc(namespace+"_books_low_stock",
+		"Books at or below their low-stock threshold.", nil, nil)
+)
+
+type stockCollector struct {
+	inventoryStore interfaces.InventoryStore
+}
+
+func (c *stockCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- outOfStockDesc
+	ch <- lowStockDesc
+}
+
+func (c *stockCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	outOfStock, lowStock, err := c.inventoryStore.CountStockAlerts(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(outOfStockDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(outOfStockDesc, prometheus.GaugeValue, float64(outOfStock))
+	ch <- prometheus.MustNewConstMetric(lowStockDesc, prometheus.GaugeValue, float64(lowStock))
+}
@@ -1,9 +1,107 @@
 
 	package models
 	import (
+		"encoding/json"
+		"errors"
 		"time"
 	)
 
+	// ErrInsufficientStock is returned when a stock movement would take a
+	// book's stock below zero.
+	var ErrInsufficientStock = errors.New("insufficient stock")
+
+	// ErrBookNotFound is returned when a book, or a stock movement for
+	// one, names a book that does not exist.
+	var ErrBookNotFound = errors.New("book not found")
+
+	// ErrAuthorNotFound is returned when an author does not exist.
+	var ErrAuthorNotFound = errors.New("author not found")
+
+	// ErrCartNotActive is returned when a cart that has already been checked
+	// out, or is being checked out, is modified.
+	var ErrCartNotActive = errors.New("cart is not active")
+
+	// ErrCartExpired is returned when a cart has outlived its expiry time.
+	var ErrCartExpired = errors.New("cart has expired")
+
+	// ErrInvalidCoupon is returned when a coupon code is unknown, expired,
+	// used up or does not apply to the order.
+	var ErrInvalidCoupon = errors.New("invalid coupon")
+
+	// ErrOrderNotPayable is returned when payment is attempted for an order
+	// that is neither pending nor already paid.
+	var ErrOrderNotPayable = errors.New("order cannot be paid")
+
+	// ErrOrderNotReturnable is returned when a return is requested for an
+	// order that has not been paid or delivered.
+	var ErrOrderNotReturnable = errors.New("order cannot be returned")
+
+	// ErrOrderHasReturns is returned when an order with return requests is
+	// changed or deleted.
+	var ErrOrderHasReturns = errors.New("order has return requests")
+
+	// ErrInvalidReturn is returned when a return names books that are not on
+	// the order or more copies than are left to return.
+	var ErrInvalidReturn = errors.New("invalid return")
+
+	// ErrReturnDecided is returned when a return request that was already
+	// approved or rejected is decided again.
+	var ErrReturnDecided = errors.New("return request already decided")
+
+	// ErrRefundClaimed is returned when a refund that is being sent to the
+	// provider, or was already completed, is sent again.
+	var ErrRefundClaimed = errors.New("refund already sent or being sent")
+
+	// ErrOrderNotCancellable is returned when an order that is no longer
+	// pending, or has a payment in progress, is cancelled.
+	var ErrOrderNotCancellable = errors.New("order cannot be cancelled")
+
+	// ErrOrderCancelled is returned when a cancelled order, whose books are
+	// already back in stock, is updated or deleted.
+	var ErrOrderCancelled = errors.New("order is cancelled")
+
+	// ErrOrderStatusChange is returned when an order update changes the
+	// status other than from paid to delivered. Payments and cancellation
+	// change it through their own operations.
+	var ErrOrderStatusChange = errors.New("order status cannot be changed this way")
+
+	// ErrOrderHasPayment is returned when an order that has a payment,
+	// other than a failed one, is deleted or has its items or customer
+	// changed. The payment was for what the order held at the time.
+	var ErrOrderHasPayment = errors.New("order has a payment")
+
+	// ErrPaymentSettled is returned when a payment that already failed or
+	// was refunded is completed.
+	var ErrPaymentSettled = errors.New("payment already settled")
+
+	// ErrCustomerErased is returned when the data of a customer whose
+	// personal data was erased is changed.
+	var ErrCustomerErased = errors.New("customer data was erased")
+
+	// ErrEmailTaken is returned when an account is registered with an email
+	// that already has one.
+	var ErrEmailTaken = errors.New("email is already registered")
+
+	// ErrUnknownAddress is returned when an order names an address that is
+	// not one of its customer's.
+	var ErrUnknownAddress = errors.New("address does not belong to the customer")
+
+	// ErrCustomerHasOrders is returned when a customer with orders is
+	// deleted. Such customers are erased instead, keeping the orders.
+	var ErrCustomerHasOrders = errors.New("customer has orders")
+
+	// ErrISBNTaken is returned when a book is given an ISBN that another
+	// book already has.
+	var ErrISBNTaken = errors.New("isbn is already used by another book")
+
+	// ErrUnknownWork is returned when a book names a work that does not
+	// exist.
+	var ErrUnknownWork = errors.New("work does not exist")
+
+	// ErrUnknownAuthor is returned when a book names an author, or other
+	// contributor, that does not exist.
+	var ErrUnknownAuthor = errors.New("author does not exist")
+
 	type Book struct {
 		ID          int       `json:"id"`
 		Title       string    `json:"title"`
@@ -12,6 +110,70 @@
 		PublishedAt time.Time `json:"published_at"`
 		Price       float64   `json:"price"`
 		Stock       int       `json:"stock"`
+
+		LowStockThreshold int `json:"low_stock_threshold"`
+		WeightGrams       int `json:"weight_grams"`
+
+		// ISBN is stored as a bare ISBN-13; an ISBN-10 is converted.
+		ISBN        string `json:"isbn,omitempty"`
+		Publisher   string `json:"publisher,omitempty"`
+		Language    string `json:"language,omitempty"`
+		Pages       int    `json:"pages,omitempty"`
+		Format      string `json:"format,omitempty"`
+		Description string `json:"description,omitempty"`
+		CoverURL    string `json:"cover_url,omitempty"`
+		// WorkID groups the editions of the same work; nil for a book
+		// that is not grouped.
+		WorkID *int `json:"work_id,omitempty"`
+
+		// Contributors lists everyone credited on the book, in credit
+		// order. Author is the first of them with the author role.
+		Contributors []Contributor `json:"contributors"`
+	}
+
+	// Contributor roles.
+	const (
+		RoleAuthor      = "author"
+		RoleEditor      = "editor"
+		RoleTranslator  = "translator"
+		RoleIllustrator = "illustrator"
+	)
+
+	// AuthorSummary is an author's bibliography size and sales over a
+	// period. Every book the author is credited on counts in full, in any
+	// role. Sales are at the unit prices paid, before order discounts and
+	// without tax or shipping; cancelled orders are left out and approved
+	// returns are taken off.
+	type AuthorSummary struct {
+		Author        Author    `json:"author"`
+		Start         time.Time `json:"start"`
+		End           time.Time `json:"end"`
+		Titles        int       `json:"titles"`
+		UnitsSold     int       `json:"units_sold"`
+		UnitsReturned int       `json:"units_returned"`
+		Revenue       float64   `json:"revenue"`
+	}
+
+	// Contributor credits an author with a role on a book.
+	type Contributor struct {
+		Author Author `json:"author"`
+		Role   string `json:"role"`
+	}
+
+	// Book formats.
+	const (
+		FormatHardcover = "hardcover"
+		FormatPaperback = "paperback"
+		FormatEbook     = "ebook"
+	)
+
+	// Work is a title independent of its editions, such as the hardcover,
+	// paperback and ebook of one novel.
+	type Work struct {
+		ID        int       `json:"id"`
+		Title     string    `json:"title"`
+		CreatedAt time.Time `json:"created_at"`
+		Editions  []Book    `json:"editions,omitempty"`
 	}
 
 	type Author struct {
@@ -21,12 +183,16 @@
 		Bio       string `json:"bio"`
 	}
 
+	// Customer is a buyer. Address is their contact address, which orders
+	// fall back to when the customer has no saved addresses.
 	type Customer struct {
 		ID        int       `json:"id"`
 		Name      string    `json:"name"`
 		Email     string    `json:"email"`
 		Address   Address   `json:"address"`
 		CreatedAt time.Time `json:"created_at"`
+		// ErasedAt is set once the customer's personal data has been erased.
+		ErasedAt *time.Time `json:"erased_at,omitempty"`
 	}
 
 	type Address struct {
@@ -37,6 +203,18 @@
 		Country    string `json:"country"`
 	}
 
+	// CustomerAddress is one of a customer's saved addresses. A customer
+	// has at most one default shipping and one default billing address.
+	type CustomerAddress struct {
+		ID         int    `json:"id"`
+		CustomerID int    `json:"customer_id"`
+		Label      string `json:"label,omitempty"`
+		Address
+		DefaultShipping bool      `json:"default_shipping"`
+		DefaultBilling  bool      `json:"default_billing"`
+		CreatedAt       time.Time `json:"created_at"`
+	}
+
 	type Order struct {
 		ID         int         `json:"id"`
 		Customer   Customer    `json:"customer"`
@@ -44,17 +222,252 @@
 		TotalPrice float64     `json:"total_price"`
 		CreatedAt  time.Time   `json:"created_at"`
 		Status     string      `json:"status"`
+
+		// ShippingAddressID and BillingAddressID pick saved addresses of the
+		// customer; by default their default addresses are used. The chosen
+		// addresses are copied onto the order, so later changes to the
+		// customer's addresses do not affect it.
+		ShippingAddressID int      `json:"shipping_address_id,omitempty"`
+		BillingAddressID  int      `json:"billing_address_id,omitempty"`
+		ShippingAddress   *Address `json:"shipping_address,omitempty"`
+		BillingAddress    *Address `json:"billing_address,omitempty"`
+
+		// Subtotal is the undiscounted sum of the items. TotalPrice is what
+		// the customer pays: Subtotal - DiscountTotal + ShippingTotal + TaxTotal.
+		Subtotal      float64         `json:"subtotal"`
+		DiscountTotal float64         `json:"discount_total"`
+		Discounts     []OrderDiscount `json:"discounts"`
+		CouponCode    string          `json:"coupon_code,omitempty"`
+		ShippingTotal float64         `json:"shipping_total"`
+		TaxRate       float64         `json:"tax_rate"`
+		TaxTotal      float64         `json:"tax_total"`
+
+		// CartID is the cart being checked out, if any. The cart is closed
+		// together with the order's creation.
+		CartID int `json:"-"`
+	}
+
+	// Order statuses.
+	const (
+		OrderPending   = "pending"
+		OrderPaid      = "paid"
+		OrderDelivered = "delivered"
+		OrderCancelled = "cancelled"
+	)
+
+	// Payment statuses.
+	const (
+		PaymentPending    = "pending"
+		PaymentAuthorized = "authorized"
+		PaymentCaptured   = "captured"
+		PaymentFailed     = "failed"
+		PaymentRefunded   = "refunded"
+	)
+
+	// Payment is one attempt to charge an order through a payment provider.
+	// At most one pending, authorized or captured payment exists per order;
+	// failed attempts are kept for the record.
+	type Payment struct {
+		ID            int       `json:"id"`
+		OrderID       int       `json:"order_id"`
+		Provider      string    `json:"provider"`
+		Status        string    `json:"status"`
+		Amount        float64   `json:"amount"`
+		Reference     string    `json:"reference,omitempty"`
+		FailureReason string    `json:"failure_reason,omitempty"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+	}
+
+	// TaxRule is a sales tax rate for a country, or for one state of it when
+	// State is set. Rate is a fraction, e.g. 0.0825 for 8.25%.
+	type TaxRule struct {
+		ID          int     `json:"id"`
+		Country     string  `json:"country"`
+		State       string  `json:"state,omitempty"`
+		Rate        float64 `json:"rate"`
+		TaxShipping bool    `json:"tax_shipping"`
+	}
+
+	// ShippingRate is one bracket of a shipping rate table. An empty Country
+	// is the fallback for countries without their own brackets; zero
+	// MaxWeightGrams or MaxItems means no limit.
+	type ShippingRate struct {
+		ID             int     `json:"id"`
+		Country        string  `json:"country"`
+		MaxWeightGrams int     `json:"max_weight_grams"`
+		MaxItems       int     `json:"max_items"`
+		BaseFee        float64 `json:"base_fee"`
+		PerItem        float64 `json:"per_item"`
+		PerKg          float64 `json:"per_kg"`
+		FreeOver       float64 `json:"free_over"`
+	}
+
+	// OrderDiscount is a discount line recorded on an order.
+	type OrderDiscount struct {
+		PromotionID int     `json:"promotion_id"`
+		Code        string  `json:"code,omitempty"`
+		Description string  `json:"description"`
+		Amount      float64 `json:"amount"`
+	}
+
+	// Promotion kinds.
+	const (
+		PromotionPercentage = "percentage"
+		PromotionFixed      = "fixed"
+	)
+
+	// Promotion is either a coupon, applied when the customer supplies its
+	// Code, or an automatic promotion (empty Code) applied to every order that
+	// qualifies. Genre and AuthorID narrow the discount to matching items,
+	// AuthorID to books crediting the author in any role; when both are
+	// empty it applies to the whole order.
+	type Promotion struct {
+		ID                 int        `json:"id"`
+		Name               string     `json:"name"`
+		Code               string     `json:"code,omitempty"`
+		Kind               string     `json:"kind"`
+		Value              float64    `json:"value"`
+		MinOrder           float64    `json:"min_order"`
+		Genre              string     `json:"genre,omitempty"`
+		AuthorID           int        `json:"author_id,omitempty"`
+		StartsAt           *time.Time `json:"starts_at,omitempty"`
+		ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+		MaxUses            int        `json:"max_uses"`
+		MaxUsesPerCustomer int        `json:"max_uses_per_customer"`
+		TimesUsed          int        `json:"times_used"`
+		Active             bool       `json:"active"`
+		CreatedAt          time.Time  `json:"created_at"`
 	}
 
 	type OrderItem struct {
 		Book     Book `json:"book"`
 		Quantity int  `json:"quantity"`
+		// UnitPrice is the book's price when the order was last priced.
+		UnitPrice float64 `json:"unit_price"`
+	}
+
+	// Return request statuses.
+	const (
+		ReturnRequested = "requested"
+		ReturnApproved  = "approved"
+		ReturnRejected  = "rejected"
+	)
+
+	// ReturnRequest asks to send back some of the books of an order. Staff
+	// approve it, which restocks the books and refunds them, or reject it.
+	type ReturnRequest struct {
+		ID           int          `json:"id"`
+		OrderID      int          `json:"order_id"`
+		Status       string       `json:"status"`
+		Reason       string       `json:"reason"`
+		Items        []ReturnItem `json:"items"`
+		Note         string       `json:"note,omitempty"`
+		RefundAmount float64      `json:"refund_amount"`
+		Refund       *Refund      `json:"refund,omitempty"`
+		CreatedAt    time.Time    `json:"created_at"`
+		DecidedAt    *time.Time   `json:"decided_at,omitempty"`
+		DecidedBy    string       `json:"decided_by,omitempty"`
+	}
+
+	type ReturnItem struct {
+		BookID    int     `json:"book_id"`
+		Quantity  int     `json:"quantity"`
+		Reason    string  `json:"reason,omitempty"`
+		UnitPrice float64 `json:"unit_price"`
+	}
+
+	// Refund statuses.
+	const (
+		RefundPending   = "pending"
+		RefundSending   = "sending"
+		RefundCompleted = "completed"
+		RefundFailed    = "failed"
+	)
+
+	// Refund is money given back for an approved return. Refunds of orders
+	// paid through a provider are sent to it and stay pending until it
+	// accepts them; refunds of other orders are settled outside the system
+	// and are completed straight away. TaxAmount is the part of Amount that
+	// refunds tax.
+	type Refund struct {
+		ID            int        `json:"id"`
+		OrderID       int        `json:"order_id"`
+		ReturnID      int        `json:"return_id"`
+		PaymentID     *int       `json:"payment_id,omitempty"`
+		Amount        float64    `json:"amount"`
+		TaxAmount     float64    `json:"tax_amount"`
+		Status        string     `json:"status"`
+		Reference     string     `json:"reference,omitempty"`
+		FailureReason string     `json:"failure_reason,omitempty"`
+		CreatedAt     time.Time  `json:"created_at"`
+		CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	}
+
+	// Cart statuses.
+	const (
+		CartActive      = "active"
+		CartCheckingOut = "checking_out"
+		CartCheckedOut  = "checked_out"
+	)
+
+	// Cart is a customer's basket before checkout. Prices and stock of the
+	// items are read live from the catalog every time the cart is loaded.
+	type Cart struct {
+		ID         int        `json:"id"`
+		CustomerID int        `json:"customer_id"`
+		Items      []CartItem `json:"items"`
+		Subtotal   float64    `json:"subtotal"`
+		Status     string     `json:"status"`
+		OrderID    *int       `json:"order_id,omitempty"`
+		CreatedAt  time.Time  `json:"created_at"`
+		UpdatedAt  time.Time  `json:"updated_at"`
+		ExpiresAt  time.Time  `json:"expires_at"`
+	}
+
+	type CartItem struct {
+		Book      Book    `json:"book"`
+		Quantity  int     `json:"quantity"`
+		LineTotal float64 `json:"line_total"`
+		Available bool    `json:"available"`
+	}
+
+	// Stock movement reasons recorded in the stock ledger.
+	const (
+		MovementOrder        = "order"
+		MovementCancellation = "cancellation"
+		MovementRestock      = "restock"
+		MovementAdjustment   = "adjustment"
+		MovementReturn       = "return"
+	)
+
+	// StockMovement is a single entry in a book's stock ledger. Change is
+	// signed: negative for stock leaving the store, positive for stock coming
+	// back in. Balance is the book's stock right after the movement.
+	type StockMovement struct {
+		ID        int       `json:"id"`
+		BookID    int       `json:"book_id"`
+		Change    int       `json:"change"`
+		Balance   int       `json:"balance"`
+		Reason    string    `json:"reason"`
+		Actor     string    `json:"actor"`
+		OrderID   *int      `json:"order_id,omitempty"`
+		Note      string    `json:"note,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
 	}
 
 	type SalesReport struct {
 		Timestamp     time.Time   `json:"timestamp"`
 		TotalRevenue  float64     `json:"total_revenue"`
 		TotalOrders   int         `json:"total_orders"`
+		GrossRevenue   float64    `json:"gross_revenue"`
+		TotalDiscounts float64    `json:"total_discounts"`
+		TotalShipping  float64    `json:"total_shipping"`
+		TotalTax       float64    `json:"total_tax"`
+		// TotalRefunds are the refunds completed in the period; NetRevenue is
+		// TotalRevenue less the refunded amounts other than tax.
+		TotalRefunds   float64    `json:"total_refunds"`
+		NetRevenue     float64    `json:"net_revenue"`
 		TopSellingBooks []BookSales `json:"top_selling_books"`
 	}
 
@@ -74,9 +487,151 @@
 	PublishedAfter  *time.Time
 	MinStock        int
 	MaxStock        int
+	ISBN            string
+	Publisher       string
+	Language        string
+	Format          string
+	MinPages        int
+	MaxPages        int
+	WorkID          int
 }
 
 
+	// Event is a domain event stored in the outbox. Payload is the JSON
+	// encoding of the typed event named by Type; see package events.
+	type Event struct {
+		ID        int64           `json:"id"`
+		Type      string          `json:"type"`
+		Payload   json.RawMessage `json:"payload"`
+		CreatedAt time.Time       `json:"created_at"`
+		Attempts  int             `json:"-"`
+	}
+
+	// WebhookSubscription sends the events listed in EventTypes ("*" for
+	// all) to URL. Deliveries are signed with Secret, which is only shown
+	// when the subscription is created.
+	type WebhookSubscription struct {
+		ID         int       `json:"id"`
+		URL        string    `json:"url"`
+		EventTypes []string  `json:"event_types"`
+		Secret     string    `json:"secret,omitempty"`
+		Active     bool      `json:"active"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	// Webhook delivery statuses.
+	const (
+		DeliveryPending   = "pending"
+		DeliverySucceeded = "succeeded"
+		DeliveryFailed    = "failed"
+	)
+
+	// WebhookDelivery is one event sent, or to be sent, to a subscription.
+	// It records the outcome of the latest attempt. A manual redelivery is a
+	// new delivery pointing at the original through RedeliveryOf.
+	type WebhookDelivery struct {
+		ID             int             `json:"id"`
+		SubscriptionID int             `json:"subscription_id"`
+		EventID        int64           `json:"event_id"`
+		EventType      string          `json:"event_type"`
+		Payload        json.RawMessage `json:"payload"`
+		Status         string          `json:"status"`
+		Attempts       int             `json:"attempts"`
+		ResponseCode   int             `json:"response_code,omitempty"`
+		ResponseBody   string          `json:"response_body,omitempty"`
+		LastError      string          `json:"last_error,omitempty"`
+		NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+		CreatedAt      time.Time       `json:"created_at"`
+		DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+		RedeliveryOf   *int            `json:"redelivery_of,omitempty"`
+		EventCreatedAt time.Time       `json:"-"`
+
+		// URL and Secret are copied from the subscription for sending.
+		URL    string `json:"-"`
+		Secret string `json:"-"`
+	}
+
+	// Idempotency key states.
+	const (
+		IdempotencyInProgress = "in_progress"
+		IdempotencyCompleted  = "completed"
+	)
+
+	// IdempotencyRecord remembers the response to a POST sent with an
+	// Idempotency-Key header, so that a retry gets the same response instead
+	// of repeating the request. Keys are scoped to the user who sent them.
+	type IdempotencyRecord struct {
+		Username       string
+		Key            string
+		Method         string
+		Path           string
+		RequestHash    string
+		Status         string
+		ResponseStatus int
+		ContentType    string
+		ResponseBody   []byte
+		CreatedAt      time.Time
+		ExpiresAt      time.Time
+	}
+
+	// APIKey lets a service call the API without logging in. Only a hash
+	// of the key is stored: Key holds the full key in the response that
+	// creates it and nowhere else. Prefix identifies the key in lists and
+	// logs.
+	type APIKey struct {
+		ID         int        `json:"id"`
+		Name       string     `json:"name"`
+		Prefix     string     `json:"prefix"`
+		Key        string     `json:"key,omitempty"`
+		Hash       string     `json:"-"`
+		Scopes     []string   `json:"scopes"`
+		CreatedBy  string     `json:"created_by"`
+		CreatedAt  time.Time  `json:"created_at"`
+		ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+		LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+		RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	}
+
+	// Account lets a customer sign in to the self-service API. Email is the
+	// sign-in name; the account belongs to exactly one customer.
+	type Account struct {
+		ID           int       `json:"id"`
+		Email        string    `json:"email"`
+		PasswordHash string    `json:"-"`
+		CustomerID   int       `json:"customer_id"`
+		CreatedAt    time.Time `json:"created_at"`
+	}
+
+	// Audit actions.
+	const (
+		AuditCustomerExported = "customer.exported"
+		AuditCustomerErased   = "customer.erased"
+	)
+
+	// AuditEntry records an action taken on a customer's data, such as an
+	// export or an erasure, and who took it.
+	type AuditEntry struct {
+		ID         int64     `json:"id"`
+		CustomerID int       `json:"customer_id"`
+		Action     string    `json:"action"`
+		Actor      string    `json:"actor"`
+		Detail     string    `json:"detail,omitempty"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	// CustomerExport is everything held about a customer, as returned to a
+	// data access request.
+	type CustomerExport struct {
+		ExportedAt time.Time         `json:"exported_at"`
+		Customer   Customer          `json:"customer"`
+		Account    *Account          `json:"account,omitempty"`
+		Addresses  []CustomerAddress `json:"addresses"`
+		Orders     []Order           `json:"orders"`
+		Payments   []Payment         `json:"payments"`
+		Returns    []ReturnRequest   `json:"returns"`
+		Audit      []AuditEntry      `json:"audit"`
+	}
+
 	type ErrorResponse struct {
 		Error string `json:"error"`
 	}
\ No newline at end of file
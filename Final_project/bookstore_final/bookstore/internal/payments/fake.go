@@ -0,0 +1,162 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Payment method tokens understood by FakeProvider. Any other token is
+// treated like FakeTokenSuccess.
+const (
+	FakeTokenSuccess = "tok_success"
+	FakeTokenDecline = "tok_decline"
+	FakeTokenTimeout = "tok_timeout"
+)
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of a fake webhook body.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeProvider is an in-memory provider for local development and tests.
+// The payment method token picks the outcome: tok_decline is declined and
+// tok_timeout hangs until the caller's context expires. Webhooks are JSON
+// WebhookEvent bodies signed with the shared secret.
+type FakeProvider struct {
+	secret []byte
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*fakePayment
+	refunds  map[string]Result
+}
+
+type fakePayment struct {
+	amount   float64
+	captured float64
+	refunded float64
+	voided   bool
+}
+
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{
+		secret:   []byte(secret),
+		payments: make(map[string]*fakePayment),
+		refunds:  make(map[string]Result),
+	}
+}
+
+func (f *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (f *FakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Result, error) {
+	switch req.Method {
+	case FakeTokenDecline:
+		return Result{}, fmt.Errorf("%w: card declined by issuer", ErrDeclined)
+	case FakeTokenTimeout:
+		<-ctx.Done()
+		return Result{}, fmt.Errorf("%w: %v", ErrTimeout, ctx.Err())
+	}
+	if req.Amount <= 0 {
+		return Result{}, fmt.Errorf("%w: invalid amount %.2f", ErrDeclined, req.Amount)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	ref := fmt.Sprintf("fake_%d_%d", time.Now().Unix(), f.seq)
+	f.payments[ref] = &fakePayment{amount: req.Amount}
+	return Result{Reference: ref, Amount: req.Amount}, nil
+}
+
+func (f *FakeProvider) Capture(ctx context.Context, reference string, amount float64) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[reference]
+	if !ok {
+		return Result{}, fmt.Errorf("unknown payment reference %q", reference)
+	}
+	if p.voided {
+		return Result{}, fmt.Errorf("%w: authorization was voided", ErrDeclined)
+	}
+	if p.captured == p.amount && amount == p.amount {
+		return Result{Reference: reference, Amount: amount}, nil
+	}
+	if amount > p.amount-p.captured {
+		return Result{}, fmt.Errorf("%w: capture exceeds authorized amount", ErrDeclined)
+	}
+	p.captured += amount
+	return Result{Reference: reference, Amount: amount}, nil
+}
+
+func (f *FakeProvider) Void(ctx context.Context, reference string) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[reference]
+	if !ok {
+		return Result{}, fmt.Errorf("unknown payment reference %q", reference)
+	}
+	if p.captured > 0 {
+		return Result{}, fmt.Errorf("%w: payment is already captured", ErrDeclined)
+	}
+	p.voided = true
+	return Result{Reference: reference}, nil
+}
+
+func (f *FakeProvider) Refund(ctx context.Context, reference, key string, amount float64) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if res, ok := f.refunds[key]; ok {
+		return res, nil
+	}
+
+	p, ok := f.payments[reference]
+	if !ok {
+		return Result{}, fmt.Errorf("unknown payment reference %q", reference)
+	}
+	if amount > p.captured-p.refunded {
+		return Result{}, fmt.Errorf("%w: refund exceeds captured amount", ErrDeclined)
+	}
+	p.refunded += amount
+	f.refunds[key] = Result{Reference: reference, Amount: amount}
+	return f.refunds[key], nil
+}
+
+func (f *FakeProvider) ParseWebhook(r *http.Request) (WebhookEvent, error) {
+	var event WebhookEvent
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return event, err
+	}
+	sig, err := hex.DecodeString(r.Header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(sig, f.sign(body)) {
+		return event, ErrInvalidSignature
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return event, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	return event, nil
+}
+
+// SignWebhook returns the signature header value for a webhook body, so
+// scripts and tests can simulate provider callbacks.
+func (f *FakeProvider) SignWebhook(body []byte) string {
+	return hex.EncodeToString(f.sign(body))
+}
+
+func (f *FakeProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
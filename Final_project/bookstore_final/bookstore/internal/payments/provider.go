@@ -0,0 +1,65 @@
+// Package payments abstracts the payment service provider used to charge
+// orders. Handlers only talk to the Provider interface; the provider in use
+// is chosen in main.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrDeclined is returned when the provider refuses the charge.
+	ErrDeclined = errors.New("payment declined")
+	// ErrTimeout is returned when the provider does not answer in time.
+	ErrTimeout = errors.New("payment provider timed out")
+	// ErrInvalidSignature is returned for webhooks that fail verification.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Webhook event types understood by the payment handler.
+const (
+	EventCaptured = "payment.captured"
+	EventFailed   = "payment.failed"
+	EventRefunded = "payment.refunded"
+)
+
+// AuthorizeRequest describes a charge to authorize. Method is the
+// provider-specific payment method token sent by the client.
+type AuthorizeRequest struct {
+	OrderID int
+	Amount  float64
+	Method  string
+}
+
+// Result is the provider's answer to an operation. Reference identifies the
+// payment at the provider and is stable across authorize, capture and
+// refund.
+type Result struct {
+	Reference string
+	Amount    float64
+}
+
+// WebhookEvent is a verified notification sent by the provider.
+type WebhookEvent struct {
+	Type      string  `json:"type"`
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	Reason    string  `json:"reason,omitempty"`
+}
+
+// Provider is a payment service provider. Capturing a payment that is
+// already captured for the same amount succeeds without charging again, so
+// a capture whose answer was lost can be repeated. Void cancels an
+// authorization that was never captured and fails once money was taken.
+// A refund is identified by key: repeating it with the same key returns
+// the first result without refunding again.
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, reference string, amount float64) (Result, error)
+	Void(ctx context.Context, reference string) (Result, error)
+	Refund(ctx context.Context, reference, key string, amount float64) (Result, error)
+	ParseWebhook(r *http.Request) (WebhookEvent, error)
+}
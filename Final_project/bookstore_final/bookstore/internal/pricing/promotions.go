@@ -0,0 +1,135 @@
+// Package pricing holds the pure order pricing rules. Stores load the data
+// and persist the results; nothing in here touches the database.
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"bookstore/internal/models"
+)
+
+// Subtotal sums the items at their book prices.
+func Subtotal(items []models.OrderItem) float64 {
+	var total float64
+	for _, item := range items {
+		total += item.Book.Price * float64(item.Quantity)
+	}
+	return round(total)
+}
+
+// Eligible reports whether a promotion can be used at time now, ignoring
+// usage limits, which depend on redemption history.
+func Eligible(p models.Promotion, now time.Time) error {
+	if !p.Active {
+		return fmt.Errorf("promotion %q is not active", p.Name)
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return fmt.Errorf("promotion %q has not started", p.Name)
+	}
+	if p.ExpiresAt != nil && !now.Before(*p.ExpiresAt) {
+		return fmt.Errorf("promotion %q has expired", p.Name)
+	}
+	return nil
+}
+
+// Discount returns the amount the promotion takes off the given items. It is
+// zero when the order is below the promotion's minimum or no item matches
+// its genre/author scope.
+func Discount(p models.Promotion, items []models.OrderItem) float64 {
+	subtotal := Subtotal(items)
+	if subtotal < p.MinOrder {
+		return 0
+	}
+
+	var base float64
+	for _, item := range items {
+		if applies(p, item.Book) {
+			base += item.Book.Price * float64(item.Quantity)
+		}
+	}
+	if base <= 0 {
+		return 0
+	}
+
+	var amount float64
+	switch p.Kind {
+	case models.PromotionPercentage:
+		amount = base * p.Value / 100
+	case models.PromotionFixed:
+		amount = p.Value
+	}
+	return round(math.Min(math.Max(amount, 0), base))
+}
+
+// ApplyPromotions computes the discount lines for an order. Promotions are
+// applied in the given order and the running discount never exceeds the
+// subtotal.
+func ApplyPromotions(items []models.OrderItem, promotions []models.Promotion) []models.OrderDiscount {
+	remaining := Subtotal(items)
+
+	var discounts []models.OrderDiscount
+	for _, p := range promotions {
+		amount := math.Min(Discount(p, items), remaining)
+		if amount <= 0 {
+			continue
+		}
+		remaining = round(remaining - amount)
+		discounts = append(discounts, models.OrderDiscount{
+			PromotionID: p.ID,
+			Code:        p.Code,
+			Description: p.Name,
+			Amount:      amount,
+		})
+	}
+	return discounts
+}
+
+// TotalDiscount sums the discount lines.
+func TotalDiscount(discounts []models.OrderDiscount) float64 {
+	var total float64
+	for _, d := range discounts {
+		total += d.Amount
+	}
+	return round(total)
+}
+
+func applies(p models.Promotion, book models.Book) bool {
+	if p.AuthorID != 0 && !credits(book, p.AuthorID) {
+		return false
+	}
+	if p.Genre != "" {
+		for _, g := range book.Genres {
+			if strings.EqualFold(g, p.Genre) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// credits reports whether the author is credited on the book, in any role.
+func credits(book models.Book, authorID int) bool {
+	if book.Author.ID == authorID {
+		return true
+	}
+	for _, c := range book.Contributors {
+		if c.Author.ID == authorID {
+			return true
+		}
+	}
+	return false
+}
+
+// round rounds to whole cents.
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Total is what the customer pays for an order.
+func Total(subtotal, discount, shipping, tax float64) float64 {
+	return round(subtotal - discount + shipping + tax)
+}
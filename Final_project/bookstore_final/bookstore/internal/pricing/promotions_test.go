@@ -0,0 +1,99 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"bookstore/internal/models"
+)
+
+func TestDiscount(t *testing.T) {
+	novel := models.Book{ID: 1, Price: 20, Genres: []string{"Fiction"}, Author: models.Author{ID: 1}}
+	atlas := models.Book{ID: 2, Price: 10, Genres: []string{"Reference"}, Author: models.Author{ID: 2},
+		Contributors: []models.Contributor{{Author: models.Author{ID: 3}, Role: models.RoleEditor}}}
+	items := []models.OrderItem{{Book: novel, Quantity: 2}, {Book: atlas, Quantity: 1}}
+
+	tests := []struct {
+		name  string
+		promo models.Promotion
+		want  float64
+	}{
+		{"percentage of order", models.Promotion{Kind: models.PromotionPercentage, Value: 10}, 5},
+		{"fixed amount", models.Promotion{Kind: models.PromotionFixed, Value: 7.5}, 7.5},
+		{"below minimum order", models.Promotion{Kind: models.PromotionFixed, Value: 5, MinOrder: 50.01}, 0},
+		{"at minimum order", models.Promotion{Kind: models.PromotionFixed, Value: 5, MinOrder: 50}, 5},
+		{"genre ignores case", models.Promotion{Kind: models.PromotionPercentage, Value: 50, Genre: "fiction"}, 20},
+		{"genre without books", models.Promotion{Kind: models.PromotionPercentage, Value: 50, Genre: "Poetry"}, 0},
+		{"author", models.Promotion{Kind: models.PromotionPercentage, Value: 50, AuthorID: 2}, 5},
+		{"author credited as editor", models.Promotion{Kind: models.PromotionPercentage, Value: 50, AuthorID: 3}, 5},
+		{"fixed capped at matching items", models.Promotion{Kind: models.PromotionFixed, Value: 15, AuthorID: 2}, 10},
+		{"genre and author both needed", models.Promotion{Kind: models.PromotionFixed, Value: 5, AuthorID: 2, Genre: "Fiction"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Discount(tt.promo, items); got != tt.want {
+				t.Errorf("Discount = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	items := []models.OrderItem{{Book: models.Book{Price: 12.5}, Quantity: 2}}
+	half := models.Promotion{ID: 1, Name: "half", Kind: models.PromotionPercentage, Value: 50}
+	ten := models.Promotion{ID: 2, Name: "ten off", Code: "TEN", Kind: models.PromotionFixed, Value: 10}
+	none := models.Promotion{ID: 3, Name: "poetry", Kind: models.PromotionFixed, Value: 5, Genre: "Poetry"}
+
+	tests := []struct {
+		name       string
+		promotions []models.Promotion
+		want       []float64
+	}{
+		{"none", nil, nil},
+		{"one", []models.Promotion{ten}, []float64{10}},
+		{"stacked", []models.Promotion{half, ten}, []float64{12.5, 10}},
+		{"capped at subtotal", []models.Promotion{ten, ten, ten}, []float64{10, 10, 5}},
+		{"not applying skipped", []models.Promotion{none, ten}, []float64{10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discounts := ApplyPromotions(items, tt.promotions)
+			if len(discounts) != len(tt.want) {
+				t.Fatalf("discounts = %+v, want amounts %v", discounts, tt.want)
+			}
+			for i, d := range discounts {
+				if d.Amount != tt.want[i] {
+					t.Errorf("discount %d = %.2f, want %.2f", i, d.Amount, tt.want[i])
+				}
+			}
+			if total := TotalDiscount(discounts); total > Subtotal(items) {
+				t.Errorf("TotalDiscount = %.2f, more than the subtotal", total)
+			}
+		})
+	}
+}
+
+func TestEligible(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		promo models.Promotion
+		ok    bool
+	}{
+		{"active", models.Promotion{Active: true}, true},
+		{"inactive", models.Promotion{}, false},
+		{"started", models.Promotion{Active: true, StartsAt: &before}, true},
+		{"not started", models.Promotion{Active: true, StartsAt: &after}, false},
+		{"not expired", models.Promotion{Active: true, ExpiresAt: &after}, true},
+		{"expires now", models.Promotion{Active: true, ExpiresAt: &now}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Eligible(tt.promo, now); (err == nil) != tt.ok {
+				t.Errorf("Eligible = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
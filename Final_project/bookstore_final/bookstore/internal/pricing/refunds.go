@@ -0,0 +1,19 @@
+package pricing
+
+import "bookstore/internal/models"
+
+// Refund returns the amount to refund for returned items and the part of it
+// that is tax. Items are refunded at the price paid less their share of the
+// order's discounts, plus the tax charged on that. Shipping is not refunded.
+func Refund(order models.Order, items []models.ReturnItem) (amount, tax float64) {
+	var value float64
+	for _, item := range items {
+		value += item.UnitPrice * float64(item.Quantity)
+	}
+	if order.Subtotal > 0 {
+		value -= value * order.DiscountTotal / order.Subtotal
+	}
+	value = round(value)
+	tax = round(value * order.TaxRate)
+	return value + tax, tax
+}
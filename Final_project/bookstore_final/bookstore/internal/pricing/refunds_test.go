@@ -0,0 +1,58 @@
+package pricing
+
+import (
+	"testing"
+
+	"bookstore/internal/models"
+)
+
+func TestRefund(t *testing.T) {
+	tests := []struct {
+		name       string
+		order      models.Order
+		items      []models.ReturnItem
+		wantAmount float64
+		wantTax    float64
+	}{
+		{
+			name:       "no discount or tax",
+			order:      models.Order{Subtotal: 50},
+			items:      []models.ReturnItem{{Quantity: 2, UnitPrice: 10}},
+			wantAmount: 20,
+		},
+		{
+			name:       "discount shared pro rata",
+			order:      models.Order{Subtotal: 50, DiscountTotal: 5},
+			items:      []models.ReturnItem{{Quantity: 2, UnitPrice: 10}},
+			wantAmount: 18,
+		},
+		{
+			name:       "tax on the discounted value",
+			order:      models.Order{Subtotal: 50, DiscountTotal: 5, TaxRate: 0.0725},
+			items:      []models.ReturnItem{{Quantity: 2, UnitPrice: 10}},
+			wantAmount: 19.31,
+			wantTax:    1.31,
+		},
+		{
+			name:       "several items rounded to cents",
+			order:      models.Order{Subtotal: 30, DiscountTotal: 10, TaxRate: 0.1},
+			items:      []models.ReturnItem{{Quantity: 1, UnitPrice: 10}, {Quantity: 1, UnitPrice: 5}},
+			wantAmount: 11,
+			wantTax:    1,
+		},
+		{
+			name:       "fully discounted order",
+			order:      models.Order{Subtotal: 30, DiscountTotal: 30},
+			items:      []models.ReturnItem{{Quantity: 3, UnitPrice: 10}},
+			wantAmount: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, tax := Refund(tt.order, tt.items)
+			if amount != tt.wantAmount || tax != tt.wantTax {
+				t.Errorf("Refund = %v, %v; want %v, %v", amount, tax, tt.wantAmount, tt.wantTax)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package pricing
+
+import (
+	"strings"
+
+	"bookstore/internal/models"
+)
+
+// ShippingTable quotes shipping from rate brackets.
+type ShippingTable []models.ShippingRate
+
+// Quote returns the shipping cost for the items to the address. Brackets for
+// the address's country take precedence over the fallback brackets; among
+// those that fit the parcel, the tightest one wins. merchandise is the
+// discounted item total, used for free-shipping thresholds.
+func (t ShippingTable) Quote(addr models.Address, items []models.OrderItem, merchandise float64) (float64, bool) {
+	var (
+		count  int
+		weight int
+	)
+	for _, item := range items {
+		count += item.Quantity
+		weight += item.Book.WeightGrams * item.Quantity
+	}
+
+	rate, ok := t.bracket(addr.Country, weight, count)
+	if !ok {
+		rate, ok = t.bracket("", weight, count)
+	}
+	if !ok {
+		return 0, false
+	}
+
+	if rate.FreeOver > 0 && merchandise >= rate.FreeOver {
+		return 0, true
+	}
+	cost := rate.BaseFee + rate.PerItem*float64(count) + rate.PerKg*float64(weight)/1000
+	return round(cost), true
+}
+
+func (t ShippingTable) bracket(country string, weight, count int) (models.ShippingRate, bool) {
+	var (
+		best  models.ShippingRate
+		found bool
+	)
+	for _, rate := range t {
+		if !strings.EqualFold(rate.Country, country) {
+			continue
+		}
+		if rate.MaxWeightGrams > 0 && weight > rate.MaxWeightGrams {
+			continue
+		}
+		if rate.MaxItems > 0 && count > rate.MaxItems {
+			continue
+		}
+		if !found || tighter(rate, best) {
+			best, found = rate, true
+		}
+	}
+	return best, found
+}
+
+// tighter reports whether a has smaller limits than b; zero means unlimited.
+func tighter(a, b models.ShippingRate) bool {
+	if a.MaxWeightGrams != b.MaxWeightGrams {
+		return limitLess(a.MaxWeightGrams, b.MaxWeightGrams)
+	}
+	return limitLess(a.MaxItems, b.MaxItems)
+}
+
+func limitLess(a, b int) bool {
+	if a == 0 {
+		return false
+	}
+	return b == 0 || a < b
+}
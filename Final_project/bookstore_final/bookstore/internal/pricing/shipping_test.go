@@ -0,0 +1,44 @@
+package pricing
+
+import (
+	"testing"
+
+	"bookstore/internal/models"
+)
+
+func TestShippingTableQuote(t *testing.T) {
+	table := ShippingTable{
+		{ID: 1, Country: "US", MaxWeightGrams: 1000, BaseFee: 3.99},
+		{ID: 2, Country: "US", MaxWeightGrams: 5000, BaseFee: 6.99, PerItem: 0.5},
+		{ID: 3, Country: "US", BaseFee: 9.99, PerKg: 1.2, FreeOver: 100},
+		{ID: 4, Country: "", MaxItems: 3, BaseFee: 12},
+	}
+	items := func(weight, quantity int) []models.OrderItem {
+		return []models.OrderItem{{Book: models.Book{WeightGrams: weight}, Quantity: quantity}}
+	}
+
+	tests := []struct {
+		name        string
+		country     string
+		items       []models.OrderItem
+		merchandise float64
+		want        float64
+		wantOK      bool
+	}{
+		{"tightest bracket", "US", items(400, 2), 20, 3.99, true},
+		{"next bracket by weight", "US", items(600, 2), 20, 7.99, true},
+		{"unlimited bracket charges per kg", "US", items(3000, 2), 60, 17.19, true},
+		{"free over threshold", "US", items(3000, 2), 100, 0, true},
+		{"country match is case-insensitive", "us", items(400, 1), 10, 3.99, true},
+		{"fallback bracket", "FR", items(400, 3), 30, 12, true},
+		{"no bracket fits", "FR", items(400, 4), 40, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := table.Quote(models.Address{Country: tt.country}, tt.items, tt.merchandise)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Quote = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
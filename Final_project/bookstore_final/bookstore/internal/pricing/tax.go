@@ -0,0 +1,41 @@
+package pricing
+
+import (
+	"strings"
+
+	"bookstore/internal/models"
+)
+
+// TaxTable looks up tax rules by address.
+type TaxTable []models.TaxRule
+
+// Lookup returns the rule for the address's state if there is one, else the
+// country-wide rule.
+func (t TaxTable) Lookup(addr models.Address) (models.TaxRule, bool) {
+	var (
+		countryRule models.TaxRule
+		found       bool
+	)
+	for _, rule := range t {
+		if !strings.EqualFold(rule.Country, addr.Country) {
+			continue
+		}
+		if rule.State != "" && strings.EqualFold(rule.State, addr.State) {
+			return rule, true
+		}
+		if rule.State == "" {
+			countryRule, found = rule, true
+		}
+	}
+	return countryRule, found
+}
+
+// Tax applies the rule to the discounted merchandise total and, when the
+// rule says so, to shipping.
+func Tax(rule models.TaxRule, merchandise, shipping float64) float64 {
+	base := merchandise
+	if rule.TaxShipping {
+		base += shipping
+	}
+	return round(base * rule.Rate)
+}
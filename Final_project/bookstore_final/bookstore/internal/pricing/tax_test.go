@@ -0,0 +1,58 @@
+package pricing
+
+import (
+	"testing"
+
+	"bookstore/internal/models"
+)
+
+func TestTaxTableLookup(t *testing.T) {
+	table := TaxTable{
+		{ID: 1, Country: "US", Rate: 0.05},
+		{ID: 2, Country: "US", State: "CA", Rate: 0.0725},
+		{ID: 3, Country: "DE", Rate: 0.07},
+	}
+
+	tests := []struct {
+		name   string
+		addr   models.Address
+		wantID int
+		wantOK bool
+	}{
+		{"state rule wins", models.Address{Country: "US", State: "CA"}, 2, true},
+		{"case-insensitive", models.Address{Country: "us", State: "ca"}, 2, true},
+		{"country rule for other states", models.Address{Country: "US", State: "NY"}, 1, true},
+		{"country rule without state", models.Address{Country: "DE"}, 3, true},
+		{"no rule", models.Address{Country: "FR"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := table.Lookup(tt.addr)
+			if ok != tt.wantOK || rule.ID != tt.wantID {
+				t.Errorf("Lookup(%+v) = rule %d, %v; want rule %d, %v", tt.addr, rule.ID, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTax(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        models.TaxRule
+		merchandise float64
+		shipping    float64
+		want        float64
+	}{
+		{"merchandise only", models.TaxRule{Rate: 0.0725}, 40, 5, 2.9},
+		{"shipping taxed", models.TaxRule{Rate: 0.0725, TaxShipping: true}, 40, 5, 3.26},
+		{"rounded to cents", models.TaxRule{Rate: 0.19}, 9.99, 0, 1.9},
+		{"zero rate", models.TaxRule{}, 40, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tax(tt.rule, tt.merchandise, tt.shipping); got != tt.want {
+				t.Errorf("Tax(%+v, %v, %v) = %v, want %v", tt.rule, tt.merchandise, tt.shipping, got, tt.want)
+			}
+		})
+	}
+}
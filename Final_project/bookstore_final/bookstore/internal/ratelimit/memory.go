@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const memorySweepInterval = time.Minute
+
+// MemoryBackend keeps buckets and failures in process memory.
+type MemoryBackend struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	failures map[string]*failures
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+	window      time.Duration
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failures),
+	}
+}
+
+func (b *MemoryBackend) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	burst := float64(limit.Requests)
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{tokens: burst, last: now}
+		b.buckets[key] = bk
+	}
+	bk.limit = limit
+	bk.tokens = math.Min(burst, bk.tokens+now.Sub(bk.last).Seconds()*limit.rate())
+	bk.last = now
+
+	result := Result{Limit: limit.Requests}
+	if bk.tokens >= 1 {
+		bk.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bk.tokens) / limit.rate())
+	}
+	result.Remaining = int(bk.tokens)
+	result.Reset = secondsToDuration((burst - bk.tokens) / limit.rate())
+	return result, nil
+}
+
+func (b *MemoryBackend) LockedUntil(ctx context.Context, key string, now time.Time) (time.Time, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if f, ok := b.failures[key]; ok && f.lockedUntil.After(now) {
+		return f.lockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (b *MemoryBackend) Fail(ctx context.Context, key string, policy LockoutPolicy, now time.Time) (time.Time, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f, ok := b.failures[key]
+	if !ok || now.Sub(f.last) > policy.Window {
+		f = &failures{}
+		b.failures[key] = f
+	}
+	f.count++
+	f.last = now
+	f.window = policy.Window
+	if d := policy.duration(f.count); d > 0 {
+		f.lockedUntil = now.Add(d)
+		return f.lockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (b *MemoryBackend) Reset(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, key)
+	return nil
+}
+
+// Start periodically forgets full buckets and expired failures until ctx is
+// done, so that memory does not grow with every client ever seen.
+func (b *MemoryBackend) Start(ctx context.Context) {
+	ticker := time.NewTicker(memorySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.sweep(now)
+		}
+	}
+}
+
+func (b *MemoryBackend) sweep(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, bk := range b.buckets {
+		if now.Sub(bk.last) >= bk.limit.Per {
+			delete(b.buckets, key)
+		}
+	}
+	for key, f := range b.failures {
+		if now.After(f.lockedUntil) && now.Sub(f.last) > f.window {
+			delete(b.failures, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
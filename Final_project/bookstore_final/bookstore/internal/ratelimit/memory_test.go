@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryBackendTake(t *testing.T) {
+	b := NewMemoryBackend()
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	start := time.Unix(1700000000, 0)
+
+	// The steps run in order against one bucket, which refills at one
+	// token per second.
+	steps := []struct {
+		name       string
+		at         time.Duration
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{"full bucket", 0, true, 2, time.Second, 0},
+		{"second of the burst", 0, true, 1, 2 * time.Second, 0},
+		{"last of the burst", 0, true, 0, 3 * time.Second, 0},
+		{"empty", 0, false, 0, 3 * time.Second, time.Second},
+		{"half a token", 500 * time.Millisecond, false, 0, 2500 * time.Millisecond, 500 * time.Millisecond},
+		{"refilled one token", time.Second, true, 0, 3 * time.Second, 0},
+		{"refill stops at the burst", 10 * time.Second, true, 2, time.Second, 0},
+	}
+	for _, s := range steps {
+		got, err := b.Take(context.Background(), "ip:1.2.3.4", limit, start.Add(s.at))
+		if err != nil {
+			t.Fatalf("%s: Take: %v", s.name, err)
+		}
+		want := Result{Allowed: s.allowed, Limit: 3, Remaining: s.remaining, Reset: s.reset, RetryAfter: s.retryAfter}
+		if got != want {
+			t.Errorf("%s: Take = %+v, want %+v", s.name, got, want)
+		}
+	}
+
+	got, _ := b.Take(context.Background(), "ip:5.6.7.8", limit, start)
+	if !got.Allowed || got.Remaining != 2 {
+		t.Errorf("other key: Take = %+v, want its own full bucket", got)
+	}
+}
+
+func TestMemoryBackendFail(t *testing.T) {
+	b := NewMemoryBackend()
+	policy := LockoutPolicy{Threshold: 3, Window: time.Minute, Base: time.Minute, Max: 5 * time.Minute}
+	start := time.Unix(1700000000, 0)
+
+	// The steps run in order against one key.
+	steps := []struct {
+		name   string
+		at     time.Duration
+		locked time.Duration // lockout from the failure; zero for none
+	}{
+		{"first failure", 0, 0},
+		{"second failure", 10 * time.Second, 0},
+		{"threshold reached", 20 * time.Second, time.Minute},
+		{"lockout doubles", 30 * time.Second, 2 * time.Minute},
+		{"doubles again", 40 * time.Second, 4 * time.Minute},
+		{"capped at max", 50 * time.Second, 5 * time.Minute},
+		{"count restarts after the window", 50*time.Second + 61*time.Second, 0},
+	}
+	for _, s := range steps {
+		now := start.Add(s.at)
+		until, err := b.Fail(context.Background(), "login:user:ann", policy, now)
+		if err != nil {
+			t.Fatalf("%s: Fail: %v", s.name, err)
+		}
+		var want time.Time
+		if s.locked > 0 {
+			want = now.Add(s.locked)
+		}
+		if !until.Equal(want) {
+			t.Errorf("%s: Fail = %v, want %v", s.name, until, want)
+		}
+	}
+
+	if err := b.Reset(context.Background(), "login:user:ann"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	until, _ := b.Fail(context.Background(), "login:user:ann", policy, start.Add(2*time.Minute))
+	if !until.IsZero() {
+		t.Errorf("Fail after Reset = %v, want no lockout", until)
+	}
+}
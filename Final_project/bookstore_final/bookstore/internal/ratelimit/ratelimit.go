@@ -0,0 +1,240 @@
+// Package ratelimit limits request rates with token buckets and locks out
+// clients that keep failing to log in.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookstore/internal/auth"
+	"bookstore/pkg/utils"
+)
+
+// Limit allows Requests per Per on average, in bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// rate returns the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the state of a bucket after taking a token from it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until a token is available, if not Allowed.
+	RetryAfter time.Duration
+}
+
+// LockoutPolicy locks a key out once it has failed Threshold times without
+// a gap of Window between failures. The lockout lasts Base and doubles with
+// every further failure, up to Max.
+type LockoutPolicy struct {
+	Threshold int
+	Window    time.Duration
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// duration returns the lockout after the given number of failures.
+func (p LockoutPolicy) duration(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	d := p.Base << uint(min(failures-p.Threshold, 30))
+	if d <= 0 || d > p.Max {
+		d = p.Max
+	}
+	return d
+}
+
+// Backend keeps the buckets and failure counts. MemoryBackend suits a
+// single server; several servers need a shared implementation, e.g. on
+// Redis, so that they enforce one limit together.
+type Backend interface {
+	// Take takes a token from the bucket of key.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	// LockedUntil returns when the lockout of key ends, or the zero time if
+	// it is not locked out.
+	LockedUntil(ctx context.Context, key string, now time.Time) (time.Time, error)
+	// Fail records a failure against key and returns when the resulting
+	// lockout ends, or the zero time if there is none.
+	Fail(ctx context.Context, key string, policy LockoutPolicy, now time.Time) (time.Time, error)
+	// Reset forgets the failures of key.
+	Reset(ctx context.Context, key string) error
+}
+
+type Config struct {
+	// IP limits every API request by client IP.
+	IP Limit
+	// User limits authenticated requests by username.
+	User Limit
+	// Login limits login attempts by client IP.
+	Login Limit
+	// Lockout applies to failed logins, both per username and per IP.
+	Lockout LockoutPolicy
+	// TrustProxy takes the client IP from the last X-Forwarded-For entry,
+	// the one added by the proxy. Only enable it behind a single proxy that
+	// appends to the header.
+	TrustProxy bool
+}
+
+// Limiter applies Config using a Backend. A Backend error lets the request
+// through rather than taking the API down.
+type Limiter struct {
+	backend Backend
+	config  Config
+	logger  *utils.Logger
+}
+
+func NewLimiter(backend Backend, config Config, logger *utils.Logger) *Limiter {
+	return &Limiter{backend: backend, config: config, logger: logger}
+}
+
+// IPMiddleware limits requests by client IP.
+func (l *Limiter) IPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := "ip:" + l.ClientIP(r)
+		if !l.take(w, r, key, l.config.IP) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UserMiddleware limits requests by authenticated user. It must run after
+// the auth middleware.
+func (l *Limiter) UserMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := "user:" + auth.Username(r.Context())
+		if !l.take(w, r, key, l.config.User) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AllowLogin checks the login limit and lockouts for a login attempt as
+// username. If the attempt is refused it writes a 429 and returns false.
+func (l *Limiter) AllowLogin(w http.ResponseWriter, r *http.Request, username string) bool {
+	ip := l.ClientIP(r)
+	if !l.take(w, r, "login:ip:"+ip, l.config.Login) {
+		return false
+	}
+
+	now := time.Now()
+	var until time.Time
+	for _, key := range loginKeys(ip, username) {
+		locked, err := l.backend.LockedUntil(r.Context(), key, now)
+		if err != nil {
+			l.logger.ErrorContext(r.Context(), "Rate limit backend failed: %v", err)
+			continue
+		}
+		if locked.After(until) {
+			until = locked
+		}
+	}
+	if until.After(now) {
+		writeLocked(w, until.Sub(now))
+		return false
+	}
+	return true
+}
+
+// LoginFailed records a failed login as username, locking it and the
+// client IP out after repeated failures.
+func (l *Limiter) LoginFailed(r *http.Request, username string) {
+	now := time.Now()
+	for _, key := range loginKeys(l.ClientIP(r), username) {
+		until, err := l.backend.Fail(r.Context(), key, l.config.Lockout, now)
+		if err != nil {
+			l.logger.ErrorContext(r.Context(), "Rate limit backend failed: %v", err)
+			continue
+		}
+		if !until.IsZero() {
+			l.logger.WarnContext(r.Context(), "Login locked out for %s until %s", key, until.Format(time.RFC3339))
+		}
+	}
+}
+
+// LoginSucceeded clears the failures of username. Those of the client IP
+// are kept, so that one valid account cannot be used to reset guessing at
+// others.
+func (l *Limiter) LoginSucceeded(r *http.Request, username string) {
+	if err := l.backend.Reset(r.Context(), "login:user:"+username); err != nil {
+		l.logger.ErrorContext(r.Context(), "Rate limit backend failed: %v", err)
+	}
+}
+
+func loginKeys(ip, username string) []string {
+	return []string{"login:ip:" + ip, "login:user:" + username}
+}
+
+// ClientIP returns the IP address the request came from. Behind a trusted
+// proxy that is the rightmost X-Forwarded-For entry; entries left of it
+// were sent by the client and can be anything.
+func (l *Limiter) ClientIP(r *http.Request) string {
+	if l.config.TrustProxy {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			last := values[len(values)-1]
+			if i := strings.LastIndex(last, ","); i >= 0 {
+				last = last[i+1:]
+			}
+			if ip := strings.TrimSpace(last); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// take takes a token for key and sets the RateLimit headers. If the bucket
+// is empty it writes a 429 and returns false.
+func (l *Limiter) take(w http.ResponseWriter, r *http.Request, key string, limit Limit) bool {
+	if limit.Requests <= 0 {
+		return true
+	}
+	result, err := l.backend.Take(r.Context(), key, limit, time.Now())
+	if err != nil {
+		l.logger.ErrorContext(r.Context(), "Rate limit backend failed: %v", err)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Per.Seconds())))
+	h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+	if !result.Allowed {
+		h.Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+func writeLocked(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(seconds(retryAfter)))
+	http.Error(w, "too many failed logins, try again later", http.StatusTooManyRequests)
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicyDuration(t *testing.T) {
+	policy := LockoutPolicy{Threshold: 5, Base: time.Minute, Max: time.Hour}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{10, 32 * time.Minute},
+		{11, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := policy.duration(tt.failures); got != tt.want {
+			t.Errorf("duration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"remote address", false, "10.0.0.1:5000", "", "10.0.0.1"},
+		{"forwarded ignored", false, "10.0.0.1:5000", "203.0.113.7", "10.0.0.1"},
+		{"last forwarded address", true, "10.0.0.1:5000", "203.0.113.7, 10.0.0.2", "10.0.0.2"},
+		{"spoofed forwarded address", true, "10.0.0.1:5000", "1.2.3.4, 203.0.113.7", "203.0.113.7"},
+		{"single forwarded address", true, "10.0.0.1:5000", "203.0.113.7", "203.0.113.7"},
+		{"empty last entry", true, "10.0.0.1:5000", "203.0.113.7,", "10.0.0.1"},
+		{"no forwarded header", true, "10.0.0.1:5000", "", "10.0.0.1"},
+		{"ipv6", false, "[2001:db8::1]:5000", "", "2001:db8::1"},
+		{"no port", false, "10.0.0.1", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(NewMemoryBackend(), Config{TrustProxy: tt.trustProxy}, nil)
+			r := httptest.NewRequest("GET", "/api/books", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := l.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
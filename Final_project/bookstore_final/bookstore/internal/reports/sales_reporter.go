@@ -6,15 +6,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"sync"
 	"time"
 
+	"bookstore/internal/events"
 	"bookstore/internal/interfaces"
+	"bookstore/internal/metrics"
 	"bookstore/internal/models"
+	"bookstore/internal/tracing"
+	"bookstore/pkg/utils"
 )
 
 type SalesReporter struct {
@@ -26,7 +29,16 @@ type SalesReporter struct {
 	stopChan    chan struct{}
 
 
-	bookStore interfaces.BookStore
+	bookStore   interfaces.BookStore
+	returnStore interfaces.ReturnStore
+	outboxStore interfaces.OutboxStore
+	logger      *utils.Logger
+
+	statusMu    sync.Mutex
+	started     time.Time
+	lastRun     time.Time
+	lastSuccess time.Time
+	lastErr     error
 }
 
 func NewSalesReporter(
@@ -35,6 +47,9 @@ func NewSalesReporter(
 	outputDir string,
 	interval time.Duration,
 	bookStore interfaces.BookStore,
+	returnStore interfaces.ReturnStore,
+	outboxStore interfaces.OutboxStore,
+	logger *utils.Logger,
 ) (*SalesReporter, error) {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create output directory: %v", err)
@@ -46,16 +61,23 @@ func NewSalesReporter(
 		interval:    interval,
 		stopChan:    make(chan struct{}),
 		bookStore:   bookStore,
+		returnStore: returnStore,
+		outboxStore: outboxStore,
+		logger:      logger,
 	}, nil
 }
 
 func (r *SalesReporter) Start(ctx context.Context) error {
+	r.statusMu.Lock()
+	r.started = time.Now()
+	r.statusMu.Unlock()
+
 	ticker := time.NewTicker(r.interval)
 	defer ticker.Stop()
 
 
-	if err := r.GenerateReport(ctx); err != nil {
-		log.Printf("Error generating initial report: %v", err)
+	if err := r.runReport(ctx); err != nil {
+		r.logger.ErrorContext(ctx, "Error generating initial report: %v", err)
 	}
 
 	for {
@@ -65,8 +87,8 @@ func (r *SalesReporter) Start(ctx context.Context) error {
 		case <-r.stopChan:
 			return nil
 		case <-ticker.C:
-			if err := r.GenerateReport(ctx); err != nil {
-				log.Printf("Error generating report: %v", err)
+			if err := r.runReport(ctx); err != nil {
+				r.logger.ErrorContext(ctx, "Error generating report: %v", err)
 			}
 		}
 	}
@@ -76,6 +98,47 @@ func (r *SalesReporter) Stop() {
 	close(r.stopChan)
 }
 
+// runReport generates a report and records how long it took and whether it
+// failed.
+func (r *SalesReporter) runReport(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "SalesReporter.GenerateReport")
+	start := time.Now()
+	err := r.GenerateReport(ctx)
+	metrics.ObserveReport(time.Since(start), err)
+	tracing.End(span, err)
+
+	r.statusMu.Lock()
+	r.lastRun = start
+	r.lastErr = err
+	if err == nil {
+		r.lastSuccess = start
+	}
+	r.statusMu.Unlock()
+	return err
+}
+
+// Check reports an error if the reporter is not running, its last run
+// failed, or it has not completed a run for two intervals.
+func (r *SalesReporter) Check(ctx context.Context) error {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	if r.started.IsZero() {
+		return fmt.Errorf("sales reporter not started")
+	}
+	if r.lastErr != nil {
+		return fmt.Errorf("last run at %s failed: %v", r.lastRun.Format(time.RFC3339), r.lastErr)
+	}
+	since := r.lastSuccess
+	if since.IsZero() {
+		since = r.started
+	}
+	if time.Since(since) > 2*r.interval {
+		return fmt.Errorf("no report generated since %s", since.Format(time.RFC3339))
+	}
+	return nil
+}
+
 func (r *SalesReporter) GenerateReport(ctx context.Context) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -89,11 +152,23 @@ func (r *SalesReporter) GenerateReport(ctx context.Context) error {
 		return fmt.Errorf("failed to fetch orders: %v", err)
 	}
 
-	var totalRevenue float64
+	var totalRevenue, grossRevenue, totalDiscounts, totalShipping, totalTax float64
 	bookSales := make(map[int]models.BookSales)
 
+	totalOrders := 0
 	for _, order := range orders {
-		totalRevenue += order.TotalPrice
+		// Cancelled orders were never paid for and their books went back
+		// into stock.
+		if order.Status == models.OrderCancelled {
+			continue
+		}
+		totalOrders++
+		// Tax is collected on behalf of the authorities and is not revenue.
+		totalRevenue += order.TotalPrice - order.TaxTotal
+		grossRevenue += order.Subtotal
+		totalDiscounts += order.DiscountTotal
+		totalShipping += order.ShippingTotal
+		totalTax += order.TaxTotal
 		for _, item := range order.Items {
 			bs, ok := bookSales[item.Book.ID]
 			if !ok {
@@ -106,6 +181,17 @@ func (r *SalesReporter) GenerateReport(ctx context.Context) error {
 			bookSales[item.Book.ID] = bs
 		}
 	}
+
+	refunds, err := r.returnStore.GetRefundsInTimeRange(ctx, startTime, endTime)
+	if err != nil {
+		return fmt.Errorf("failed to fetch refunds: %v", err)
+	}
+	var totalRefunds, refundedRevenue float64
+	for _, refund := range refunds {
+		totalRefunds += refund.Amount
+		refundedRevenue += refund.Amount - refund.TaxAmount
+	}
+
 	var topSellingBooks []models.BookSales
 	for _, bs := range bookSales {
 		topSellingBooks = append(topSellingBooks, bs)
@@ -120,7 +206,13 @@ func (r *SalesReporter) GenerateReport(ctx context.Context) error {
 	report := models.SalesReport{
 		Timestamp:       endTime,
 		TotalRevenue:    totalRevenue,
-		TotalOrders:     len(orders),
+		TotalOrders:     totalOrders,
+		GrossRevenue:    grossRevenue,
+		TotalDiscounts:  totalDiscounts,
+		TotalShipping:   totalShipping,
+		TotalTax:        totalTax,
+		TotalRefunds:    totalRefunds,
+		NetRevenue:      totalRevenue - refundedRevenue,
 		TopSellingBooks: topSellingBooks,
 	}
 
@@ -134,7 +226,18 @@ func (r *SalesReporter) GenerateReport(ctx context.Context) error {
 	if err := r.writeReportToFile(filepath, report); err != nil {
 		return fmt.Errorf("failed to write report to file: %v", err)
 	}
-	log.Printf("Generated sales report: %s", filepath)
+	r.logger.InfoContext(ctx, "Generated sales report: %s", filepath)
+
+	err = r.outboxStore.Enqueue(ctx, events.ReportGeneratedEvent{
+		Timestamp:    report.Timestamp,
+		TotalOrders:  report.TotalOrders,
+		TotalRevenue: report.TotalRevenue,
+		NetRevenue:   report.NetRevenue,
+		File:         filename,
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to publish report event: %v", err)
+	}
 
 
 	if len(topSellingBooks) > 0 {
@@ -143,7 +246,7 @@ func (r *SalesReporter) GenerateReport(ctx context.Context) error {
 			bookID := topSellingBooks[i].Book.ID
 			err := r.adjustBookPrice(ctx, bookID)
 			if err != nil {
-				log.Printf("Failed to adjust price for book %d: %v", bookID, err)
+				r.logger.ErrorContext(ctx, "Failed to adjust price for book %d: %v", bookID, err)
 			}
 		}
 	}
@@ -160,18 +263,13 @@ func (r *SalesReporter) writeReportToFile(filepath string, report models.SalesRe
 }
 
 
+// adjustBookPrice raises the price in place, so that stock and other fields
+// changed since the report was built are not written back.
 func (r *SalesReporter) adjustBookPrice(ctx context.Context, bookID int) error {
-	book, err := r.bookStore.GetBook(ctx, bookID)
-	if err != nil {
-		return fmt.Errorf("cannot fetch book: %w", err)
-	}
-	newPrice := book.Price * 1.10 
-	book.Price = newPrice
-
-	_, err = r.bookStore.UpdateBook(ctx, bookID, book)
+	book, err := r.bookStore.AdjustPrice(ctx, bookID, 1.10)
 	if err != nil {
 		return fmt.Errorf("cannot update book price: %w", err)
 	}
-	log.Printf("Adjusted price for Book ID=%d to %.2f", bookID, newPrice)
+	r.logger.InfoContext(ctx, "Adjusted price for Book ID=%d to %.2f", bookID, book.Price)
 	return nil
 }
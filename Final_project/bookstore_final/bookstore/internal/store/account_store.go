@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"bookstore/internal/events"
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresAccountStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAccountStore(db *sql.DB) (interfaces.AccountStore, error) {
+	return &PostgresAccountStore{db: db}, nil
+}
+
+func (s *PostgresAccountStore) CreateAccount(ctx context.Context, account models.Account, customer models.Customer) (models.Account, models.Customer, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return account, customer, fmt.Errorf("CreateAccount error: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO customers (name, email, street, city, state, postal_code, country, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id
+    `,
+		customer.Name,
+		customer.Email,
+		customer.Address.Street,
+		customer.Address.City,
+		customer.Address.State,
+		customer.Address.PostalCode,
+		customer.Address.Country,
+		now,
+	).Scan(&customer.ID)
+	if err != nil {
+		return account, customer, fmt.Errorf("CreateAccount error: %w", err)
+	}
+
+	account.CustomerID = customer.ID
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO accounts (email, password_hash, customer_id, created_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `, account.Email, account.PasswordHash, account.CustomerID, now).Scan(&account.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return account, customer, models.ErrEmailTaken
+		}
+		return account, customer, fmt.Errorf("CreateAccount error: %w", err)
+	}
+
+	err = enqueueEvent(ctx, tx, events.CustomerRegisteredEvent{
+		CustomerID: customer.ID,
+		Name:       customer.Name,
+		Email:      customer.Email,
+	})
+	if err != nil {
+		return account, customer, fmt.Errorf("CreateAccount: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return account, customer, fmt.Errorf("CreateAccount commit: %w", err)
+	}
+	account.CreatedAt = now
+	customer.CreatedAt = now
+	return account, customer, nil
+}
+
+func (s *PostgresAccountStore) GetAccountByEmail(ctx context.Context, email string) (models.Account, error) {
+	var a models.Account
+	query := `
+        SELECT id, email, password_hash, customer_id, created_at
+        FROM accounts
+        WHERE LOWER(email) = LOWER($1)
+    `
+	err := s.db.QueryRowContext(ctx, query, email).Scan(
+		&a.ID,
+		&a.Email,
+		&a.PasswordHash,
+		&a.CustomerID,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return a, fmt.Errorf("account not found with email: %s", email)
+		}
+		return a, fmt.Errorf("GetAccountByEmail error: %w", err)
+	}
+	return a, nil
+}
+
+func (s *PostgresAccountStore) GetAccountByCustomer(ctx context.Context, customerID int) (models.Account, error) {
+	var a models.Account
+	query := `
+        SELECT id, email, password_hash, customer_id, created_at
+        FROM accounts
+        WHERE customer_id = $1
+    `
+	err := s.db.QueryRowContext(ctx, query, customerID).Scan(
+		&a.ID,
+		&a.Email,
+		&a.PasswordHash,
+		&a.CustomerID,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return a, fmt.Errorf("account not found for customer: %d", customerID)
+		}
+		return a, fmt.Errorf("GetAccountByCustomer error: %w", err)
+	}
+	return a, nil
+}
+
+func (s *PostgresAccountStore) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE accounts SET password_hash = $1 WHERE id = $2`, passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("UpdatePassword error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("account not found with id: %d", id)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresAddressStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAddressStore(db *sql.DB) (interfaces.AddressStore, error) {
+	return &PostgresAddressStore{db: db}, nil
+}
+
+const addressColumns = `
+        id, customer_id, label, street, city, state, postal_code, country,
+        default_shipping, default_billing, created_at
+`
+
+func scanAddress(row rowScanner) (models.CustomerAddress, error) {
+	var a models.CustomerAddress
+	err := row.Scan(
+		&a.ID,
+		&a.CustomerID,
+		&a.Label,
+		&a.Street,
+		&a.City,
+		&a.State,
+		&a.PostalCode,
+		&a.Country,
+		&a.DefaultShipping,
+		&a.DefaultBilling,
+		&a.CreatedAt,
+	)
+	return a, err
+}
+
+func (s *PostgresAddressStore) CreateAddress(ctx context.Context, address models.CustomerAddress) (models.CustomerAddress, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return address, fmt.Errorf("CreateAddress error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockCustomer(ctx, tx, address.CustomerID); err != nil {
+		return address, err
+	}
+	var hasShipping, hasBilling bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(BOOL_OR(default_shipping), FALSE), COALESCE(BOOL_OR(default_billing), FALSE)
+        FROM addresses WHERE customer_id = $1
+    `, address.CustomerID).Scan(&hasShipping, &hasBilling)
+	if err != nil {
+		return address, fmt.Errorf("CreateAddress error: %w", err)
+	}
+	address.DefaultShipping = address.DefaultShipping || !hasShipping
+	address.DefaultBilling = address.DefaultBilling || !hasBilling
+	if err := clearDefaults(ctx, tx, address.CustomerID, 0, address); err != nil {
+		return address, fmt.Errorf("CreateAddress error: %w", err)
+	}
+
+	now := time.Now()
+	query := `
+        INSERT INTO addresses (customer_id, label, street, city, state, postal_code, country,
+                               default_shipping, default_billing, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id
+    `
+	err = tx.QueryRowContext(ctx, query,
+		address.CustomerID,
+		address.Label,
+		address.Street,
+		address.City,
+		address.State,
+		address.PostalCode,
+		address.Country,
+		address.DefaultShipping,
+		address.DefaultBilling,
+		now,
+	).Scan(&address.ID)
+	if err != nil {
+		return address, fmt.Errorf("CreateAddress error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return address, fmt.Errorf("CreateAddress commit: %w", err)
+	}
+	address.CreatedAt = now
+	return address, nil
+}
+
+func (s *PostgresAddressStore) GetAddress(ctx context.Context, customerID, id int) (models.CustomerAddress, error) {
+	address, err := scanAddress(s.db.QueryRowContext(ctx,
+		`SELECT `+addressColumns+` FROM addresses WHERE id = $1 AND customer_id = $2`, id, customerID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return address, fmt.Errorf("address not found with id: %d", id)
+		}
+		return address, fmt.Errorf("GetAddress error: %w", err)
+	}
+	return address, nil
+}
+
+func (s *PostgresAddressStore) UpdateAddress(ctx context.Context, customerID, id int, address models.CustomerAddress) (models.CustomerAddress, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return address, fmt.Errorf("UpdateAddress error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockCustomer(ctx, tx, customerID); err != nil {
+		return address, err
+	}
+	if err := clearDefaults(ctx, tx, customerID, id, address); err != nil {
+		return address, fmt.Errorf("UpdateAddress error: %w", err)
+	}
+
+	query := `
+        UPDATE addresses
+        SET label = $1, street = $2, city = $3, state = $4, postal_code = $5, country = $6,
+            default_shipping = $7, default_billing = $8
+        WHERE id = $9 AND customer_id = $10
+        RETURNING created_at
+    `
+	err = tx.QueryRowContext(ctx, query,
+		address.Label,
+		address.Street,
+		address.City,
+		address.State,
+		address.PostalCode,
+		address.Country,
+		address.DefaultShipping,
+		address.DefaultBilling,
+		id,
+		customerID,
+	).Scan(&address.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return address, fmt.Errorf("address not found with id: %d", id)
+		}
+		return address, fmt.Errorf("UpdateAddress error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return address, fmt.Errorf("UpdateAddress commit: %w", err)
+	}
+	address.ID = id
+	address.CustomerID = customerID
+	return address, nil
+}
+
+// DeleteAddress removes a saved address. Orders keep their copy of it.
+func (s *PostgresAddressStore) DeleteAddress(ctx context.Context, customerID, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM addresses WHERE id = $1 AND customer_id = $2`, id, customerID)
+	if err != nil {
+		return fmt.Errorf("DeleteAddress error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("address not found with id: %d", id)
+	}
+	return nil
+}
+
+func (s *PostgresAddressStore) ListAddresses(ctx context.Context, customerID int) ([]models.CustomerAddress, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+addressColumns+` FROM addresses WHERE customer_id = $1 ORDER BY id`, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("ListAddresses error: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.CustomerAddress
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, a)
+	}
+	return results, rows.Err()
+}
+
+// lockCustomer serialises changes to a customer's addresses, so that two
+// addresses cannot both become the default.
+func lockCustomer(ctx context.Context, tx *sql.Tx, customerID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM customers WHERE id = $1 FOR UPDATE`, customerID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("customer not found with id: %d", customerID)
+		}
+		return err
+	}
+	return nil
+}
+
+// clearDefaults takes the default flags that address claims away from the
+// customer's other addresses.
+func clearDefaults(ctx context.Context, tx *sql.Tx, customerID, exceptID int, address models.CustomerAddress) error {
+	if address.DefaultShipping {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE addresses SET default_shipping = FALSE WHERE customer_id = $1 AND id <> $2`,
+			customerID, exceptID)
+		if err != nil {
+			return err
+		}
+	}
+	if address.DefaultBilling {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE addresses SET default_billing = FALSE WHERE customer_id = $1 AND id <> $2`,
+			customerID, exceptID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresAPIKeyStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAPIKeyStore(db *sql.DB) (interfaces.APIKeyStore, error) {
+	return &PostgresAPIKeyStore{db: db}, nil
+}
+
+const apiKeyColumns = `
+        id, name, prefix, hash, scopes, created_by, created_at, expires_at, last_used_at, revoked_at
+`
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var k models.APIKey
+	err := row.Scan(
+		&k.ID,
+		&k.Name,
+		&k.Prefix,
+		&k.Hash,
+		pq.Array(&k.Scopes),
+		&k.CreatedBy,
+		&k.CreatedAt,
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+	)
+	return k, err
+}
+
+func (s *PostgresAPIKeyStore) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	query := `
+        INSERT INTO api_keys (name, prefix, hash, scopes, created_by, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `
+	now := time.Now()
+	err := s.db.QueryRowContext(ctx, query,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		pq.Array(key.Scopes),
+		key.CreatedBy,
+		now,
+		key.ExpiresAt,
+	).Scan(&key.ID)
+	if err != nil {
+		return key, fmt.Errorf("CreateAPIKey error: %w", err)
+	}
+	key.CreatedAt = now
+	return key, nil
+}
+
+func (s *PostgresAPIKeyStore) GetAPIKey(ctx context.Context, id int) (models.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return key, fmt.Errorf("API key not found with id: %d", id)
+		}
+		return key, fmt.Errorf("GetAPIKey error: %w", err)
+	}
+	return key, nil
+}
+
+func (s *PostgresAPIKeyStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return key, fmt.Errorf("API key not found with prefix: %s", prefix)
+		}
+		return key, fmt.Errorf("GetAPIKeyByPrefix error: %w", err)
+	}
+	return key, nil
+}
+
+func (s *PostgresAPIKeyStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ListAPIKeys error: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ListAPIKeys error: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *PostgresAPIKeyStore) RevokeAPIKey(ctx context.Context, id int) (models.APIKey, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2`, time.Now(), id)
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("RevokeAPIKey error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.APIKey{}, fmt.Errorf("API key not found with id: %d", id)
+	}
+	return s.GetAPIKey(ctx, id)
+}
+
+func (s *PostgresAPIKeyStore) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE api_keys SET last_used_at = $1
+        WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $1)
+    `, at, id)
+	if err != nil {
+		return fmt.Errorf("TouchAPIKey error: %w", err)
+	}
+	return nil
+}
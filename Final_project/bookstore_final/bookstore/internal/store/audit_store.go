@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresAuditStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAuditStore(db *sql.DB) (interfaces.AuditStore, error) {
+	return &PostgresAuditStore{db: db}, nil
+}
+
+func (s *PostgresAuditStore) RecordAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	query := `
+        INSERT INTO audit_log (customer_id, action, actor, detail, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `
+	entry.CreatedAt = time.Now()
+	err := s.db.QueryRowContext(ctx, query,
+		entry.CustomerID,
+		entry.Action,
+		entry.Actor,
+		entry.Detail,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+	if err != nil {
+		return entry, fmt.Errorf("RecordAudit error: %w", err)
+	}
+	return entry, nil
+}
+
+func (s *PostgresAuditStore) ListAudit(ctx context.Context, customerID int) ([]models.AuditEntry, error) {
+	query := `
+        SELECT id, customer_id, action, actor, detail, created_at
+        FROM audit_log
+        WHERE customer_id = $1
+        ORDER BY id
+    `
+	rows, err := s.db.QueryContext(ctx, query, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("ListAudit error: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.CustomerID, &e.Action, &e.Actor, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+	return results, rows.Err()
+}
+
+// recordAudit writes an audit entry as part of another store's transaction.
+func recordAudit(ctx context.Context, tx execer, entry models.AuditEntry) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO audit_log (customer_id, action, actor, detail, created_at)
+        VALUES ($1, $2, $3, $4, NOW())
+    `, entry.CustomerID, entry.Action, entry.Actor, entry.Detail)
+	if err != nil {
+		return fmt.Errorf("audit insert failed: %w", err)
+	}
+	return nil
+}
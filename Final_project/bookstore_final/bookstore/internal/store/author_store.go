@@ -5,6 +5,7 @@ import (
     "context"
     "database/sql"
     "fmt"
+    "time"
 
     "bookstore/internal/interfaces"
     "bookstore/internal/models"
@@ -50,7 +51,7 @@ func (s *PostgresAuthorStore) GetAuthor(ctx context.Context, id int) (models.Aut
     )
     if err != nil {
         if err == sql.ErrNoRows {
-            return author, fmt.Errorf("author not found with id: %d", id)
+            return author, fmt.Errorf("%w with id: %d", models.ErrAuthorNotFound, id)
         }
         return author, err
     }
@@ -85,6 +86,52 @@ func (s *PostgresAuthorStore) DeleteAuthor(ctx context.Context, id int) error {
     return nil
 }
 
+func (s *PostgresAuthorStore) GetAuthorSummary(ctx context.Context, id int, start, end time.Time) (models.AuthorSummary, error) {
+    summary := models.AuthorSummary{Start: start, End: end}
+    author, err := s.GetAuthor(ctx, id)
+    if err != nil {
+        return summary, err
+    }
+    summary.Author = author
+
+    query := `
+        WITH credited AS (
+            SELECT DISTINCT book_id FROM book_contributors WHERE author_id = $1
+        ),
+        sold AS (
+            SELECT COALESCE(SUM(oi.quantity), 0) AS units,
+                   COALESCE(SUM(oi.quantity * oi.unit_price), 0) AS amount
+            FROM order_items oi
+            JOIN orders o ON o.id = oi.order_id
+            WHERE oi.book_id IN (SELECT book_id FROM credited)
+              AND o.status <> $4
+              AND o.created_at BETWEEN $2 AND $3
+        ),
+        returned AS (
+            SELECT COALESCE(SUM(ri.quantity), 0) AS units,
+                   COALESCE(SUM(ri.quantity * ri.unit_price), 0) AS amount
+            FROM return_items ri
+            JOIN return_requests rr ON rr.id = ri.return_id
+            JOIN orders o ON o.id = rr.order_id
+            WHERE ri.book_id IN (SELECT book_id FROM credited)
+              AND rr.status = $5
+              AND o.created_at BETWEEN $2 AND $3
+        )
+        SELECT (SELECT COUNT(*) FROM credited), sold.units, returned.units, sold.amount - returned.amount
+        FROM sold, returned
+    `
+    err = s.db.QueryRowContext(ctx, query, id, start, end, models.OrderCancelled, models.ReturnApproved).Scan(
+        &summary.Titles,
+        &summary.UnitsSold,
+        &summary.UnitsReturned,
+        &summary.Revenue,
+    )
+    if err != nil {
+        return summary, fmt.Errorf("GetAuthorSummary error: %w", err)
+    }
+    return summary, nil
+}
+
 func (s *PostgresAuthorStore) ListAuthors(ctx context.Context) ([]models.Author, error) {
     query := `SELECT id, first_name, last_name, bio FROM authors ORDER BY id`
     rows, err := s.db.QueryContext(ctx, query)
@@ -4,9 +4,14 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/lib/pq"
+
+	"bookstore/internal/events"
 	"bookstore/internal/interfaces"
 	"bookstore/internal/models"
 )
@@ -19,83 +24,340 @@ func NewPostgresBookStore(db *sql.DB) (interfaces.BookStore, error) {
 	return &PostgresBookStore{db: db}, nil
 }
 
+const bookColumns = `
+        b.id, b.title, b.genres, b.published_at, b.price, b.stock, b.low_stock_threshold, b.weight_grams,
+        COALESCE(b.isbn, ''), b.publisher, b.language, b.pages, b.format, b.description, b.cover_url, b.work_id,
+        (SELECT COALESCE(json_agg(json_build_object(
+                    'author', json_build_object('id', ca.id, 'first_name', ca.first_name,
+                                                'last_name', ca.last_name, 'bio', ca.bio),
+                    'role', bc.role) ORDER BY bc.position), '[]')
+         FROM book_contributors bc
+         JOIN authors ca ON ca.id = bc.author_id
+         WHERE bc.book_id = b.id),
+        a.id, a.first_name, a.last_name, a.bio
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBook(row rowScanner) (models.Book, error) {
+	var (
+		book         models.Book
+		contributors []byte
+	)
+	err := row.Scan(
+		&book.ID,
+		&book.Title,
+		pq.Array(&book.Genres),
+		&book.PublishedAt,
+		&book.Price,
+		&book.Stock,
+		&book.LowStockThreshold,
+		&book.WeightGrams,
+		&book.ISBN,
+		&book.Publisher,
+		&book.Language,
+		&book.Pages,
+		&book.Format,
+		&book.Description,
+		&book.CoverURL,
+		&book.WorkID,
+		&contributors,
+		&book.Author.ID,
+		&book.Author.FirstName,
+		&book.Author.LastName,
+		&book.Author.Bio,
+	)
+	if err != nil {
+		return book, err
+	}
+	err = json.Unmarshal(contributors, &book.Contributors)
+	return book, err
+}
+
+// creditAuthor keeps a book's author and contributors in step: a book
+// without contributors is credited to its author alone, and otherwise its
+// author is the first contributor with the author role.
+func creditAuthor(book *models.Book) {
+	if len(book.Contributors) == 0 {
+		book.Contributors = []models.Contributor{{Author: book.Author, Role: models.RoleAuthor}}
+		return
+	}
+	for _, c := range book.Contributors {
+		if c.Role == models.RoleAuthor {
+			book.Author = c.Author
+			return
+		}
+	}
+}
+
+// setContributors replaces a book's contributors, keeping their order.
+func setContributors(ctx context.Context, tx *sql.Tx, bookID int, contributors []models.Contributor) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_contributors WHERE book_id = $1`, bookID); err != nil {
+		return err
+	}
+	for i, c := range contributors {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO book_contributors (book_id, author_id, role, position)
+            VALUES ($1, $2, $3, $4)
+        `, bookID, c.Author.ID, c.Role, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bookContributors loads a book's contributors, in order.
+func bookContributors(ctx context.Context, tx *sql.Tx, bookID int) ([]models.Contributor, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT a.id, a.first_name, a.last_name, a.bio, bc.role
+        FROM book_contributors bc
+        JOIN authors a ON a.id = bc.author_id
+        WHERE bc.book_id = $1
+        ORDER BY bc.position
+    `, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contributors []models.Contributor
+	for rows.Next() {
+		var c models.Contributor
+		if err := rows.Scan(&c.Author.ID, &c.Author.FirstName, &c.Author.LastName, &c.Author.Bio, &c.Role); err != nil {
+			return nil, err
+		}
+		contributors = append(contributors, c)
+	}
+	return contributors, rows.Err()
+}
+
+// bookError maps constraint violations on a book's ISBN and work onto
+// domain errors.
+func bookError(method string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505" && pqErr.Constraint == "books_isbn_idx":
+			return models.ErrISBNTaken
+		case pqErr.Code == "23503" && pqErr.Constraint == "books_work_id_fkey":
+			return models.ErrUnknownWork
+		case pqErr.Code == "23503" && (pqErr.Constraint == "books_author_id_fkey" ||
+			pqErr.Constraint == "book_contributors_author_id_fkey"):
+			return models.ErrUnknownAuthor
+		}
+	}
+	return fmt.Errorf("%s error: %w", method, err)
+}
 
+
+// CreateBook inserts the book with no stock and books its initial stock
+// through the ledger, so the ledger always accounts for every unit.
 func (s *PostgresBookStore) CreateBook(ctx context.Context, book models.Book) (models.Book, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return book, err
+	}
+	defer tx.Rollback()
+
+	creditAuthor(&book)
 	query := `
-        INSERT INTO books (title, author_id, published_at, price, stock)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO books (title, author_id, genres, published_at, price, stock, low_stock_threshold, weight_grams,
+                           isbn, publisher, language, pages, format, description, cover_url, work_id)
+        VALUES ($1, $2, COALESCE($3, '{}'::text[]), $4, $5, 0, $6, $7,
+                NULLIF($8, ''), $9, $10, $11, $12, $13, $14, $15)
         RETURNING id
     `
-	err := s.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		book.Title,
 		book.Author.ID,
+		pq.Array(book.Genres),
 		book.PublishedAt,
 		book.Price,
-		book.Stock,
+		book.LowStockThreshold,
+		book.WeightGrams,
+		book.ISBN,
+		book.Publisher,
+		book.Language,
+		book.Pages,
+		book.Format,
+		book.Description,
+		book.CoverURL,
+		book.WorkID,
 	).Scan(&book.ID)
 	if err != nil {
-		return book, fmt.Errorf("CreateBook error: %w", err)
+		return book, bookError("CreateBook", err)
 	}
-	return book, nil
+	if err := setContributors(ctx, tx, book.ID, book.Contributors); err != nil {
+		return book, bookError("CreateBook", err)
+	}
+
+	if book.Stock != 0 {
+		_, err = recordMovement(ctx, tx, models.StockMovement{
+			BookID: book.ID,
+			Change: book.Stock,
+			Reason: models.MovementAdjustment,
+			Note:   "initial stock",
+		})
+		if err != nil {
+			return book, fmt.Errorf("CreateBook error: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return book, err
+	}
+	// Read back so the contributors come with their names.
+	return s.GetBook(ctx, book.ID)
 }
 
 
 func (s *PostgresBookStore) GetBook(ctx context.Context, id int) (models.Book, error) {
-	var book models.Book
-	var author models.Author
-
-	query := `
-        SELECT b.id, b.title, b.published_at, b.price, b.stock,
-               a.id, a.first_name, a.last_name, a.bio
+	query := `SELECT ` + bookColumns + `
         FROM books b
         JOIN authors a ON b.author_id = a.id
         WHERE b.id = $1
     `
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&book.ID,
-		&book.Title,
-		&book.PublishedAt,
-		&book.Price,
-		&book.Stock,
-		&author.ID,
-		&author.FirstName,
-		&author.LastName,
-		&author.Bio,
-	)
+	book, err := scanBook(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return book, fmt.Errorf("book not found with id: %d", id)
+			return book, fmt.Errorf("%w with id: %d", models.ErrBookNotFound, id)
 		}
 		return book, err
 	}
-	book.Author = author
 	return book, nil
 }
 
 
+// UpdateBook updates the catalog fields of a book. Stock is owned by the
+// stock ledger and changes only through restocks and adjustments, so the
+// book's stock field is ignored. The book is returned as stored.
 func (s *PostgresBookStore) UpdateBook(ctx context.Context, id int, book models.Book) (models.Book, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return book, fmt.Errorf("UpdateBook error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldPrice float64
+	err = tx.QueryRowContext(ctx, `SELECT price FROM books WHERE id = $1 FOR UPDATE`, id).Scan(&oldPrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return book, fmt.Errorf("%w with id: %d", models.ErrBookNotFound, id)
+		}
+		return book, fmt.Errorf("UpdateBook error: %w", err)
+	}
+
+	// A book sent without contributors keeps its own; a new author only
+	// takes over the first author credit.
+	if book.Contributors == nil {
+		if book.Contributors, err = bookContributors(ctx, tx, id); err != nil {
+			return book, fmt.Errorf("UpdateBook error: %w", err)
+		}
+		for i, c := range book.Contributors {
+			if c.Role == models.RoleAuthor {
+				if c.Author.ID != book.Author.ID {
+					book.Contributors[i].Author = book.Author
+				}
+				break
+			}
+		}
+	}
+	creditAuthor(&book)
 	query := `
         UPDATE books
         SET title = $1,
             author_id = $2,
-            published_at = $3,
-            price = $4,
-            stock = $5
-        WHERE id = $6
+            genres = COALESCE($3, '{}'::text[]),
+            published_at = $4,
+            price = $5,
+            low_stock_threshold = $6,
+            weight_grams = $7,
+            isbn = NULLIF($8, ''),
+            publisher = $9,
+            language = $10,
+            pages = $11,
+            format = $12,
+            description = $13,
+            cover_url = $14,
+            work_id = $15
+        WHERE id = $16
     `
-	_, err := s.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		book.Title,
 		book.Author.ID,
+		pq.Array(book.Genres),
 		book.PublishedAt,
 		book.Price,
-		book.Stock,
+		book.LowStockThreshold,
+		book.WeightGrams,
+		book.ISBN,
+		book.Publisher,
+		book.Language,
+		book.Pages,
+		book.Format,
+		book.Description,
+		book.CoverURL,
+		book.WorkID,
 		id,
 	)
 	if err != nil {
-		return book, fmt.Errorf("UpdateBook error: %w", err)
+		return book, bookError("UpdateBook", err)
 	}
-	book.ID = id
-	return book, nil
+	if err := setContributors(ctx, tx, id, book.Contributors); err != nil {
+		return book, bookError("UpdateBook", err)
+	}
+	if book.Price != oldPrice {
+		err := enqueueEvent(ctx, tx, events.BookPriceChangedEvent{BookID: id, OldPrice: oldPrice, NewPrice: book.Price})
+		if err != nil {
+			return book, fmt.Errorf("UpdateBook: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return book, fmt.Errorf("UpdateBook commit: %w", err)
+	}
+	return s.GetBook(ctx, id)
+}
+
+
+// AdjustPrice multiplies a book's price by factor, rounded to the cent,
+// leaving every other field as it is.
+func (s *PostgresBookStore) AdjustPrice(ctx context.Context, id int, factor float64) (models.Book, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Book{}, fmt.Errorf("AdjustPrice error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldPrice, newPrice float64
+	err = tx.QueryRowContext(ctx, `SELECT price FROM books WHERE id = $1 FOR UPDATE`, id).Scan(&oldPrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Book{}, fmt.Errorf("%w with id: %d", models.ErrBookNotFound, id)
+		}
+		return models.Book{}, fmt.Errorf("AdjustPrice error: %w", err)
+	}
+	err = tx.QueryRowContext(ctx,
+		`UPDATE books SET price = ROUND(price * $1, 2) WHERE id = $2 RETURNING price`,
+		factor, id,
+	).Scan(&newPrice)
+	if err != nil {
+		return models.Book{}, fmt.Errorf("AdjustPrice error: %w", err)
+	}
+	if newPrice != oldPrice {
+		err := enqueueEvent(ctx, tx, events.BookPriceChangedEvent{BookID: id, OldPrice: oldPrice, NewPrice: newPrice})
+		if err != nil {
+			return models.Book{}, fmt.Errorf("AdjustPrice: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Book{}, fmt.Errorf("AdjustPrice commit: %w", err)
+	}
+	return s.GetBook(ctx, id)
 }
 
 
@@ -110,9 +372,7 @@ func (s *PostgresBookStore) DeleteBook(ctx context.Context, id int) error {
 
 
 func (s *PostgresBookStore) ListBooks(ctx context.Context) ([]models.Book, error) {
-	query := `
-        SELECT b.id, b.title, b.published_at, b.price, b.stock,
-               a.id, a.first_name, a.last_name, a.bio
+	query := `SELECT ` + bookColumns + `
         FROM books b
         JOIN authors a ON b.author_id = a.id
         ORDER BY b.id
@@ -123,28 +383,24 @@ func (s *PostgresBookStore) ListBooks(ctx context.Context) ([]models.Book, error
 	}
 	defer rows.Close()
 
-	var result []models.Book
-	for rows.Next() {
-		var book models.Book
-		var author models.Author
-		err := rows.Scan(
-			&book.ID,
-			&book.Title,
-			&book.PublishedAt,
-			&book.Price,
-			&book.Stock,
-			&author.ID,
-			&author.FirstName,
-			&author.LastName,
-			&author.Bio,
-		)
-		if err != nil {
-			return nil, err
-		}
-		book.Author = author
-		result = append(result, book)
+	return scanBooks(rows)
+}
+
+
+func (s *PostgresBookStore) ListBooksByAuthor(ctx context.Context, authorID int) ([]models.Book, error) {
+	query := `SELECT ` + bookColumns + `
+        FROM books b
+        JOIN authors a ON b.author_id = a.id
+        WHERE b.id IN (SELECT book_id FROM book_contributors WHERE author_id = $1)
+        ORDER BY b.id
+    `
+	rows, err := s.db.QueryContext(ctx, query, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("ListBooksByAuthor error: %w", err)
 	}
-	return result, rows.Err()
+	defer rows.Close()
+
+	return scanBooks(rows)
 }
 
 
@@ -161,9 +417,18 @@ func (s *PostgresBookStore) SearchBooks(ctx context.Context, criteria models.Sea
 		i++
 	}
 	if criteria.Author != "" {
-		clauses = append(clauses, fmt.Sprintf("(LOWER(a.first_name) LIKE LOWER($%d) OR LOWER(a.last_name) LIKE LOWER($%d))", i, i+1))
-		args = append(args, "%"+criteria.Author+"%", "%"+criteria.Author+"%")
-		i += 2
+		// Any contributor matches, by first, last or full name.
+		clauses = append(clauses, fmt.Sprintf(`EXISTS (
+            SELECT 1 FROM book_contributors bc
+            JOIN authors ca ON ca.id = bc.author_id
+            WHERE bc.book_id = b.id AND LOWER(ca.first_name || ' ' || ca.last_name) LIKE LOWER($%d))`, i))
+		args = append(args, "%"+criteria.Author+"%")
+		i++
+	}
+	if len(criteria.Genres) > 0 {
+		clauses = append(clauses, fmt.Sprintf("b.genres && $%d", i))
+		args = append(args, pq.Array(criteria.Genres))
+		i++
 	}
 	if criteria.MinPrice > 0 {
 		clauses = append(clauses, fmt.Sprintf("b.price >= $%d", i))
@@ -195,10 +460,43 @@ func (s *PostgresBookStore) SearchBooks(ctx context.Context, criteria models.Sea
 		args = append(args, criteria.MaxStock)
 		i++
 	}
+	if criteria.ISBN != "" {
+		clauses = append(clauses, fmt.Sprintf("b.isbn = $%d", i))
+		args = append(args, criteria.ISBN)
+		i++
+	}
+	if criteria.Publisher != "" {
+		clauses = append(clauses, fmt.Sprintf("LOWER(b.publisher) LIKE LOWER($%d)", i))
+		args = append(args, "%"+criteria.Publisher+"%")
+		i++
+	}
+	if criteria.Language != "" {
+		clauses = append(clauses, fmt.Sprintf("LOWER(b.language) = LOWER($%d)", i))
+		args = append(args, criteria.Language)
+		i++
+	}
+	if criteria.Format != "" {
+		clauses = append(clauses, fmt.Sprintf("b.format = $%d", i))
+		args = append(args, criteria.Format)
+		i++
+	}
+	if criteria.MinPages > 0 {
+		clauses = append(clauses, fmt.Sprintf("b.pages >= $%d", i))
+		args = append(args, criteria.MinPages)
+		i++
+	}
+	if criteria.MaxPages > 0 {
+		clauses = append(clauses, fmt.Sprintf("b.pages <= $%d", i))
+		args = append(args, criteria.MaxPages)
+		i++
+	}
+	if criteria.WorkID > 0 {
+		clauses = append(clauses, fmt.Sprintf("b.work_id = $%d", i))
+		args = append(args, criteria.WorkID)
+		i++
+	}
 
-	query := `
-        SELECT b.id, b.title, b.published_at, b.price, b.stock,
-               a.id, a.first_name, a.last_name, a.bio
+	query := `SELECT ` + bookColumns + `
         FROM books b
         JOIN authors a ON b.author_id = a.id
     `
@@ -213,25 +511,16 @@ func (s *PostgresBookStore) SearchBooks(ctx context.Context, criteria models.Sea
 	}
 	defer rows.Close()
 
+	return scanBooks(rows)
+}
+
+func scanBooks(rows *sql.Rows) ([]models.Book, error) {
 	var result []models.Book
 	for rows.Next() {
-		var book models.Book
-		var author models.Author
-		err := rows.Scan(
-			&book.ID,
-			&book.Title,
-			&book.PublishedAt,
-			&book.Price,
-			&book.Stock,
-			&author.ID,
-			&author.FirstName,
-			&author.LastName,
-			&author.Bio,
-		)
+		book, err := scanBook(rows)
 		if err != nil {
 			return nil, err
 		}
-		book.Author = author
 		result = append(result, book)
 	}
 	return result, rows.Err()
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+// PostgresCartStore keeps one active cart per customer. Every change to a
+// cart pushes its expiry ttl into the future.
+type PostgresCartStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+func NewPostgresCartStore(db *sql.DB, ttl time.Duration) (interfaces.CartStore, error) {
+	return &PostgresCartStore{db: db, ttl: ttl}, nil
+}
+
+func (s *PostgresCartStore) GetOrCreateCart(ctx context.Context, customerID int) (models.Cart, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Cart{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM carts WHERE customer_id = $1 AND status = $2 AND expires_at <= $3`,
+		customerID, models.CartActive, now,
+	)
+	if err != nil {
+		return models.Cart{}, fmt.Errorf("GetOrCreateCart error: %w", err)
+	}
+
+	query := `
+        INSERT INTO carts (customer_id, status, created_at, updated_at, expires_at)
+        VALUES ($1, $2, $3, $3, $4)
+        ON CONFLICT (customer_id) WHERE status = 'active' DO NOTHING
+    `
+	_, err = tx.ExecContext(ctx, query, customerID, models.CartActive, now, now.Add(s.ttl))
+	if err != nil {
+		return models.Cart{}, fmt.Errorf("GetOrCreateCart error: %w", err)
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM carts WHERE customer_id = $1 AND status = $2`,
+		customerID, models.CartActive,
+	).Scan(&id)
+	if err != nil {
+		return models.Cart{}, fmt.Errorf("GetOrCreateCart error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Cart{}, err
+	}
+	return s.GetCart(ctx, id)
+}
+
+func (s *PostgresCartStore) GetCart(ctx context.Context, id int) (models.Cart, error) {
+	var (
+		cart    models.Cart
+		orderID sql.NullInt64
+	)
+	query := `
+        SELECT id, customer_id, status, order_id, created_at, updated_at, expires_at
+        FROM carts
+        WHERE id = $1
+    `
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&cart.ID,
+		&cart.CustomerID,
+		&cart.Status,
+		&orderID,
+		&cart.CreatedAt,
+		&cart.UpdatedAt,
+		&cart.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return cart, fmt.Errorf("cart not found with id: %d", id)
+		}
+		return cart, err
+	}
+	if orderID.Valid {
+		oid := int(orderID.Int64)
+		cart.OrderID = &oid
+	}
+
+	items, err := s.getCartItems(ctx, id)
+	if err != nil {
+		return cart, err
+	}
+	cart.Items = items
+	for _, item := range items {
+		cart.Subtotal += item.LineTotal
+	}
+	return cart, nil
+}
+
+func (s *PostgresCartStore) AddItem(ctx context.Context, cartID, bookID, quantity int) (models.Cart, error) {
+	query := `
+        INSERT INTO cart_items (cart_id, book_id, quantity, added_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (cart_id, book_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+    `
+	return s.modify(ctx, cartID, func(tx *sql.Tx, now time.Time) error {
+		_, err := tx.ExecContext(ctx, query, cartID, bookID, quantity, now)
+		return err
+	})
+}
+
+func (s *PostgresCartStore) SetItemQuantity(ctx context.Context, cartID, bookID, quantity int) (models.Cart, error) {
+	if quantity == 0 {
+		return s.RemoveItem(ctx, cartID, bookID)
+	}
+	query := `
+        INSERT INTO cart_items (cart_id, book_id, quantity, added_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (cart_id, book_id) DO UPDATE SET quantity = EXCLUDED.quantity
+    `
+	return s.modify(ctx, cartID, func(tx *sql.Tx, now time.Time) error {
+		_, err := tx.ExecContext(ctx, query, cartID, bookID, quantity, now)
+		return err
+	})
+}
+
+func (s *PostgresCartStore) RemoveItem(ctx context.Context, cartID, bookID int) (models.Cart, error) {
+	return s.modify(ctx, cartID, func(tx *sql.Tx, now time.Time) error {
+		_, err := tx.ExecContext(ctx,
+			`DELETE FROM cart_items WHERE cart_id = $1 AND book_id = $2`,
+			cartID, bookID,
+		)
+		return err
+	})
+}
+
+func (s *PostgresCartStore) DeleteCart(ctx context.Context, id int) error {
+	query := `DELETE FROM carts WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("DeleteCart error: %w", err)
+	}
+	return nil
+}
+
+// BeginCheckout moves an active cart to checking_out so that a concurrent
+// checkout of the same cart cannot create a second order.
+func (s *PostgresCartStore) BeginCheckout(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockActiveCart(ctx, tx, id, time.Now()); err != nil {
+		return err
+	}
+	// updated_at marks when the checkout began, for ReleaseStaleCheckouts.
+	_, err = tx.ExecContext(ctx,
+		`UPDATE carts SET status = $1, updated_at = $2 WHERE id = $3`,
+		models.CartCheckingOut, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("BeginCheckout error: %w", err)
+	}
+	return tx.Commit()
+}
+
+// checkOutCart closes a cart whose checkout created the order, in the
+// order's transaction. A cart no longer checking out, e.g. one released as
+// stale, fails the order.
+func checkOutCart(ctx context.Context, tx *sql.Tx, id, orderID int) error {
+	query := `
+        UPDATE carts
+        SET status = $1, order_id = $2, updated_at = $3
+        WHERE id = $4 AND status = $5
+    `
+	res, err := tx.ExecContext(ctx, query,
+		models.CartCheckedOut, orderID, time.Now(), id, models.CartCheckingOut,
+	)
+	if err != nil {
+		return fmt.Errorf("cart checkout failed: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: cart %d", models.ErrCartNotActive, id)
+	}
+	return nil
+}
+
+// AbortCheckout returns a cart to active after a failed checkout.
+func (s *PostgresCartStore) AbortCheckout(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE carts SET status = $1 WHERE id = $2 AND status = $3`,
+		models.CartActive, id, models.CartCheckingOut,
+	)
+	if err != nil {
+		return fmt.Errorf("AbortCheckout error: %w", err)
+	}
+	return nil
+}
+
+// ReleaseStaleCheckouts recovers carts left in checking_out by a server that
+// stopped in the middle of a checkout.
+func (s *PostgresCartStore) ReleaseStaleCheckouts(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE carts SET status = $1, updated_at = $2 WHERE status = $3 AND updated_at <= $4`,
+		models.CartActive, time.Now(), models.CartCheckingOut, before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("ReleaseStaleCheckouts error: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (s *PostgresCartStore) DeleteExpiredCarts(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM carts WHERE status = $1 AND expires_at <= $2`,
+		models.CartActive, before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteExpiredCarts error: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// modify runs fn against an active, unexpired cart and slides its expiry.
+func (s *PostgresCartStore) modify(ctx context.Context, cartID int, fn func(tx *sql.Tx, now time.Time) error) (models.Cart, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Cart{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if err := lockActiveCart(ctx, tx, cartID, now); err != nil {
+		return models.Cart{}, err
+	}
+	if err := fn(tx, now); err != nil {
+		return models.Cart{}, fmt.Errorf("cart update failed: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE carts SET updated_at = $1, expires_at = $2 WHERE id = $3`,
+		now, now.Add(s.ttl), cartID,
+	)
+	if err != nil {
+		return models.Cart{}, fmt.Errorf("cart update failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Cart{}, err
+	}
+	return s.GetCart(ctx, cartID)
+}
+
+func lockActiveCart(ctx context.Context, tx *sql.Tx, id int, now time.Time) error {
+	var (
+		status    string
+		expiresAt time.Time
+	)
+	err := tx.QueryRowContext(ctx,
+		`SELECT status, expires_at FROM carts WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&status, &expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("cart not found with id: %d", id)
+		}
+		return err
+	}
+	if status != models.CartActive {
+		return models.ErrCartNotActive
+	}
+	if !expiresAt.After(now) {
+		return models.ErrCartExpired
+	}
+	return nil
+}
+
+func (s *PostgresCartStore) getCartItems(ctx context.Context, cartID int) ([]models.CartItem, error) {
+	query := `SELECT ci.quantity, ` + bookColumns + `
+        FROM cart_items ci
+        JOIN books b ON ci.book_id = b.id
+        JOIN authors a ON b.author_id = a.id
+        WHERE ci.cart_id = $1
+        ORDER BY ci.added_at, b.id
+    `
+	rows, err := s.db.QueryContext(ctx, query, cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.CartItem
+	for rows.Next() {
+		var item models.CartItem
+		book, err := scanBook(prefixScanner{rows, []interface{}{&item.Quantity}})
+		if err != nil {
+			return nil, err
+		}
+		item.Book = book
+		item.LineTotal = book.Price * float64(item.Quantity)
+		item.Available = book.Stock >= item.Quantity
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// prefixScanner lets scanBook read rows that carry extra leading columns.
+type prefixScanner struct {
+	row    rowScanner
+	prefix []interface{}
+}
+
+func (p prefixScanner) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(append([]interface{}{}, p.prefix...), dest...)...)
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"time"
 
+	"bookstore/internal/events"
 	"bookstore/internal/interfaces"
 	"bookstore/internal/models"
 )
@@ -25,8 +27,14 @@ func (s *PostgresCustomerStore) CreateCustomer(ctx context.Context, customer mod
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id
     `
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return customer, fmt.Errorf("CreateCustomer error: %w", err)
+	}
+	defer tx.Rollback()
+
 	now := time.Now()
-	err := s.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		customer.Name,
 		customer.Email,
 		customer.Address.Street,
@@ -39,6 +47,19 @@ func (s *PostgresCustomerStore) CreateCustomer(ctx context.Context, customer mod
 	if err != nil {
 		return customer, fmt.Errorf("CreateCustomer error: %w", err)
 	}
+
+	err = enqueueEvent(ctx, tx, events.CustomerRegisteredEvent{
+		CustomerID: customer.ID,
+		Name:       customer.Name,
+		Email:      customer.Email,
+	})
+	if err != nil {
+		return customer, fmt.Errorf("CreateCustomer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return customer, fmt.Errorf("CreateCustomer commit: %w", err)
+	}
 	customer.CreatedAt = now
 	return customer, nil
 }
@@ -46,7 +67,7 @@ func (s *PostgresCustomerStore) CreateCustomer(ctx context.Context, customer mod
 func (s *PostgresCustomerStore) GetCustomer(ctx context.Context, id int) (models.Customer, error) {
 	var c models.Customer
 	query := `
-        SELECT id, name, email, street, city, state, postal_code, country, created_at
+        SELECT id, name, email, street, city, state, postal_code, country, created_at, erased_at
         FROM customers
         WHERE id = $1
     `
@@ -60,6 +81,7 @@ func (s *PostgresCustomerStore) GetCustomer(ctx context.Context, id int) (models
 		&c.Address.PostalCode,
 		&c.Address.Country,
 		&c.CreatedAt,
+		&c.ErasedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -76,14 +98,17 @@ func (s *PostgresCustomerStore) UpdateCustomer(ctx context.Context, id int, cust
 	if err != nil {
 		return customer, err
 	}
+	if existing.ErasedAt != nil {
+		return customer, fmt.Errorf("%w: customer %d", models.ErrCustomerErased, id)
+	}
 
 	query := `
         UPDATE customers
         SET name = $1, email = $2, street = $3, city = $4,
             state = $5, postal_code = $6, country = $7
-        WHERE id = $8
+        WHERE id = $8 AND erased_at IS NULL
     `
-	_, err = s.db.ExecContext(ctx, query,
+	res, err := s.db.ExecContext(ctx, query,
 		customer.Name,
 		customer.Email,
 		customer.Address.Street,
@@ -96,23 +121,155 @@ func (s *PostgresCustomerStore) UpdateCustomer(ctx context.Context, id int, cust
 	if err != nil {
 		return customer, fmt.Errorf("UpdateCustomer error: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return customer, fmt.Errorf("%w: customer %d", models.ErrCustomerErased, id)
+	}
 	customer.ID = id
 	customer.CreatedAt = existing.CreatedAt
+	customer.ErasedAt = existing.ErasedAt
 	return customer, nil
 }
 
+// DeleteCustomer deletes a customer without orders. The customer row is
+// locked first, so no order can be placed for it in the meantime.
 func (s *PostgresCustomerStore) DeleteCustomer(ctx context.Context, id int) error {
-	query := `DELETE FROM customers WHERE id = $1`
-	_, err := s.db.ExecContext(ctx, query, id)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("DeleteCustomer error: %w", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	if err := lockCustomer(ctx, tx, id); err != nil {
+		return err
+	}
+	var orders int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE customer_id = $1`, id).Scan(&orders); err != nil {
+		return fmt.Errorf("DeleteCustomer error: %w", err)
+	}
+	if orders > 0 {
+		return fmt.Errorf("%w: customer %d has %d orders, erase the customer instead", models.ErrCustomerHasOrders, id, orders)
+	}
+
+	query := `DELETE FROM customers WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("DeleteCustomer error: %w", err)
+	}
+	return tx.Commit()
+}
+
+// erasedName replaces the name of an erased customer.
+const erasedName = "Erased customer"
+
+func (s *PostgresCustomerStore) EraseCustomer(ctx context.Context, id int, actor string) (models.Customer, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("EraseCustomer error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var erasedAt *time.Time
+	var email, accountEmail string
+	err = tx.QueryRowContext(ctx,
+		`SELECT erased_at, email FROM customers WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&erasedAt, &email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Customer{}, fmt.Errorf("customer not found with id: %d", id)
+		}
+		return models.Customer{}, fmt.Errorf("EraseCustomer error: %w", err)
+	}
+	if erasedAt != nil {
+		tx.Rollback()
+		return s.GetCustomer(ctx, id)
+	}
+	// Customers sign in with their account's email, which is what the
+	// records they made name them by.
+	err = tx.QueryRowContext(ctx, `SELECT email FROM accounts WHERE customer_id = $1`, id).Scan(&accountEmail)
+	if err != nil && err != sql.ErrNoRows {
+		return models.Customer{}, fmt.Errorf("EraseCustomer (accounts): %w", err)
+	}
+	if accountEmail == "" {
+		accountEmail = email
+	}
+
+	now := time.Now()
+	// The email stays unique per customer and is not deliverable.
+	erasedEmail := fmt.Sprintf("erased-%d@invalid", id)
+	_, err = tx.ExecContext(ctx, `
+        UPDATE customers
+        SET name = $1, email = $2, street = '', city = '',
+            state = '', postal_code = '', country = '', erased_at = $3
+        WHERE id = $4
+    `, erasedName, erasedEmail, now, id)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("EraseCustomer (customers): %w", err)
+	}
+
+	steps := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{"accounts", `DELETE FROM accounts WHERE customer_id = $1`, []interface{}{id}},
+		{"addresses", `DELETE FROM addresses WHERE customer_id = $1`, []interface{}{id}},
+		{"carts", `DELETE FROM carts WHERE customer_id = $1 AND status = $2`, []interface{}{id, models.CartActive}},
+		// Tax is reported by state and country, so orders keep those.
+		{"orders", `
+            UPDATE orders
+            SET shipping_address = CASE WHEN shipping_address IS NULL THEN NULL ELSE
+                    jsonb_build_object('state', shipping_address->>'state', 'country', shipping_address->>'country') END,
+                billing_address = CASE WHEN billing_address IS NULL THEN NULL ELSE
+                    jsonb_build_object('state', billing_address->>'state', 'country', billing_address->>'country') END
+            WHERE customer_id = $1
+        `, []interface{}{id}},
+		// The registration event carries the name and email.
+		{"outbox_events", `
+            UPDATE outbox_events SET payload = payload - 'name' - 'email'
+            WHERE type = $1 AND payload->>'customer_id' = $2
+        `, []interface{}{events.CustomerRegistered, strconv.Itoa(id)}},
+		{"webhook_deliveries", `
+            UPDATE webhook_deliveries SET payload = payload - 'name' - 'email'
+            WHERE event_type = $1 AND payload->>'customer_id' = $2
+        `, []interface{}{events.CustomerRegistered, strconv.Itoa(id)}},
+		// Records the customer made name them by email.
+		{"stock_movements", `
+            UPDATE stock_movements SET actor = $1 WHERE LOWER(actor) IN (LOWER($2), LOWER($3))
+        `, []interface{}{erasedEmail, email, accountEmail}},
+		{"audit_log", `
+            UPDATE audit_log SET actor = $1 WHERE LOWER(actor) IN (LOWER($2), LOWER($3))
+        `, []interface{}{erasedEmail, email, accountEmail}},
+		// Stored responses may hold addresses.
+		{"idempotency_keys", `
+            DELETE FROM idempotency_keys WHERE LOWER(username) IN (LOWER($1), LOWER($2))
+        `, []interface{}{email, accountEmail}},
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step.query, step.args...); err != nil {
+			return models.Customer{}, fmt.Errorf("EraseCustomer (%s): %w", step.name, err)
+		}
+	}
+
+	if err := enqueueEvent(ctx, tx, events.CustomerErasedEvent{CustomerID: id}); err != nil {
+		return models.Customer{}, fmt.Errorf("EraseCustomer: %w", err)
+	}
+	err = recordAudit(ctx, tx, models.AuditEntry{
+		CustomerID: id,
+		Action:     models.AuditCustomerErased,
+		Actor:      actor,
+	})
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("EraseCustomer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Customer{}, fmt.Errorf("EraseCustomer commit: %w", err)
+	}
+	return s.GetCustomer(ctx, id)
 }
 
 func (s *PostgresCustomerStore) ListCustomers(ctx context.Context) ([]models.Customer, error) {
 	query := `
-        SELECT id, name, email, street, city, state, postal_code, country, created_at
+        SELECT id, name, email, street, city, state, postal_code, country, created_at, erased_at
         FROM customers
         ORDER BY id
     `
@@ -135,6 +292,7 @@ func (s *PostgresCustomerStore) ListCustomers(ctx context.Context) ([]models.Cus
 			&c.Address.PostalCode,
 			&c.Address.Country,
 			&c.CreatedAt,
+			&c.ErasedAt,
 		); err != nil {
 			return nil, err
 		}
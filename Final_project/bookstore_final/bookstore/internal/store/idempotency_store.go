@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresIdempotencyStore struct {
+	db *sql.DB
+}
+
+func NewPostgresIdempotencyStore(db *sql.DB) (interfaces.IdempotencyStore, error) {
+	return &PostgresIdempotencyStore{db: db}, nil
+}
+
+func (s *PostgresIdempotencyStore) BeginRequest(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	// An expired key is taken over as if it had never been used.
+	res, err := s.db.ExecContext(ctx, `
+        INSERT INTO idempotency_keys (username, key, method, path, request_hash, status, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (username, key) DO UPDATE
+        SET method = EXCLUDED.method, path = EXCLUDED.path, request_hash = EXCLUDED.request_hash,
+            status = EXCLUDED.status, response_status = 0, content_type = '', response_body = NULL,
+            created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+        WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+    `,
+		record.Username,
+		record.Key,
+		record.Method,
+		record.Path,
+		record.RequestHash,
+		models.IdempotencyInProgress,
+		record.CreatedAt,
+		record.ExpiresAt,
+	)
+	if err != nil {
+		return record, false, fmt.Errorf("BeginRequest error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return record, true, nil
+	}
+
+	var existing models.IdempotencyRecord
+	err = s.db.QueryRowContext(ctx, `
+        SELECT username, key, method, path, request_hash, status, response_status,
+               content_type, COALESCE(response_body, ''::bytea), created_at, expires_at
+        FROM idempotency_keys
+        WHERE username = $1 AND key = $2
+    `, record.Username, record.Key).Scan(
+		&existing.Username,
+		&existing.Key,
+		&existing.Method,
+		&existing.Path,
+		&existing.RequestHash,
+		&existing.Status,
+		&existing.ResponseStatus,
+		&existing.ContentType,
+		&existing.ResponseBody,
+		&existing.CreatedAt,
+		&existing.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		// Released between the insert and the select; try again.
+		return s.BeginRequest(ctx, record)
+	}
+	if err != nil {
+		return existing, false, fmt.Errorf("BeginRequest error: %w", err)
+	}
+	return existing, false, nil
+}
+
+func (s *PostgresIdempotencyStore) CompleteRequest(ctx context.Context, record models.IdempotencyRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE idempotency_keys
+        SET status = $1, response_status = $2, content_type = $3, response_body = $4
+        WHERE username = $5 AND key = $6
+    `,
+		models.IdempotencyCompleted,
+		record.ResponseStatus,
+		record.ContentType,
+		record.ResponseBody,
+		record.Username,
+		record.Key,
+	)
+	if err != nil {
+		return fmt.Errorf("CompleteRequest error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) ReleaseRequest(ctx context.Context, username, key string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE username = $1 AND key = $2`, username, key,
+	)
+	if err != nil {
+		return fmt.Errorf("ReleaseRequest error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteExpiredKeys error: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"bookstore/internal/auth"
+	"bookstore/internal/events"
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresInventoryStore struct {
+	db *sql.DB
+}
+
+func NewPostgresInventoryStore(db *sql.DB) (interfaces.InventoryStore, error) {
+	return &PostgresInventoryStore{db: db}, nil
+}
+
+func (s *PostgresInventoryStore) RecordMovement(ctx context.Context, movement models.StockMovement) (models.StockMovement, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return movement, err
+	}
+	defer tx.Rollback()
+
+	movement, err = recordMovement(ctx, tx, movement)
+	if err != nil {
+		return movement, err
+	}
+	if err := tx.Commit(); err != nil {
+		return movement, err
+	}
+	return movement, nil
+}
+
+func (s *PostgresInventoryStore) ListMovements(ctx context.Context, bookID int) ([]models.StockMovement, error) {
+	query := `
+        SELECT id, book_id, change, balance, reason, actor, order_id, note, created_at
+        FROM stock_movements
+        WHERE book_id = $1
+        ORDER BY id
+    `
+	rows, err := s.db.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("ListMovements error: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []models.StockMovement
+	for rows.Next() {
+		var (
+			m       models.StockMovement
+			orderID sql.NullInt64
+		)
+		if err := rows.Scan(
+			&m.ID,
+			&m.BookID,
+			&m.Change,
+			&m.Balance,
+			&m.Reason,
+			&m.Actor,
+			&orderID,
+			&m.Note,
+			&m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if orderID.Valid {
+			id := int(orderID.Int64)
+			m.OrderID = &id
+		}
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
+
+// ReconcileStock resets the book's stock column to the sum of its ledger
+// entries, repairing any drift caused by writes that bypassed the ledger.
+func (s *PostgresInventoryStore) ReconcileStock(ctx context.Context, bookID int) (models.Book, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Book{}, err
+	}
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRowContext(ctx, `SELECT stock FROM books WHERE id = $1 FOR UPDATE`, bookID).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Book{}, fmt.Errorf("%w with id: %d", models.ErrBookNotFound, bookID)
+		}
+		return models.Book{}, fmt.Errorf("ReconcileStock error: %w", err)
+	}
+
+	var ledger int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(change), 0) FROM stock_movements WHERE book_id = $1`,
+		bookID,
+	).Scan(&ledger)
+	if err != nil {
+		return models.Book{}, fmt.Errorf("ReconcileStock error: %w", err)
+	}
+
+	if ledger != current {
+		_, err = tx.ExecContext(ctx, `UPDATE books SET stock = $1 WHERE id = $2`, ledger, bookID)
+		if err != nil {
+			return models.Book{}, fmt.Errorf("ReconcileStock error: %w", err)
+		}
+	}
+
+	book, err := scanBook(tx.QueryRowContext(ctx, `SELECT `+bookColumns+`
+        FROM books b
+        JOIN authors a ON b.author_id = a.id
+        WHERE b.id = $1
+    `, bookID))
+	if err != nil {
+		return book, fmt.Errorf("ReconcileStock error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return book, err
+	}
+	return book, nil
+}
+
+func (s *PostgresInventoryStore) ListLowStock(ctx context.Context) ([]models.Book, error) {
+	query := `SELECT ` + bookColumns + `
+        FROM books b
+        JOIN authors a ON b.author_id = a.id
+        WHERE b.low_stock_threshold > 0 AND b.stock <= b.low_stock_threshold
+        ORDER BY b.stock, b.id
+    `
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ListLowStock error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBooks(rows)
+}
+
+func (s *PostgresInventoryStore) CountStockAlerts(ctx context.Context) (int, int, error) {
+	var outOfStock, lowStock int
+	err := s.db.QueryRowContext(ctx, `
+        SELECT COUNT(*) FILTER (WHERE stock <= 0),
+               COUNT(*) FILTER (WHERE low_stock_threshold > 0 AND stock <= low_stock_threshold)
+        FROM books
+    `).Scan(&outOfStock, &lowStock)
+	if err != nil {
+		return 0, 0, fmt.Errorf("CountStockAlerts error: %w", err)
+	}
+	return outOfStock, lowStock, nil
+}
+
+// recordMovement applies a stock movement to the book and appends it to the
+// ledger inside the caller's transaction. The caller must roll back when an
+// error is returned, including models.ErrInsufficientStock.
+func recordMovement(ctx context.Context, tx *sql.Tx, m models.StockMovement) (models.StockMovement, error) {
+	if m.Actor == "" {
+		m.Actor = auth.Username(ctx)
+	}
+
+	var title string
+	var threshold int
+	err := tx.QueryRowContext(ctx,
+		`UPDATE books SET stock = stock + $1 WHERE id = $2 RETURNING stock, title, low_stock_threshold`,
+		m.Change, m.BookID,
+	).Scan(&m.Balance, &title, &threshold)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return m, fmt.Errorf("%w with id: %d", models.ErrBookNotFound, m.BookID)
+		}
+		return m, fmt.Errorf("stock update failed: %w", err)
+	}
+	if m.Balance < 0 {
+		return m, fmt.Errorf("%w for book: %s", models.ErrInsufficientStock, title)
+	}
+
+	query := `
+        INSERT INTO stock_movements (book_id, change, balance, reason, actor, order_id, note, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+        RETURNING id, created_at
+    `
+	err = tx.QueryRowContext(ctx, query,
+		m.BookID,
+		m.Change,
+		m.Balance,
+		m.Reason,
+		m.Actor,
+		m.OrderID,
+		m.Note,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return m, fmt.Errorf("stock ledger insert failed: %w", err)
+	}
+
+	err = enqueueEvent(ctx, tx, events.StockChangedEvent{
+		BookID: m.BookID,
+		Change: m.Change,
+		Stock:  m.Balance,
+		Reason: m.Reason,
+	})
+	if err != nil {
+		return m, err
+	}
+
+	// Only crossing the threshold raises the event, not every sale below it.
+	previous := m.Balance - m.Change
+	if threshold > 0 && m.Balance <= threshold && previous > threshold {
+		err := enqueueEvent(ctx, tx, events.StockLowEvent{
+			BookID:    m.BookID,
+			Title:     title,
+			Stock:     m.Balance,
+			Threshold: threshold,
+		})
+		if err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
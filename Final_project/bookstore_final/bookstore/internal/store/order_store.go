@@ -4,11 +4,15 @@ package store
 import (
     "context"
     "database/sql"
+    "encoding/json"
     "fmt"
+    "sort"
     "time"
 
+    "bookstore/internal/events"
     "bookstore/internal/interfaces"
     "bookstore/internal/models"
+    "bookstore/internal/pricing"
 )
 
 type PostgresOrderStore struct {
@@ -19,50 +23,102 @@ func NewPostgresOrderStore(db *sql.DB) (interfaces.OrderStore, error) {
     return &PostgresOrderStore{db: db}, nil
 }
 
+// CreateOrder prices the order from the catalog, inserts it and takes the
+// ordered quantities out of stock through the ledger, all in one transaction.
+// It fails with models.ErrInsufficientStock if any book runs short.
 func (s *PostgresOrderStore) CreateOrder(ctx context.Context, order models.Order) (models.Order, error) {
     tx, err := s.db.BeginTx(ctx, nil)
     if err != nil {
         return order, err
     }
+    defer tx.Rollback()
 
- 
-    var total float64
-    for _, item := range order.Items {
-        total += item.Book.Price * float64(item.Quantity)
+    items, err := loadItems(ctx, tx, order.Items)
+    if err != nil {
+        return order, fmt.Errorf("CreateOrder: %w", err)
     }
+    order.Items = items
     now := time.Now()
+    // Addresses are only ever copied from the customer's, never taken
+    // from the request.
+    order.ShippingAddress, order.BillingAddress = nil, nil
+
+    promotions, err := applicablePromotions(ctx, tx, order.Customer.ID, order.CouponCode, now)
+    if err != nil {
+        return order, fmt.Errorf("CreateOrder: %w", err)
+    }
+    if err := priceOrder(ctx, tx, &order, promotions); err != nil {
+        return order, fmt.Errorf("CreateOrder: %w", err)
+    }
+    if order.CouponCode != "" && !hasCoupon(order.Discounts) {
+        return order, fmt.Errorf("CreateOrder: %w: code %q does not apply to this order", models.ErrInvalidCoupon, order.CouponCode)
+    }
 
 
     query := `
-        INSERT INTO orders (customer_id, total_price, created_at, status)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO orders (customer_id, subtotal, discount_total, shipping_total, tax_rate, tax_total,
+                            total_price, created_at, status, shipping_address, billing_address)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
         RETURNING id
     `
     err = tx.QueryRowContext(ctx, query,
         order.Customer.ID,
-        total,
+        order.Subtotal,
+        order.DiscountTotal,
+        order.ShippingTotal,
+        order.TaxRate,
+        order.TaxTotal,
+        order.TotalPrice,
         now,
-        "pending",
+        models.OrderPending,
+        addressJSON(order.ShippingAddress),
+        addressJSON(order.BillingAddress),
     ).Scan(&order.ID)
     if err != nil {
-        tx.Rollback()
         return order, fmt.Errorf("CreateOrder (orders insert): %w", err)
     }
     order.CreatedAt = now
-    order.TotalPrice = total
-    order.Status = "pending"
+    order.Status = models.OrderPending
 
+    if err := saveOrderDiscounts(ctx, tx, order.ID, order.Discounts); err != nil {
+        return order, fmt.Errorf("CreateOrder (order_discounts insert): %w", err)
+    }
+    err = enqueueEvent(ctx, tx, events.OrderCreatedEvent{
+        OrderID:    order.ID,
+        CustomerID: order.Customer.ID,
+        TotalPrice: order.TotalPrice,
+    })
+    if err != nil {
+        return order, fmt.Errorf("CreateOrder: %w", err)
+    }
+    if err := redeemPromotions(ctx, tx, order.ID, order.Customer.ID, promotions, order.Discounts); err != nil {
+        return order, fmt.Errorf("CreateOrder: %w", err)
+    }
 
+
+    current := make(map[int]int)
     for _, item := range order.Items {
         ins := `
-            INSERT INTO order_items (order_id, book_id, quantity)
-            VALUES ($1, $2, $3)
+            INSERT INTO order_items (order_id, book_id, quantity, unit_price)
+            VALUES ($1, $2, $3, $4)
         `
-        _, err := tx.ExecContext(ctx, ins, order.ID, item.Book.ID, item.Quantity)
+        _, err := tx.ExecContext(ctx, ins, order.ID, item.Book.ID, item.Quantity, item.UnitPrice)
         if err != nil {
-            tx.Rollback()
             return order, fmt.Errorf("CreateOrder (order_items insert): %w", err)
         }
+        current[item.Book.ID] += item.Quantity
+    }
+    // moveOrderStock locks the books in id order, so that concurrent
+    // orders for the same books cannot deadlock.
+    if err := moveOrderStock(ctx, tx, order.ID, nil, current); err != nil {
+        return order, fmt.Errorf("CreateOrder: %w", err)
+    }
+    // Closing the cart here means a cart released as stale never has an
+    // order already, so checking it out again cannot order twice.
+    if order.CartID != 0 {
+        if err := checkOutCart(ctx, tx, order.CartID, order.ID); err != nil {
+            return order, fmt.Errorf("CreateOrder: %w", err)
+        }
     }
 
     if err := tx.Commit(); err != nil {
@@ -71,26 +127,28 @@ func (s *PostgresOrderStore) CreateOrder(ctx context.Context, order models.Order
     return order, nil
 }
 
-func (s *PostgresOrderStore) GetOrder(ctx context.Context, id int) (models.Order, error) {
-    var order models.Order
+const orderColumns = `
+        o.id, o.subtotal, o.discount_total, o.shipping_total, o.tax_rate, o.tax_total,
+        o.total_price, o.created_at, o.status, o.shipping_address, o.billing_address,
+        c.id, c.name, c.email, c.street, c.city, c.state, c.postal_code, c.country, c.created_at
+`
 
-
-    query := `
-        SELECT o.id, o.customer_id, o.total_price, o.created_at, o.status,
-               c.id, c.name, c.email, c.street, c.city, c.state, c.postal_code, c.country, c.created_at
-        FROM orders o
-        JOIN customers c ON o.customer_id = c.id
-        WHERE o.id = $1
-    `
-    row := s.db.QueryRowContext(ctx, query, id)
-
-    var cust models.Customer
+func scanOrder(row rowScanner) (models.Order, error) {
+    var order models.Order
+    var shipping, billing []byte
+    cust := &order.Customer
     err := row.Scan(
         &order.ID,
-        &order.Customer.ID,
+        &order.Subtotal,
+        &order.DiscountTotal,
+        &order.ShippingTotal,
+        &order.TaxRate,
+        &order.TaxTotal,
         &order.TotalPrice,
         &order.CreatedAt,
         &order.Status,
+        &shipping,
+        &billing,
         &cust.ID,
         &cust.Name,
         &cust.Email,
@@ -101,24 +159,40 @@ func (s *PostgresOrderStore) GetOrder(ctx context.Context, id int) (models.Order
         &cust.Address.Country,
         &cust.CreatedAt,
     )
+    if err != nil {
+        return order, err
+    }
+    if order.ShippingAddress, err = parseAddressJSON(shipping); err != nil {
+        return order, err
+    }
+    order.BillingAddress, err = parseAddressJSON(billing)
+    return order, err
+}
+
+func (s *PostgresOrderStore) GetOrder(ctx context.Context, id int) (models.Order, error) {
+    query := `SELECT ` + orderColumns + `
+        FROM orders o
+        JOIN customers c ON o.customer_id = c.id
+        WHERE o.id = $1
+    `
+    order, err := scanOrder(s.db.QueryRowContext(ctx, query, id))
     if err != nil {
         if err == sql.ErrNoRows {
             return order, fmt.Errorf("order not found with id: %d", id)
         }
         return order, err
     }
-    order.Customer = cust
-
 
-    items, err := s.getOrderItems(ctx, order.ID)
-    if err != nil {
+    if err := s.loadOrderLines(ctx, &order); err != nil {
         return order, err
     }
-    order.Items = items
-
     return order, nil
 }
 
+// UpdateOrder replaces the order's items and status. Stock is moved through
+// the ledger by the difference between the old and new quantities of each
+// book, so only the delta is reserved or released. Promotions already applied
+// to the order are recomputed against the new items.
 func (s *PostgresOrderStore) UpdateOrder(ctx context.Context, id int, updated models.Order) (models.Order, error) {
     tx, err := s.db.BeginTx(ctx, nil)
     if err != nil {
@@ -126,31 +200,99 @@ func (s *PostgresOrderStore) UpdateOrder(ctx context.Context, id int, updated mo
     }
     defer tx.Rollback()
 
+    var createdAt time.Time
+    var status string
+    var customerID int
+    var shipping, billing []byte
+    err = tx.QueryRowContext(ctx,
+        `SELECT created_at, status, customer_id, shipping_address, billing_address FROM orders WHERE id = $1 FOR UPDATE`,
+        id,
+    ).Scan(&createdAt, &status, &customerID, &shipping, &billing)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return updated, fmt.Errorf("order not found with id: %d", id)
+        }
+        return updated, fmt.Errorf("UpdateOrder: cannot fetch existing order: %w", err)
+    }
+    if status == models.OrderCancelled {
+        return updated, fmt.Errorf("%w: order %d", models.ErrOrderCancelled, id)
+    }
+    if updated.Status == "" {
+        updated.Status = status
+    }
+    if err := checkStatusChange(status, updated.Status); err != nil {
+        return updated, fmt.Errorf("%w: order %d", err, id)
+    }
+    if err := checkNoReturns(ctx, tx, id); err != nil {
+        return updated, err
+    }
+    paid, err := hasPayment(ctx, tx, id)
+    if err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
+    }
+    if paid {
+        return s.fulfilOrder(ctx, tx, id, status, customerID, updated)
+    }
+    // The order keeps its addresses unless others are picked by ID. Orders
+    // from before addresses were copied get them now.
+    if updated.ShippingAddress, err = parseAddressJSON(shipping); err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
+    }
+    if updated.BillingAddress, err = parseAddressJSON(billing); err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
+    }
 
-    var total float64
-    for _, item := range updated.Items {
-        total += item.Book.Price * float64(item.Quantity)
+    previous, err := orderQuantities(ctx, tx, id)
+    if err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
+    }
+
+    items, err := loadItems(ctx, tx, updated.Items)
+    if err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
+    }
+    updated.Items = items
+
+    promotions, err := orderPromotions(ctx, tx, id)
+    if err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
+    }
+    if err := priceOrder(ctx, tx, &updated, promotions); err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
     }
 
 
     up := `
         UPDATE orders
-        SET customer_id = $1, total_price = $2, status = $3
-        WHERE id = $4
+        SET customer_id = $1, subtotal = $2, discount_total = $3, shipping_total = $4,
+            tax_rate = $5, tax_total = $6, total_price = $7, status = $8,
+            shipping_address = $9, billing_address = $10
+        WHERE id = $11
     `
     _, err = tx.ExecContext(ctx, up,
         updated.Customer.ID,
-        total,
+        updated.Subtotal,
+        updated.DiscountTotal,
+        updated.ShippingTotal,
+        updated.TaxRate,
+        updated.TaxTotal,
+        updated.TotalPrice,
         updated.Status,
+        addressJSON(updated.ShippingAddress),
+        addressJSON(updated.BillingAddress),
         id,
     )
     if err != nil {
         return updated, fmt.Errorf("UpdateOrder (orders update): %w", err)
     }
-
-    existing, err := s.GetOrder(ctx, id)
-    if err != nil {
-        return updated, fmt.Errorf("UpdateOrder: cannot fetch existing order: %w", err)
+    if err := saveOrderDiscounts(ctx, tx, id, updated.Discounts); err != nil {
+        return updated, fmt.Errorf("UpdateOrder (order_discounts update): %w", err)
+    }
+    if updated.Status != status {
+        err := enqueueEvent(ctx, tx, events.OrderStatusChangedEvent{OrderID: id, From: status, To: updated.Status})
+        if err != nil {
+            return updated, fmt.Errorf("UpdateOrder: %w", err)
+        }
     }
 
 
@@ -161,15 +303,21 @@ func (s *PostgresOrderStore) UpdateOrder(ctx context.Context, id int, updated mo
     }
 
 
+    current := make(map[int]int)
     for _, item := range updated.Items {
         ins := `
-            INSERT INTO order_items (order_id, book_id, quantity)
-            VALUES ($1, $2, $3)
+            INSERT INTO order_items (order_id, book_id, quantity, unit_price)
+            VALUES ($1, $2, $3, $4)
         `
-        _, err := tx.ExecContext(ctx, ins, id, item.Book.ID, item.Quantity)
+        _, err := tx.ExecContext(ctx, ins, id, item.Book.ID, item.Quantity, item.UnitPrice)
         if err != nil {
             return updated, fmt.Errorf("UpdateOrder (insert items): %w", err)
         }
+        current[item.Book.ID] += item.Quantity
+    }
+
+    if err := moveOrderStock(ctx, tx, id, previous, current); err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
     }
 
     if err := tx.Commit(); err != nil {
@@ -177,24 +325,96 @@ func (s *PostgresOrderStore) UpdateOrder(ctx context.Context, id int, updated mo
     }
 
     updated.ID = id
-    updated.CreatedAt = existing.CreatedAt
-    updated.TotalPrice = total
+    updated.CreatedAt = createdAt
     return updated, nil
 }
 
+// fulfilOrder updates an order that has a payment. The payment was for the
+// order's items and total, so those stay as they are and only the status
+// may move on to delivered.
+func (s *PostgresOrderStore) fulfilOrder(ctx context.Context, tx *sql.Tx, id int, status string, customerID int, updated models.Order) (models.Order, error) {
+    previous, err := orderQuantities(ctx, tx, id)
+    if err != nil {
+        return updated, fmt.Errorf("UpdateOrder: %w", err)
+    }
+    current := make(map[int]int)
+    for _, item := range updated.Items {
+        current[item.Book.ID] += item.Quantity
+    }
+    if !sameQuantities(previous, current) || (updated.Customer.ID != 0 && updated.Customer.ID != customerID) {
+        return updated, fmt.Errorf("%w: order %d", models.ErrOrderHasPayment, id)
+    }
+
+    if updated.Status != status {
+        _, err := tx.ExecContext(ctx, `UPDATE orders SET status = $1 WHERE id = $2`, updated.Status, id)
+        if err != nil {
+            return updated, fmt.Errorf("UpdateOrder (orders update): %w", err)
+        }
+        err = enqueueEvent(ctx, tx, events.OrderStatusChangedEvent{OrderID: id, From: status, To: updated.Status})
+        if err != nil {
+            return updated, fmt.Errorf("UpdateOrder: %w", err)
+        }
+    }
+    if err := tx.Commit(); err != nil {
+        return updated, err
+    }
+    return s.GetOrder(ctx, id)
+}
+
+// DeleteOrder puts the order's items back into stock as a cancellation and
+// removes the order. The ledger entries outlive the order. Orders with a
+// payment are kept, together with the payment and its refunds.
 func (s *PostgresOrderStore) DeleteOrder(ctx context.Context, id int) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if err := checkNoReturns(ctx, tx, id); err != nil {
+        return err
+    }
+    var status string
+    err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return fmt.Errorf("order not found with id: %d", id)
+        }
+        return fmt.Errorf("DeleteOrder error: %w", err)
+    }
+    if status == models.OrderCancelled {
+        return fmt.Errorf("%w: order %d", models.ErrOrderCancelled, id)
+    }
+    paid, err := hasPayment(ctx, tx, id)
+    if err != nil {
+        return fmt.Errorf("DeleteOrder error: %w", err)
+    }
+    if paid {
+        return fmt.Errorf("%w: order %d", models.ErrOrderHasPayment, id)
+    }
+    previous, err := orderQuantities(ctx, tx, id)
+    if err != nil {
+        return fmt.Errorf("DeleteOrder error: %w", err)
+    }
+    if err := moveOrderStock(ctx, tx, id, previous, nil); err != nil {
+        return fmt.Errorf("DeleteOrder error: %w", err)
+    }
+
     query := `DELETE FROM orders WHERE id = $1`
-    _, err := s.db.ExecContext(ctx, query, id)
+    _, err = tx.ExecContext(ctx, query, id)
     if err != nil {
         return fmt.Errorf("DeleteOrder error: %w", err)
     }
-    return nil
+    // Deleting an order cancels it as far as anyone listening is concerned.
+    err = enqueueEvent(ctx, tx, events.OrderStatusChangedEvent{OrderID: id, From: status, To: models.OrderCancelled})
+    if err != nil {
+        return fmt.Errorf("DeleteOrder: %w", err)
+    }
+    return tx.Commit()
 }
 
 func (s *PostgresOrderStore) ListOrders(ctx context.Context) ([]models.Order, error) {
-    query := `
-        SELECT o.id, o.customer_id, o.total_price, o.created_at, o.status,
-               c.id, c.name, c.email, c.street, c.city, c.state, c.postal_code, c.country, c.created_at
+    query := `SELECT ` + orderColumns + `
         FROM orders o
         JOIN customers c ON o.customer_id = c.id
         ORDER BY o.id
@@ -207,43 +427,117 @@ func (s *PostgresOrderStore) ListOrders(ctx context.Context) ([]models.Order, er
 
     var results []models.Order
     for rows.Next() {
-        var order models.Order
-        var cust models.Customer
-        if err := rows.Scan(
-            &order.ID,
-            &order.Customer.ID,
-            &order.TotalPrice,
-            &order.CreatedAt,
-            &order.Status,
-            &cust.ID,
-            &cust.Name,
-            &cust.Email,
-            &cust.Address.Street,
-            &cust.Address.City,
-            &cust.Address.State,
-            &cust.Address.PostalCode,
-            &cust.Address.Country,
-            &cust.CreatedAt,
-        ); err != nil {
+        order, err := scanOrder(rows)
+        if err != nil {
             return nil, err
         }
-        order.Customer = cust
+        results = append(results, order)
+    }
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+    rows.Close()
 
+    for i := range results {
+        if err := s.loadOrderLines(ctx, &results[i]); err != nil {
+            return nil, err
+        }
+    }
+    return results, nil
+}
 
-        items, err := s.getOrderItems(ctx, order.ID)
+func (s *PostgresOrderStore) ListCustomerOrders(ctx context.Context, customerID int) ([]models.Order, error) {
+    query := `SELECT ` + orderColumns + `
+        FROM orders o
+        JOIN customers c ON o.customer_id = c.id
+        WHERE o.customer_id = $1
+        ORDER BY o.id DESC
+    `
+    rows, err := s.db.QueryContext(ctx, query, customerID)
+    if err != nil {
+        return nil, fmt.Errorf("ListCustomerOrders error: %w", err)
+    }
+    defer rows.Close()
+
+    var results []models.Order
+    for rows.Next() {
+        order, err := scanOrder(rows)
         if err != nil {
             return nil, err
         }
-        order.Items = items
-
         results = append(results, order)
     }
     if err = rows.Err(); err != nil {
         return nil, err
     }
+    rows.Close()
+
+    for i := range results {
+        if err := s.loadOrderLines(ctx, &results[i]); err != nil {
+            return nil, err
+        }
+    }
     return results, nil
 }
 
+// CancelOrder releases the order's stock and coupon redemptions and marks
+// it cancelled. Unlike DeleteOrder the order is kept. An order with a
+// payment in progress cannot be cancelled, since the payment may still be
+// captured.
+func (s *PostgresOrderStore) CancelOrder(ctx context.Context, id int) (models.Order, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return models.Order{}, err
+    }
+    defer tx.Rollback()
+
+    var status string
+    err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return models.Order{}, fmt.Errorf("order not found with id: %d", id)
+        }
+        return models.Order{}, fmt.Errorf("CancelOrder error: %w", err)
+    }
+    if status != models.OrderPending {
+        return models.Order{}, fmt.Errorf("%w: order %d is %s", models.ErrOrderNotCancellable, id, status)
+    }
+    var inFlight int
+    err = tx.QueryRowContext(ctx,
+        `SELECT COUNT(*) FROM payments WHERE order_id = $1 AND status IN ($2, $3)`,
+        id, models.PaymentPending, models.PaymentAuthorized,
+    ).Scan(&inFlight)
+    if err != nil {
+        return models.Order{}, fmt.Errorf("CancelOrder error: %w", err)
+    }
+    if inFlight > 0 {
+        return models.Order{}, fmt.Errorf("%w: order %d has a payment in progress", models.ErrOrderNotCancellable, id)
+    }
+
+    previous, err := orderQuantities(ctx, tx, id)
+    if err != nil {
+        return models.Order{}, fmt.Errorf("CancelOrder error: %w", err)
+    }
+    if err := moveOrderStock(ctx, tx, id, previous, nil); err != nil {
+        return models.Order{}, fmt.Errorf("CancelOrder error: %w", err)
+    }
+    if _, err := tx.ExecContext(ctx, `DELETE FROM promotion_redemptions WHERE order_id = $1`, id); err != nil {
+        return models.Order{}, fmt.Errorf("CancelOrder error: %w", err)
+    }
+    _, err = tx.ExecContext(ctx, `UPDATE orders SET status = $1 WHERE id = $2`, models.OrderCancelled, id)
+    if err != nil {
+        return models.Order{}, fmt.Errorf("CancelOrder error: %w", err)
+    }
+    err = enqueueEvent(ctx, tx, events.OrderStatusChangedEvent{OrderID: id, From: status, To: models.OrderCancelled})
+    if err != nil {
+        return models.Order{}, fmt.Errorf("CancelOrder: %w", err)
+    }
+    if err := tx.Commit(); err != nil {
+        return models.Order{}, err
+    }
+    return s.GetOrder(ctx, id)
+}
+
 func (s *PostgresOrderStore) GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]models.Order, error) {
     query := `
         SELECT id
@@ -273,15 +567,34 @@ func (s *PostgresOrderStore) GetOrdersInTimeRange(ctx context.Context, start, en
 }
 
 
+// loadOrderLines fills in the items and discount lines of an order.
+func (s *PostgresOrderStore) loadOrderLines(ctx context.Context, order *models.Order) error {
+    items, err := s.getOrderItems(ctx, order.ID)
+    if err != nil {
+        return err
+    }
+    order.Items = items
+
+    discounts, err := s.getOrderDiscounts(ctx, order.ID)
+    if err != nil {
+        return err
+    }
+    order.Discounts = discounts
+    for _, d := range discounts {
+        if d.Code != "" {
+            order.CouponCode = d.Code
+        }
+    }
+    return nil
+}
+
 func (s *PostgresOrderStore) getOrderItems(ctx context.Context, orderID int) ([]models.OrderItem, error) {
-    query := `
-        SELECT oi.book_id, oi.quantity,
-               b.title, b.price, b.stock,
-               a.id, a.first_name, a.last_name, a.bio
+    query := `SELECT oi.quantity, oi.unit_price, ` + bookColumns + `
         FROM order_items oi
         JOIN books b ON oi.book_id = b.id
         JOIN authors a ON b.author_id = a.id
         WHERE oi.order_id = $1
+        ORDER BY oi.id
     `
     rows, err := s.db.QueryContext(ctx, query, orderID)
     if err != nil {
@@ -291,28 +604,304 @@ func (s *PostgresOrderStore) getOrderItems(ctx context.Context, orderID int) ([]
 
     var items []models.OrderItem
     for rows.Next() {
-        var (
-            item   models.OrderItem
-            book   models.Book
-            author models.Author
-        )
-        err := rows.Scan(
-            &book.ID,
-            &item.Quantity,
-            &book.Title,
-            &book.Price,
-            &book.Stock,
-            &author.ID,
-            &author.FirstName,
-            &author.LastName,
-            &author.Bio,
-        )
+        var item models.OrderItem
+        book, err := scanBook(prefixScanner{rows, []interface{}{&item.Quantity, &item.UnitPrice}})
         if err != nil {
             return nil, err
         }
-        book.Author = author
         item.Book = book
         items = append(items, item)
     }
     return items, rows.Err()
 }
+
+func (s *PostgresOrderStore) getOrderDiscounts(ctx context.Context, orderID int) ([]models.OrderDiscount, error) {
+    query := `
+        SELECT COALESCE(promotion_id, 0), code, description, amount
+        FROM order_discounts
+        WHERE order_id = $1
+        ORDER BY id
+    `
+    rows, err := s.db.QueryContext(ctx, query, orderID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var discounts []models.OrderDiscount
+    for rows.Next() {
+        var d models.OrderDiscount
+        if err := rows.Scan(&d.PromotionID, &d.Code, &d.Description, &d.Amount); err != nil {
+            return nil, err
+        }
+        discounts = append(discounts, d)
+    }
+    return discounts, rows.Err()
+}
+
+// loadItems replaces the books sent by the client with the catalog rows, so
+// that prices, genres and authors used for pricing come from the database.
+func loadItems(ctx context.Context, tx *sql.Tx, items []models.OrderItem) ([]models.OrderItem, error) {
+    loaded := make([]models.OrderItem, 0, len(items))
+    for _, item := range items {
+        if item.Quantity <= 0 {
+            return nil, fmt.Errorf("invalid quantity %d for book %d", item.Quantity, item.Book.ID)
+        }
+        book, err := scanBook(tx.QueryRowContext(ctx, `SELECT `+bookColumns+`
+            FROM books b
+            JOIN authors a ON b.author_id = a.id
+            WHERE b.id = $1
+        `, item.Book.ID))
+        if err != nil {
+            if err == sql.ErrNoRows {
+                return nil, fmt.Errorf("book not found with id: %d", item.Book.ID)
+            }
+            return nil, err
+        }
+        loaded = append(loaded, models.OrderItem{Book: book, Quantity: item.Quantity, UnitPrice: book.Price})
+    }
+    return loaded, nil
+}
+
+// priceOrder fills in the order's discounts, shipping, tax and total from
+// its items, the given promotions and the customer's address. Missing tax or
+// shipping rates price as zero.
+func priceOrder(ctx context.Context, tx *sql.Tx, order *models.Order, promotions []models.Promotion) error {
+    order.Subtotal = pricing.Subtotal(order.Items)
+    order.Discounts = pricing.ApplyPromotions(order.Items, promotions)
+    order.DiscountTotal = pricing.TotalDiscount(order.Discounts)
+    merchandise := order.Subtotal - order.DiscountTotal
+
+    if err := resolveOrderAddresses(ctx, tx, order); err != nil {
+        return err
+    }
+    addr := *order.ShippingAddress
+
+    rates, err := listShippingRates(ctx, tx)
+    if err != nil {
+        return err
+    }
+    order.ShippingTotal, _ = rates.Quote(addr, order.Items, merchandise)
+
+    taxes, err := listTaxRules(ctx, tx)
+    if err != nil {
+        return err
+    }
+    order.TaxRate, order.TaxTotal = 0, 0
+    if rule, ok := taxes.Lookup(addr); ok {
+        order.TaxRate = rule.Rate
+        order.TaxTotal = pricing.Tax(rule, merchandise, order.ShippingTotal)
+    }
+
+    order.TotalPrice = pricing.Total(order.Subtotal, order.DiscountTotal, order.ShippingTotal, order.TaxTotal)
+    return nil
+}
+
+// resolveOrderAddresses copies the addresses named by ShippingAddressID and
+// BillingAddressID onto the order. Where no ID is given and the order has
+// no address yet, the customer's default is used; failing that, shipping
+// goes to the customer's contact address and billing to the shipping one.
+func resolveOrderAddresses(ctx context.Context, tx *sql.Tx, order *models.Order) error {
+    if order.ShippingAddressID != 0 || order.ShippingAddress == nil {
+        addr, err := savedAddress(ctx, tx, order.Customer.ID, order.ShippingAddressID, "default_shipping")
+        if err != nil {
+            return err
+        }
+        if addr == nil {
+            addr, err = contactAddress(ctx, tx, order.Customer.ID)
+            if err != nil {
+                return err
+            }
+        }
+        order.ShippingAddress = addr
+    }
+    if order.BillingAddressID != 0 || order.BillingAddress == nil {
+        addr, err := savedAddress(ctx, tx, order.Customer.ID, order.BillingAddressID, "default_billing")
+        if err != nil {
+            return err
+        }
+        if addr == nil {
+            copied := *order.ShippingAddress
+            addr = &copied
+        }
+        order.BillingAddress = addr
+    }
+    return nil
+}
+
+// savedAddress returns the customer's address with the given ID, or with
+// no ID their default by defaultColumn. It is nil if there is no default.
+func savedAddress(ctx context.Context, tx *sql.Tx, customerID, id int, defaultColumn string) (*models.Address, error) {
+    query := `SELECT street, city, state, postal_code, country FROM addresses WHERE customer_id = $1 AND ` + defaultColumn
+    args := []interface{}{customerID}
+    if id != 0 {
+        query = `SELECT street, city, state, postal_code, country FROM addresses WHERE customer_id = $1 AND id = $2`
+        args = append(args, id)
+    }
+    var addr models.Address
+    err := tx.QueryRowContext(ctx, query, args...).
+        Scan(&addr.Street, &addr.City, &addr.State, &addr.PostalCode, &addr.Country)
+    if err == sql.ErrNoRows {
+        if id != 0 {
+            return nil, fmt.Errorf("%w: address %d, customer %d", models.ErrUnknownAddress, id, customerID)
+        }
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &addr, nil
+}
+
+func contactAddress(ctx context.Context, tx *sql.Tx, customerID int) (*models.Address, error) {
+    var addr models.Address
+    err := tx.QueryRowContext(ctx,
+        `SELECT street, city, state, postal_code, country FROM customers WHERE id = $1`,
+        customerID,
+    ).Scan(&addr.Street, &addr.City, &addr.State, &addr.PostalCode, &addr.Country)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, fmt.Errorf("customer not found with id: %d", customerID)
+        }
+        return nil, err
+    }
+    return &addr, nil
+}
+
+// addressJSON encodes an address snapshot for a JSONB column.
+func addressJSON(addr *models.Address) interface{} {
+    if addr == nil {
+        return nil
+    }
+    b, _ := json.Marshal(addr)
+    return string(b)
+}
+
+func parseAddressJSON(b []byte) (*models.Address, error) {
+    if b == nil {
+        return nil, nil
+    }
+    var addr models.Address
+    if err := json.Unmarshal(b, &addr); err != nil {
+        return nil, err
+    }
+    return &addr, nil
+}
+
+func hasCoupon(discounts []models.OrderDiscount) bool {
+    for _, d := range discounts {
+        if d.Code != "" {
+            return true
+        }
+    }
+    return false
+}
+
+// checkNoReturns refuses changes to orders with return requests, whose
+// quantities and prices the returns and refunds were based on.
+func checkNoReturns(ctx context.Context, tx *sql.Tx, orderID int) error {
+    var n int
+    err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM return_requests WHERE order_id = $1`, orderID).Scan(&n)
+    if err != nil {
+        return err
+    }
+    if n > 0 {
+        return fmt.Errorf("%w: order %d", models.ErrOrderHasReturns, orderID)
+    }
+    return nil
+}
+
+// checkStatusChange allows an order update to keep the status or to mark a
+// paid order delivered. Orders become paid or cancelled through their
+// payment or CancelOrder, which also settle what goes with it.
+func checkStatusChange(from, to string) error {
+    if from == to || (from == models.OrderPaid && to == models.OrderDelivered) {
+        return nil
+    }
+    return fmt.Errorf("%w: %s to %s", models.ErrOrderStatusChange, from, to)
+}
+
+// hasPayment reports whether the order has a payment that did not fail.
+// Callers hold the order's row lock, which BeginPayment takes too.
+func hasPayment(ctx context.Context, tx *sql.Tx, orderID int) (bool, error) {
+    var exists bool
+    err := tx.QueryRowContext(ctx,
+        `SELECT EXISTS (SELECT 1 FROM payments WHERE order_id = $1 AND status <> $2)`,
+        orderID, models.PaymentFailed,
+    ).Scan(&exists)
+    return exists, err
+}
+
+// sameQuantities reports whether two orders hold the same books.
+func sameQuantities(a, b map[int]int) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for bookID, n := range a {
+        if b[bookID] != n {
+            return false
+        }
+    }
+    return true
+}
+
+// orderQuantities returns the quantity ordered per book for an order.
+func orderQuantities(ctx context.Context, tx *sql.Tx, orderID int) (map[int]int, error) {
+    rows, err := tx.QueryContext(ctx,
+        `SELECT book_id, SUM(quantity) FROM order_items WHERE order_id = $1 GROUP BY book_id`,
+        orderID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    quantities := make(map[int]int)
+    for rows.Next() {
+        var bookID, qty int
+        if err := rows.Scan(&bookID, &qty); err != nil {
+            return nil, err
+        }
+        quantities[bookID] = qty
+    }
+    return quantities, rows.Err()
+}
+
+// moveOrderStock records the ledger movements needed to go from the previous
+// to the current per-book quantities of an order.
+func moveOrderStock(ctx context.Context, tx *sql.Tx, orderID int, previous, current map[int]int) error {
+    deltas := make(map[int]int)
+    for bookID, qty := range previous {
+        deltas[bookID] += qty
+    }
+    for bookID, qty := range current {
+        deltas[bookID] -= qty
+    }
+
+    bookIDs := make([]int, 0, len(deltas))
+    for bookID := range deltas {
+        bookIDs = append(bookIDs, bookID)
+    }
+    sort.Ints(bookIDs)
+
+    for _, bookID := range bookIDs {
+        change := deltas[bookID]
+        if change == 0 {
+            continue
+        }
+        reason := models.MovementOrder
+        if change > 0 {
+            reason = models.MovementCancellation
+        }
+        _, err := recordMovement(ctx, tx, models.StockMovement{
+            BookID:  bookID,
+            Change:  change,
+            Reason:  reason,
+            OrderID: &orderID,
+        })
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
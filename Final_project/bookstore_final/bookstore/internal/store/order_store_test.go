@@ -0,0 +1,57 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"bookstore/internal/models"
+)
+
+func TestCheckStatusChange(t *testing.T) {
+	tests := []struct {
+		from, to string
+		ok       bool
+	}{
+		{models.OrderPending, models.OrderPending, true},
+		{models.OrderPaid, models.OrderPaid, true},
+		{models.OrderPaid, models.OrderDelivered, true},
+		{models.OrderPending, models.OrderPaid, false},
+		{models.OrderPending, models.OrderCancelled, false},
+		{models.OrderPending, models.OrderDelivered, false},
+		{models.OrderPaid, models.OrderPending, false},
+		{models.OrderPaid, models.OrderCancelled, false},
+		{models.OrderDelivered, models.OrderPaid, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			err := checkStatusChange(tt.from, tt.to)
+			if tt.ok && err != nil {
+				t.Errorf("checkStatusChange = %v, want nil", err)
+			}
+			if !tt.ok && !errors.Is(err, models.ErrOrderStatusChange) {
+				t.Errorf("checkStatusChange = %v, want %v", err, models.ErrOrderStatusChange)
+			}
+		})
+	}
+}
+
+func TestSameQuantities(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[int]int
+		want bool
+	}{
+		{"same", map[int]int{1: 2, 3: 1}, map[int]int{3: 1, 1: 2}, true},
+		{"both empty", map[int]int{}, nil, true},
+		{"quantity changed", map[int]int{1: 2}, map[int]int{1: 3}, false},
+		{"book added", map[int]int{1: 2}, map[int]int{1: 2, 4: 1}, false},
+		{"book swapped", map[int]int{1: 2}, map[int]int{4: 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameQuantities(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameQuantities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
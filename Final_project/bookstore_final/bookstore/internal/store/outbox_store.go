@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bookstore/internal/events"
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresOutboxStore struct {
+	db *sql.DB
+}
+
+func NewPostgresOutboxStore(db *sql.DB) (interfaces.OutboxStore, error) {
+	return &PostgresOutboxStore{db: db}, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// enqueueEvent writes a typed event to the outbox. Called with a *sql.Tx,
+// the event is published if and only if the transaction commits.
+func enqueueEvent(ctx context.Context, tx execer, event interface{}) error {
+	eventType, payload, err := events.Encode(event)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO outbox_events (type, payload, created_at, next_attempt_at)
+        VALUES ($1, $2, NOW(), NOW())
+    `, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("outbox insert failed: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresOutboxStore) Enqueue(ctx context.Context, event interface{}) error {
+	if err := enqueueEvent(ctx, s.db, event); err != nil {
+		return fmt.Errorf("Enqueue error: %w", err)
+	}
+	return nil
+}
+
+// EventsAfter returns dispatched events with an ID above afterID, oldest
+// first, so that stream clients can catch up on what they missed.
+func (s *PostgresOutboxStore) EventsAfter(ctx context.Context, afterID int64, limit int) ([]models.Event, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, type, payload, created_at, attempts
+        FROM outbox_events
+        WHERE id > $1 AND dispatched_at IS NOT NULL
+        ORDER BY id
+        LIMIT $2
+    `, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("EventsAfter error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]models.Event, error) {
+	var list []models.Event
+	for rows.Next() {
+		var e models.Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.CreatedAt, &e.Attempts); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+func (s *PostgresOutboxStore) PendingEvents(ctx context.Context, now time.Time, limit int) ([]models.Event, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, type, payload, created_at, attempts
+        FROM outbox_events
+        WHERE dispatched_at IS NULL AND next_attempt_at <= $1
+        ORDER BY id
+        LIMIT $2
+    `, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("PendingEvents error: %w", err)
+	}
+	defer rows.Close()
+
+	pending, err := scanEvents(rows)
+	if err != nil {
+		return nil, fmt.Errorf("PendingEvents scan: %w", err)
+	}
+	return pending, nil
+}
+
+func (s *PostgresOutboxStore) MarkEventDispatched(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE outbox_events
+        SET dispatched_at = NOW(), attempts = attempts + 1, last_error = ''
+        WHERE id = $1
+    `, id)
+	if err != nil {
+		return fmt.Errorf("MarkEventDispatched error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresOutboxStore) MarkEventFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE outbox_events
+        SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+        WHERE id = $3
+    `, reason, retryAt, id)
+	if err != nil {
+		return fmt.Errorf("MarkEventFailed error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresOutboxStore) DeleteDispatchedEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM outbox_events WHERE dispatched_at IS NOT NULL AND dispatched_at < $1`, before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteDispatchedEvents error: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bookstore/internal/events"
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresPaymentStore struct {
+	db *sql.DB
+}
+
+func NewPostgresPaymentStore(db *sql.DB) (interfaces.PaymentStore, error) {
+	return &PostgresPaymentStore{db: db}, nil
+}
+
+const paymentColumns = `
+        id, order_id, provider, status, amount, reference, failure_reason, created_at, updated_at
+`
+
+func scanPayment(row rowScanner) (models.Payment, error) {
+	var p models.Payment
+	err := row.Scan(
+		&p.ID,
+		&p.OrderID,
+		&p.Provider,
+		&p.Status,
+		&p.Amount,
+		&p.Reference,
+		&p.FailureReason,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	return p, err
+}
+
+func (s *PostgresPaymentStore) BeginPayment(ctx context.Context, orderID int, provider string) (models.Payment, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Payment{}, false, fmt.Errorf("BeginPayment error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var total float64
+	err = tx.QueryRowContext(ctx,
+		`SELECT status, total_price FROM orders WHERE id = $1 FOR UPDATE`, orderID,
+	).Scan(&status, &total)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Payment{}, false, fmt.Errorf("order not found with id: %d", orderID)
+		}
+		return models.Payment{}, false, fmt.Errorf("BeginPayment error: %w", err)
+	}
+
+	// The order row lock serializes concurrent attempts, so an active
+	// payment seen here cannot be superseded before this transaction ends.
+	active, err := scanPayment(tx.QueryRowContext(ctx, `SELECT `+paymentColumns+`
+        FROM payments
+        WHERE order_id = $1 AND status IN ($2, $3, $4)
+    `, orderID, models.PaymentPending, models.PaymentAuthorized, models.PaymentCaptured))
+	if err == nil {
+		return active, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return models.Payment{}, false, fmt.Errorf("BeginPayment error: %w", err)
+	}
+	if status != models.OrderPending {
+		return models.Payment{}, false, fmt.Errorf("%w: order %d is %s", models.ErrOrderNotPayable, orderID, status)
+	}
+
+	now := time.Now()
+	p := models.Payment{
+		OrderID:   orderID,
+		Provider:  provider,
+		Status:    models.PaymentPending,
+		Amount:    total,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO payments (order_id, provider, status, amount, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $5)
+        RETURNING id
+    `, p.OrderID, p.Provider, p.Status, p.Amount, now).Scan(&p.ID)
+	if err != nil {
+		return p, false, fmt.Errorf("BeginPayment error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return p, false, fmt.Errorf("BeginPayment commit: %w", err)
+	}
+	return p, true, nil
+}
+
+func (s *PostgresPaymentStore) UpdatePayment(ctx context.Context, id int, status, reference, reason string) (models.Payment, error) {
+	query := `
+        UPDATE payments
+        SET status = $1, reference = COALESCE(NULLIF($2, ''), reference), failure_reason = $3, updated_at = NOW()
+        WHERE id = $4
+        RETURNING ` + paymentColumns
+	p, err := scanPayment(s.db.QueryRowContext(ctx, query, status, reference, reason, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return p, fmt.Errorf("payment not found with id: %d", id)
+		}
+		return p, fmt.Errorf("UpdatePayment error: %w", err)
+	}
+	return p, nil
+}
+
+func (s *PostgresPaymentStore) CompletePayment(ctx context.Context, id int, reference string) (models.Payment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Payment{}, fmt.Errorf("CompletePayment error: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := scanPayment(tx.QueryRowContext(ctx, `
+        UPDATE payments
+        SET status = $1, reference = COALESCE(NULLIF($2, ''), reference), failure_reason = '', updated_at = NOW()
+        WHERE id = $3 AND status IN ($4, $5)
+        RETURNING `+paymentColumns,
+		models.PaymentCaptured, reference, id, models.PaymentPending, models.PaymentAuthorized,
+	))
+	if err == sql.ErrNoRows {
+		// Already captured is a repeat and succeeds; a failed or refunded
+		// payment is not brought back.
+		p, err = scanPayment(tx.QueryRowContext(ctx,
+			`SELECT `+paymentColumns+` FROM payments WHERE id = $1`, id,
+		))
+		if err == sql.ErrNoRows {
+			return p, fmt.Errorf("payment not found with id: %d", id)
+		}
+		if err == nil && p.Status != models.PaymentCaptured {
+			return p, fmt.Errorf("%w: payment %d is %s", models.ErrPaymentSettled, id, p.Status)
+		}
+		if err == nil {
+			return p, nil
+		}
+	}
+	if err != nil {
+		return p, fmt.Errorf("CompletePayment error: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE orders SET status = $1 WHERE id = $2 AND status = $3`,
+		models.OrderPaid, p.OrderID, models.OrderPending,
+	)
+	if err != nil {
+		return p, fmt.Errorf("CompletePayment (orders update): %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		err := enqueueEvent(ctx, tx, events.OrderStatusChangedEvent{
+			OrderID: p.OrderID,
+			From:    models.OrderPending,
+			To:      models.OrderPaid,
+		})
+		if err != nil {
+			return p, fmt.Errorf("CompletePayment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return p, fmt.Errorf("CompletePayment commit: %w", err)
+	}
+	return p, nil
+}
+
+func (s *PostgresPaymentStore) AbandonPayment(ctx context.Context, id int, before time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+        UPDATE payments
+        SET status = $1, failure_reason = $2, updated_at = NOW()
+        WHERE id = $3 AND status = $4 AND updated_at <= $5
+    `, models.PaymentFailed, "abandoned: no answer recorded from the provider", id, models.PaymentPending, before)
+	if err != nil {
+		return false, fmt.Errorf("AbandonPayment error: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("AbandonPayment error: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *PostgresPaymentStore) GetPayment(ctx context.Context, id int) (models.Payment, error) {
+	p, err := scanPayment(s.db.QueryRowContext(ctx,
+		`SELECT `+paymentColumns+` FROM payments WHERE id = $1`, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return p, fmt.Errorf("payment not found with id: %d", id)
+		}
+		return p, err
+	}
+	return p, nil
+}
+
+func (s *PostgresPaymentStore) GetPaymentByReference(ctx context.Context, provider, reference string) (models.Payment, error) {
+	p, err := scanPayment(s.db.QueryRowContext(ctx,
+		`SELECT `+paymentColumns+` FROM payments WHERE provider = $1 AND reference = $2`, provider, reference,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return p, fmt.Errorf("payment not found with reference: %s", reference)
+		}
+		return p, err
+	}
+	return p, nil
+}
+
+func (s *PostgresPaymentStore) ListPayments(ctx context.Context, orderID int) ([]models.Payment, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+paymentColumns+` FROM payments WHERE order_id = $1 ORDER BY id`, orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListPayments error: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ListPayments scan: %w", err)
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
@@ -0,0 +1,290 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+	"bookstore/internal/pricing"
+)
+
+type PostgresPromotionStore struct {
+	db *sql.DB
+}
+
+func NewPostgresPromotionStore(db *sql.DB) (interfaces.PromotionStore, error) {
+	return &PostgresPromotionStore{db: db}, nil
+}
+
+const promotionColumns = `
+        p.id, p.name, COALESCE(p.code, ''), p.kind, p.value, p.min_order, p.genre,
+        COALESCE(p.author_id, 0), p.starts_at, p.expires_at, p.max_uses, p.max_uses_per_customer,
+        (SELECT COUNT(*) FROM promotion_redemptions r WHERE r.promotion_id = p.id),
+        p.active, p.created_at
+`
+
+func scanPromotion(row rowScanner) (models.Promotion, error) {
+	var p models.Promotion
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.Code,
+		&p.Kind,
+		&p.Value,
+		&p.MinOrder,
+		&p.Genre,
+		&p.AuthorID,
+		&p.StartsAt,
+		&p.ExpiresAt,
+		&p.MaxUses,
+		&p.MaxUsesPerCustomer,
+		&p.TimesUsed,
+		&p.Active,
+		&p.CreatedAt,
+	)
+	return p, err
+}
+
+func (s *PostgresPromotionStore) CreatePromotion(ctx context.Context, p models.Promotion) (models.Promotion, error) {
+	query := `
+        INSERT INTO promotions (name, code, kind, value, min_order, genre, author_id,
+                                starts_at, expires_at, max_uses, max_uses_per_customer, active, created_at)
+        VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, NULLIF($7, 0), $8, $9, $10, $11, $12, $13)
+        RETURNING id
+    `
+	now := time.Now()
+	err := s.db.QueryRowContext(ctx, query,
+		p.Name,
+		p.Code,
+		p.Kind,
+		p.Value,
+		p.MinOrder,
+		p.Genre,
+		p.AuthorID,
+		p.StartsAt,
+		p.ExpiresAt,
+		p.MaxUses,
+		p.MaxUsesPerCustomer,
+		p.Active,
+		now,
+	).Scan(&p.ID)
+	if err != nil {
+		return p, fmt.Errorf("CreatePromotion error: %w", err)
+	}
+	p.CreatedAt = now
+	return p, nil
+}
+
+func (s *PostgresPromotionStore) GetPromotion(ctx context.Context, id int) (models.Promotion, error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions p WHERE p.id = $1`
+	p, err := scanPromotion(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return p, fmt.Errorf("promotion not found with id: %d", id)
+		}
+		return p, err
+	}
+	return p, nil
+}
+
+func (s *PostgresPromotionStore) UpdatePromotion(ctx context.Context, id int, p models.Promotion) (models.Promotion, error) {
+	query := `
+        UPDATE promotions
+        SET name = $1, code = NULLIF($2, ''), kind = $3, value = $4, min_order = $5,
+            genre = $6, author_id = NULLIF($7, 0), starts_at = $8, expires_at = $9,
+            max_uses = $10, max_uses_per_customer = $11, active = $12
+        WHERE id = $13
+    `
+	res, err := s.db.ExecContext(ctx, query,
+		p.Name,
+		p.Code,
+		p.Kind,
+		p.Value,
+		p.MinOrder,
+		p.Genre,
+		p.AuthorID,
+		p.StartsAt,
+		p.ExpiresAt,
+		p.MaxUses,
+		p.MaxUsesPerCustomer,
+		p.Active,
+		id,
+	)
+	if err != nil {
+		return p, fmt.Errorf("UpdatePromotion error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return p, fmt.Errorf("promotion not found with id: %d", id)
+	}
+	return s.GetPromotion(ctx, id)
+}
+
+func (s *PostgresPromotionStore) DeletePromotion(ctx context.Context, id int) error {
+	query := `DELETE FROM promotions WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("DeletePromotion error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresPromotionStore) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions p ORDER BY p.id`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ListPromotions error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPromotions(rows)
+}
+
+func scanPromotions(rows *sql.Rows) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p)
+	}
+	return promotions, rows.Err()
+}
+
+// applicablePromotions returns the automatic promotions the customer can
+// still use, followed by the coupon matching code, if one was given. An
+// unusable coupon is an error wrapping models.ErrInvalidCoupon; automatic
+// promotions that are not usable are simply left out.
+func applicablePromotions(ctx context.Context, tx *sql.Tx, customerID int, code string, now time.Time) ([]models.Promotion, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+promotionColumns+` FROM promotions p WHERE p.code IS NULL AND p.active ORDER BY p.id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	automatic, err := scanPromotions(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var promotions []models.Promotion
+	for _, p := range automatic {
+		if pricing.Eligible(p, now) != nil {
+			continue
+		}
+		if err := checkUsage(ctx, tx, p, customerID); err != nil {
+			continue
+		}
+		promotions = append(promotions, p)
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return promotions, nil
+	}
+
+	coupon, err := scanPromotion(tx.QueryRowContext(ctx,
+		`SELECT `+promotionColumns+` FROM promotions p WHERE LOWER(p.code) = LOWER($1)`, code,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: unknown code %q", models.ErrInvalidCoupon, code)
+		}
+		return nil, err
+	}
+	if err := pricing.Eligible(coupon, now); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidCoupon, err)
+	}
+	if err := checkUsage(ctx, tx, coupon, customerID); err != nil {
+		return nil, err
+	}
+	return append(promotions, coupon), nil
+}
+
+// checkUsage enforces the overall and per-customer usage limits.
+func checkUsage(ctx context.Context, tx *sql.Tx, p models.Promotion, customerID int) error {
+	var used, usedByCustomer int
+	err := tx.QueryRowContext(ctx, `
+        SELECT COUNT(*), COUNT(*) FILTER (WHERE customer_id = $2)
+        FROM promotion_redemptions
+        WHERE promotion_id = $1
+    `, p.ID, customerID).Scan(&used, &usedByCustomer)
+	if err != nil {
+		return err
+	}
+	if p.MaxUses > 0 && used >= p.MaxUses {
+		return fmt.Errorf("%w: promotion %q has been used up", models.ErrInvalidCoupon, p.Name)
+	}
+	if p.MaxUsesPerCustomer > 0 && usedByCustomer >= p.MaxUsesPerCustomer {
+		return fmt.Errorf("%w: promotion %q already used by this customer", models.ErrInvalidCoupon, p.Name)
+	}
+	return nil
+}
+
+// redeemPromotions records one redemption per applied promotion. Limited
+// promotions are locked and re-checked first so that concurrent orders
+// cannot exceed their limits.
+func redeemPromotions(ctx context.Context, tx *sql.Tx, orderID, customerID int, promotions []models.Promotion, discounts []models.OrderDiscount) error {
+	byID := make(map[int]models.Promotion)
+	for _, p := range promotions {
+		byID[p.ID] = p
+	}
+
+	for _, d := range discounts {
+		p := byID[d.PromotionID]
+		if p.MaxUses > 0 || p.MaxUsesPerCustomer > 0 {
+			if _, err := tx.ExecContext(ctx, `SELECT id FROM promotions WHERE id = $1 FOR UPDATE`, p.ID); err != nil {
+				return err
+			}
+			if err := checkUsage(ctx, tx, p, customerID); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO promotion_redemptions (promotion_id, order_id, customer_id, created_at)
+            VALUES ($1, $2, $3, NOW())
+        `, d.PromotionID, orderID, customerID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveOrderDiscounts(ctx context.Context, tx *sql.Tx, orderID int, discounts []models.OrderDiscount) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM order_discounts WHERE order_id = $1`, orderID); err != nil {
+		return err
+	}
+	for _, d := range discounts {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO order_discounts (order_id, promotion_id, code, description, amount)
+            VALUES ($1, $2, $3, $4, $5)
+        `, orderID, d.PromotionID, d.Code, d.Description, d.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderPromotions reloads the promotions already applied to an order so its
+// discounts can be recomputed when its items change.
+func orderPromotions(ctx context.Context, tx *sql.Tx, orderID int) ([]models.Promotion, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT `+promotionColumns+`
+        FROM order_discounts d
+        JOIN promotions p ON d.promotion_id = p.id
+        WHERE d.order_id = $1
+        ORDER BY d.id
+    `, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPromotions(rows)
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+	"bookstore/internal/pricing"
+)
+
+// PostgresRateStore holds the tax rule and shipping rate tables used to
+// price orders.
+type PostgresRateStore struct {
+	db *sql.DB
+}
+
+func NewPostgresRateStore(db *sql.DB) (interfaces.RateStore, error) {
+	return &PostgresRateStore{db: db}, nil
+}
+
+func (s *PostgresRateStore) CreateTaxRule(ctx context.Context, rule models.TaxRule) (models.TaxRule, error) {
+	query := `
+        INSERT INTO tax_rules (country, state, rate, tax_shipping)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `
+	err := s.db.QueryRowContext(ctx, query,
+		rule.Country,
+		rule.State,
+		rule.Rate,
+		rule.TaxShipping,
+	).Scan(&rule.ID)
+	if err != nil {
+		return rule, fmt.Errorf("CreateTaxRule error: %w", err)
+	}
+	return rule, nil
+}
+
+func (s *PostgresRateStore) UpdateTaxRule(ctx context.Context, id int, rule models.TaxRule) (models.TaxRule, error) {
+	query := `
+        UPDATE tax_rules
+        SET country = $1, state = $2, rate = $3, tax_shipping = $4
+        WHERE id = $5
+    `
+	res, err := s.db.ExecContext(ctx, query,
+		rule.Country,
+		rule.State,
+		rule.Rate,
+		rule.TaxShipping,
+		id,
+	)
+	if err != nil {
+		return rule, fmt.Errorf("UpdateTaxRule error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return rule, fmt.Errorf("tax rule not found with id: %d", id)
+	}
+	rule.ID = id
+	return rule, nil
+}
+
+func (s *PostgresRateStore) DeleteTaxRule(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM tax_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteTaxRule error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresRateStore) ListTaxRules(ctx context.Context) ([]models.TaxRule, error) {
+	rules, err := listTaxRules(ctx, s.db)
+	if err != nil {
+		return nil, fmt.Errorf("ListTaxRules error: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *PostgresRateStore) CreateShippingRate(ctx context.Context, rate models.ShippingRate) (models.ShippingRate, error) {
+	query := `
+        INSERT INTO shipping_rates (country, max_weight_grams, max_items, base_fee, per_item, per_kg, free_over)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `
+	err := s.db.QueryRowContext(ctx, query,
+		rate.Country,
+		rate.MaxWeightGrams,
+		rate.MaxItems,
+		rate.BaseFee,
+		rate.PerItem,
+		rate.PerKg,
+		rate.FreeOver,
+	).Scan(&rate.ID)
+	if err != nil {
+		return rate, fmt.Errorf("CreateShippingRate error: %w", err)
+	}
+	return rate, nil
+}
+
+func (s *PostgresRateStore) UpdateShippingRate(ctx context.Context, id int, rate models.ShippingRate) (models.ShippingRate, error) {
+	query := `
+        UPDATE shipping_rates
+        SET country = $1, max_weight_grams = $2, max_items = $3, base_fee = $4,
+            per_item = $5, per_kg = $6, free_over = $7
+        WHERE id = $8
+    `
+	res, err := s.db.ExecContext(ctx, query,
+		rate.Country,
+		rate.MaxWeightGrams,
+		rate.MaxItems,
+		rate.BaseFee,
+		rate.PerItem,
+		rate.PerKg,
+		rate.FreeOver,
+		id,
+	)
+	if err != nil {
+		return rate, fmt.Errorf("UpdateShippingRate error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return rate, fmt.Errorf("shipping rate not found with id: %d", id)
+	}
+	rate.ID = id
+	return rate, nil
+}
+
+func (s *PostgresRateStore) DeleteShippingRate(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM shipping_rates WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteShippingRate error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresRateStore) ListShippingRates(ctx context.Context) ([]models.ShippingRate, error) {
+	rates, err := listShippingRates(ctx, s.db)
+	if err != nil {
+		return nil, fmt.Errorf("ListShippingRates error: %w", err)
+	}
+	return rates, nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func listTaxRules(ctx context.Context, q queryer) (pricing.TaxTable, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, country, state, rate, tax_shipping FROM tax_rules ORDER BY country, state, id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules pricing.TaxTable
+	for rows.Next() {
+		var r models.TaxRule
+		if err := rows.Scan(&r.ID, &r.Country, &r.State, &r.Rate, &r.TaxShipping); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func listShippingRates(ctx context.Context, q queryer) (pricing.ShippingTable, error) {
+	rows, err := q.QueryContext(ctx, `
+        SELECT id, country, max_weight_grams, max_items, base_fee, per_item, per_kg, free_over
+        FROM shipping_rates
+        ORDER BY country, id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates pricing.ShippingTable
+	for rows.Next() {
+		var r models.ShippingRate
+		if err := rows.Scan(
+			&r.ID,
+			&r.Country,
+			&r.MaxWeightGrams,
+			&r.MaxItems,
+			&r.BaseFee,
+			&r.PerItem,
+			&r.PerKg,
+			&r.FreeOver,
+		); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
@@ -4,7 +4,6 @@ package store
 import (
     "context"
     "database/sql"
-    "fmt"
     "time"
 
     "bookstore/internal/interfaces"
@@ -19,9 +18,8 @@ func NewPostgresReportStore(db *sql.DB) (interfaces.ReportStore, error) {
     return &PostgresReportStore{db: db}, nil
 }
 
+// SaveReport is a no-op: generated reports are not stored.
 func (s *PostgresReportStore) SaveReport(ctx context.Context, report models.SalesReport) error {
- 
-    fmt.Println("SaveReport called, but not storing in DB (no table).")
     return nil
 }
 
@@ -0,0 +1,567 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"bookstore/internal/auth"
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+	"bookstore/internal/pricing"
+)
+
+type PostgresReturnStore struct {
+	db *sql.DB
+}
+
+func NewPostgresReturnStore(db *sql.DB) (interfaces.ReturnStore, error) {
+	return &PostgresReturnStore{db: db}, nil
+}
+
+const returnColumns = `
+        id, order_id, status, reason, note, refund_amount, created_at, decided_at, decided_by
+`
+
+const refundColumns = `
+        id, order_id, return_id, payment_id, amount, tax_amount, status, reference,
+        failure_reason, created_at, completed_at
+`
+
+func scanReturn(row rowScanner) (models.ReturnRequest, error) {
+	var r models.ReturnRequest
+	err := row.Scan(
+		&r.ID,
+		&r.OrderID,
+		&r.Status,
+		&r.Reason,
+		&r.Note,
+		&r.RefundAmount,
+		&r.CreatedAt,
+		&r.DecidedAt,
+		&r.DecidedBy,
+	)
+	return r, err
+}
+
+func scanRefund(row rowScanner) (models.Refund, error) {
+	var r models.Refund
+	err := row.Scan(
+		&r.ID,
+		&r.OrderID,
+		&r.ReturnID,
+		&r.PaymentID,
+		&r.Amount,
+		&r.TaxAmount,
+		&r.Status,
+		&r.Reference,
+		&r.FailureReason,
+		&r.CreatedAt,
+		&r.CompletedAt,
+	)
+	return r, err
+}
+
+// CreateReturn checks the requested quantities against what was ordered
+// less what is already being or has been returned, and records the request
+// at the prices paid.
+func (s *PostgresReturnStore) CreateReturn(ctx context.Context, ret models.ReturnRequest) (models.ReturnRequest, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ret, fmt.Errorf("CreateReturn error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, ret.OrderID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ret, fmt.Errorf("order not found with id: %d", ret.OrderID)
+		}
+		return ret, fmt.Errorf("CreateReturn error: %w", err)
+	}
+	if status != models.OrderPaid && status != models.OrderDelivered {
+		return ret, fmt.Errorf("%w: order %d is %s", models.ErrOrderNotReturnable, ret.OrderID, status)
+	}
+
+	returnable, prices, err := returnableQuantities(ctx, tx, ret.OrderID)
+	if err != nil {
+		return ret, fmt.Errorf("CreateReturn error: %w", err)
+	}
+	requested := make(map[int]int)
+	for i, item := range ret.Items {
+		if item.Quantity <= 0 {
+			return ret, fmt.Errorf("%w: quantity must be positive for book %d", models.ErrInvalidReturn, item.BookID)
+		}
+		requested[item.BookID] += item.Quantity
+		if requested[item.BookID] > returnable[item.BookID] {
+			return ret, fmt.Errorf("%w: only %d of book %d can be returned", models.ErrInvalidReturn, returnable[item.BookID], item.BookID)
+		}
+		ret.Items[i].UnitPrice = prices[item.BookID]
+	}
+
+	ret.Status = models.ReturnRequested
+	ret.CreatedAt = time.Now()
+	ret.Note, ret.RefundAmount, ret.DecidedAt, ret.DecidedBy, ret.Refund = "", 0, nil, "", nil
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO return_requests (order_id, status, reason, created_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `, ret.OrderID, ret.Status, ret.Reason, ret.CreatedAt).Scan(&ret.ID)
+	if err != nil {
+		return ret, fmt.Errorf("CreateReturn (return_requests insert): %w", err)
+	}
+
+	for _, item := range ret.Items {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO return_items (return_id, book_id, quantity, reason, unit_price)
+            VALUES ($1, $2, $3, $4, $5)
+        `, ret.ID, item.BookID, item.Quantity, item.Reason, item.UnitPrice)
+		if err != nil {
+			return ret, fmt.Errorf("CreateReturn (return_items insert): %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ret, fmt.Errorf("CreateReturn commit: %w", err)
+	}
+	return ret, nil
+}
+
+// returnableQuantities returns, per book of the order, the quantity not yet
+// covered by a pending or approved return, and the unit price paid.
+func returnableQuantities(ctx context.Context, tx *sql.Tx, orderID int) (map[int]int, map[int]float64, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT oi.book_id, SUM(oi.quantity), MAX(oi.unit_price),
+               COALESCE((SELECT SUM(ri.quantity)
+                         FROM return_items ri
+                         JOIN return_requests rr ON ri.return_id = rr.id
+                         WHERE rr.order_id = oi.order_id AND ri.book_id = oi.book_id AND rr.status <> $2), 0)
+        FROM order_items oi
+        WHERE oi.order_id = $1
+        GROUP BY oi.order_id, oi.book_id
+    `, orderID, models.ReturnRejected)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	quantities := make(map[int]int)
+	prices := make(map[int]float64)
+	for rows.Next() {
+		var bookID, ordered, returned int
+		var price float64
+		if err := rows.Scan(&bookID, &ordered, &price, &returned); err != nil {
+			return nil, nil, err
+		}
+		quantities[bookID] = ordered - returned
+		prices[bookID] = price
+	}
+	return quantities, prices, rows.Err()
+}
+
+func (s *PostgresReturnStore) GetReturn(ctx context.Context, id int) (models.ReturnRequest, error) {
+	ret, err := scanReturn(s.db.QueryRowContext(ctx,
+		`SELECT `+returnColumns+` FROM return_requests WHERE id = $1`, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ret, fmt.Errorf("return request not found with id: %d", id)
+		}
+		return ret, err
+	}
+	if err := s.loadReturnLines(ctx, &ret); err != nil {
+		return ret, fmt.Errorf("GetReturn error: %w", err)
+	}
+	return ret, nil
+}
+
+func (s *PostgresReturnStore) ListReturns(ctx context.Context, orderID int, status string) ([]models.ReturnRequest, error) {
+	var conditions []string
+	var args []interface{}
+	if orderID > 0 {
+		args = append(args, orderID)
+		conditions = append(conditions, fmt.Sprintf("order_id = $%d", len(args)))
+	}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	query := `SELECT ` + returnColumns + ` FROM return_requests`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ListReturns error: %w", err)
+	}
+	var returns []models.ReturnRequest
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ListReturns scan: %w", err)
+		}
+		returns = append(returns, ret)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListReturns error: %w", err)
+	}
+
+	for i := range returns {
+		if err := s.loadReturnLines(ctx, &returns[i]); err != nil {
+			return nil, fmt.Errorf("ListReturns error: %w", err)
+		}
+	}
+	return returns, nil
+}
+
+// loadReturnLines fills in the items and refund of a return request.
+func (s *PostgresReturnStore) loadReturnLines(ctx context.Context, ret *models.ReturnRequest) error {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT book_id, quantity, reason, unit_price
+        FROM return_items
+        WHERE return_id = $1
+        ORDER BY id
+    `, ret.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ret.Items = nil
+	for rows.Next() {
+		var item models.ReturnItem
+		if err := rows.Scan(&item.BookID, &item.Quantity, &item.Reason, &item.UnitPrice); err != nil {
+			return err
+		}
+		ret.Items = append(ret.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	refund, err := scanRefund(s.db.QueryRowContext(ctx,
+		`SELECT `+refundColumns+` FROM refunds WHERE return_id = $1`, ret.ID,
+	))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ret.Refund = &refund
+	return nil
+}
+
+// ApproveReturn puts the returned books back in stock and records the
+// refund in one transaction. The refund is left pending when the order was
+// paid through a provider, for the caller to send it there.
+func (s *PostgresReturnStore) ApproveReturn(ctx context.Context, id int, note string) (models.ReturnRequest, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ReturnRequest{}, fmt.Errorf("ApproveReturn error: %w", err)
+	}
+	defer tx.Rollback()
+
+	ret, err := lockPendingReturn(ctx, tx, id)
+	if err != nil {
+		return ret, err
+	}
+
+	var order models.Order
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, subtotal, discount_total, tax_rate FROM orders WHERE id = $1`, ret.OrderID,
+	).Scan(&order.ID, &order.Subtotal, &order.DiscountTotal, &order.TaxRate)
+	if err != nil {
+		return ret, fmt.Errorf("ApproveReturn (order): %w", err)
+	}
+
+	items, err := returnItems(ctx, tx, id)
+	if err != nil {
+		return ret, fmt.Errorf("ApproveReturn (items): %w", err)
+	}
+	for _, item := range items {
+		_, err := recordMovement(ctx, tx, models.StockMovement{
+			BookID:  item.BookID,
+			Change:  item.Quantity,
+			Reason:  models.MovementReturn,
+			OrderID: &ret.OrderID,
+			Note:    fmt.Sprintf("return request %d", id),
+		})
+		if err != nil {
+			return ret, fmt.Errorf("ApproveReturn: %w", err)
+		}
+	}
+
+	amount, tax := pricing.Refund(order, items)
+	now := time.Now()
+	ret.Status = models.ReturnApproved
+	ret.Note = note
+	ret.RefundAmount = amount
+	ret.DecidedAt = &now
+	ret.DecidedBy = auth.Username(ctx)
+	if err := decideReturn(ctx, tx, ret); err != nil {
+		return ret, fmt.Errorf("ApproveReturn: %w", err)
+	}
+
+	refund := models.Refund{
+		OrderID:   ret.OrderID,
+		ReturnID:  ret.ID,
+		Amount:    amount,
+		TaxAmount: tax,
+		Status:    models.RefundCompleted,
+		CreatedAt: now,
+	}
+	var paymentID int
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM payments WHERE order_id = $1 AND status = $2`, ret.OrderID, models.PaymentCaptured,
+	).Scan(&paymentID)
+	switch {
+	case err == nil:
+		refund.PaymentID = &paymentID
+		refund.Status = models.RefundPending
+	case err == sql.ErrNoRows:
+		refund.CompletedAt = &now
+	default:
+		return ret, fmt.Errorf("ApproveReturn (payment): %w", err)
+	}
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO refunds (order_id, return_id, payment_id, amount, tax_amount, status, created_at, completed_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id
+    `, refund.OrderID, refund.ReturnID, refund.PaymentID, refund.Amount, refund.TaxAmount,
+		refund.Status, refund.CreatedAt, refund.CompletedAt,
+	).Scan(&refund.ID)
+	if err != nil {
+		return ret, fmt.Errorf("ApproveReturn (refunds insert): %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ret, fmt.Errorf("ApproveReturn commit: %w", err)
+	}
+	ret.Items = items
+	ret.Refund = &refund
+	return ret, nil
+}
+
+func (s *PostgresReturnStore) RejectReturn(ctx context.Context, id int, note string) (models.ReturnRequest, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ReturnRequest{}, fmt.Errorf("RejectReturn error: %w", err)
+	}
+	defer tx.Rollback()
+
+	ret, err := lockPendingReturn(ctx, tx, id)
+	if err != nil {
+		return ret, err
+	}
+	now := time.Now()
+	ret.Status = models.ReturnRejected
+	ret.Note = note
+	ret.DecidedAt = &now
+	ret.DecidedBy = auth.Username(ctx)
+	if err := decideReturn(ctx, tx, ret); err != nil {
+		return ret, fmt.Errorf("RejectReturn: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ret, fmt.Errorf("RejectReturn commit: %w", err)
+	}
+	return s.GetReturn(ctx, id)
+}
+
+func lockPendingReturn(ctx context.Context, tx *sql.Tx, id int) (models.ReturnRequest, error) {
+	ret, err := scanReturn(tx.QueryRowContext(ctx,
+		`SELECT `+returnColumns+` FROM return_requests WHERE id = $1 FOR UPDATE`, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ret, fmt.Errorf("return request not found with id: %d", id)
+		}
+		return ret, err
+	}
+	if ret.Status != models.ReturnRequested {
+		return ret, fmt.Errorf("%w: return request %d is %s", models.ErrReturnDecided, id, ret.Status)
+	}
+	return ret, nil
+}
+
+func decideReturn(ctx context.Context, tx *sql.Tx, ret models.ReturnRequest) error {
+	_, err := tx.ExecContext(ctx, `
+        UPDATE return_requests
+        SET status = $1, note = $2, refund_amount = $3, decided_at = $4, decided_by = $5
+        WHERE id = $6
+    `, ret.Status, ret.Note, ret.RefundAmount, ret.DecidedAt, ret.DecidedBy, ret.ID)
+	return err
+}
+
+// returnItems loads the items of a return, sorted by book so that stock
+// rows are locked in a consistent order.
+func returnItems(ctx context.Context, tx *sql.Tx, returnID int) ([]models.ReturnItem, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT book_id, quantity, reason, unit_price FROM return_items WHERE return_id = $1`, returnID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.ReturnItem
+	for rows.Next() {
+		var item models.ReturnItem
+		if err := rows.Scan(&item.BookID, &item.Quantity, &item.Reason, &item.UnitPrice); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].BookID < items[j].BookID })
+	return items, rows.Err()
+}
+
+func (s *PostgresReturnStore) GetRefund(ctx context.Context, id int) (models.Refund, error) {
+	refund, err := scanRefund(s.db.QueryRowContext(ctx,
+		`SELECT `+refundColumns+` FROM refunds WHERE id = $1`, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return refund, fmt.Errorf("refund not found with id: %d", id)
+		}
+		return refund, err
+	}
+	return refund, nil
+}
+
+func (s *PostgresReturnStore) ListRefunds(ctx context.Context, orderID int) ([]models.Refund, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+refundColumns+` FROM refunds WHERE order_id = $1 ORDER BY id`, orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListRefunds error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRefunds(rows)
+}
+
+func scanRefunds(rows *sql.Rows) ([]models.Refund, error) {
+	var refunds []models.Refund
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds, rows.Err()
+}
+
+func (s *PostgresReturnStore) ClaimRefund(ctx context.Context, id int, stale time.Time) (models.Refund, error) {
+	refund, err := scanRefund(s.db.QueryRowContext(ctx, `
+        UPDATE refunds
+        SET status = $1, claimed_at = NOW()
+        WHERE id = $2 AND (status IN ($3, $4) OR (status = $1 AND claimed_at <= $5))
+        RETURNING `+refundColumns,
+		models.RefundSending, id, models.RefundPending, models.RefundFailed, stale,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			if _, err := s.GetRefund(ctx, id); err != nil {
+				return refund, err
+			}
+			return refund, fmt.Errorf("%w: refund %d", models.ErrRefundClaimed, id)
+		}
+		return refund, fmt.Errorf("ClaimRefund error: %w", err)
+	}
+	return refund, nil
+}
+
+// CompleteRefund records the provider's acceptance of a refund. Once every
+// item of the order has been refunded, the payment is marked refunded.
+// Shipping is never refunded, so the refunds do not add up to the payment.
+func (s *PostgresReturnStore) CompleteRefund(ctx context.Context, id int, reference string) (models.Refund, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Refund{}, fmt.Errorf("CompleteRefund error: %w", err)
+	}
+	defer tx.Rollback()
+
+	refund, err := scanRefund(tx.QueryRowContext(ctx, `
+        UPDATE refunds
+        SET status = $1, reference = $2, failure_reason = '', completed_at = NOW()
+        WHERE id = $3
+        RETURNING `+refundColumns,
+		models.RefundCompleted, reference, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return refund, fmt.Errorf("refund not found with id: %d", id)
+		}
+		return refund, fmt.Errorf("CompleteRefund error: %w", err)
+	}
+
+	if refund.PaymentID != nil {
+		_, err = tx.ExecContext(ctx, `
+            UPDATE payments p
+            SET status = $1, updated_at = NOW()
+            WHERE p.id = $2 AND p.status = $4 AND NOT EXISTS (
+                SELECT 1
+                FROM order_items oi
+                WHERE oi.order_id = p.order_id
+                GROUP BY oi.book_id
+                HAVING SUM(oi.quantity) > (
+                    SELECT COALESCE(SUM(ri.quantity), 0)
+                    FROM return_items ri
+                    JOIN refunds r ON r.return_id = ri.return_id
+                    WHERE r.payment_id = p.id AND r.status = $3 AND ri.book_id = oi.book_id
+                )
+            )
+        `, models.PaymentRefunded, *refund.PaymentID, models.RefundCompleted, models.PaymentCaptured)
+		if err != nil {
+			return refund, fmt.Errorf("CompleteRefund (payments update): %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return refund, fmt.Errorf("CompleteRefund commit: %w", err)
+	}
+	return refund, nil
+}
+
+func (s *PostgresReturnStore) FailRefund(ctx context.Context, id int, reason string) (models.Refund, error) {
+	refund, err := scanRefund(s.db.QueryRowContext(ctx, `
+        UPDATE refunds
+        SET status = $1, failure_reason = $2
+        WHERE id = $3
+        RETURNING `+refundColumns,
+		models.RefundFailed, reason, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return refund, fmt.Errorf("refund not found with id: %d", id)
+		}
+		return refund, fmt.Errorf("FailRefund error: %w", err)
+	}
+	return refund, nil
+}
+
+// GetRefundsInTimeRange returns the refunds completed in [start, end].
+func (s *PostgresReturnStore) GetRefundsInTimeRange(ctx context.Context, start, end time.Time) ([]models.Refund, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+refundColumns+`
+        FROM refunds
+        WHERE status = $1 AND completed_at BETWEEN $2 AND $3
+        ORDER BY id
+    `, models.RefundCompleted, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("GetRefundsInTimeRange error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRefunds(rows)
+}
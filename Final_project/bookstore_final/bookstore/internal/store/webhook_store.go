@@ -0,0 +1,256 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresWebhookStore struct {
+	db *sql.DB
+}
+
+func NewPostgresWebhookStore(db *sql.DB) (interfaces.WebhookStore, error) {
+	return &PostgresWebhookStore{db: db}, nil
+}
+
+const deliveryColumns = `
+        d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+        d.response_code, d.response_body, d.last_error, d.next_attempt_at, d.created_at,
+        d.delivered_at, d.redelivery_of, d.event_created_at, s.url, s.secret
+`
+
+func scanDelivery(row rowScanner) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(
+		&d.ID,
+		&d.SubscriptionID,
+		&d.EventID,
+		&d.EventType,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.ResponseCode,
+		&d.ResponseBody,
+		&d.LastError,
+		&d.NextAttemptAt,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+		&d.RedeliveryOf,
+		&d.EventCreatedAt,
+		&d.URL,
+		&d.Secret,
+	)
+	return d, err
+}
+
+func scanDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *PostgresWebhookStore) CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	query := `
+        INSERT INTO webhook_subscriptions (url, event_types, secret, active, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `
+	now := time.Now()
+	err := s.db.QueryRowContext(ctx, query,
+		sub.URL,
+		pq.Array(sub.EventTypes),
+		sub.Secret,
+		sub.Active,
+		now,
+	).Scan(&sub.ID)
+	if err != nil {
+		return sub, fmt.Errorf("CreateSubscription error: %w", err)
+	}
+	sub.CreatedAt = now
+	return sub, nil
+}
+
+func (s *PostgresWebhookStore) GetSubscription(ctx context.Context, id int) (models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	err := s.db.QueryRowContext(ctx, `
+        SELECT id, url, event_types, secret, active, created_at
+        FROM webhook_subscriptions
+        WHERE id = $1
+    `, id).Scan(&sub.ID, &sub.URL, pq.Array(&sub.EventTypes), &sub.Secret, &sub.Active, &sub.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return sub, fmt.Errorf("webhook subscription not found with id: %d", id)
+		}
+		return sub, err
+	}
+	return sub, nil
+}
+
+// UpdateSubscription changes the URL, event types and active flag. The
+// secret is only replaced when a new one is given.
+func (s *PostgresWebhookStore) UpdateSubscription(ctx context.Context, id int, sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	query := `
+        UPDATE webhook_subscriptions
+        SET url = $1, event_types = $2, secret = COALESCE(NULLIF($3, ''), secret), active = $4
+        WHERE id = $5
+    `
+	res, err := s.db.ExecContext(ctx, query,
+		sub.URL,
+		pq.Array(sub.EventTypes),
+		sub.Secret,
+		sub.Active,
+		id,
+	)
+	if err != nil {
+		return sub, fmt.Errorf("UpdateSubscription error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sub, fmt.Errorf("webhook subscription not found with id: %d", id)
+	}
+	return s.GetSubscription(ctx, id)
+}
+
+func (s *PostgresWebhookStore) DeleteSubscription(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteSubscription error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, url, event_types, secret, active, created_at
+        FROM webhook_subscriptions
+        ORDER BY id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("ListSubscriptions error: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []models.WebhookSubscription
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, pq.Array(&sub.EventTypes), &sub.Secret, &sub.Active, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ListSubscriptions scan: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *PostgresWebhookStore) EnqueueDeliveries(ctx context.Context, event models.Event) error {
+	_, err := s.db.ExecContext(ctx, `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, event_created_at,
+                                        status, next_attempt_at, created_at)
+        SELECT id, $1, $2, $3, $4, $5, NOW(), NOW()
+        FROM webhook_subscriptions
+        WHERE active AND event_types && ARRAY[$2, '*']
+        ON CONFLICT (subscription_id, event_id) WHERE redelivery_of IS NULL DO NOTHING
+    `, event.ID, event.Type, []byte(event.Payload), event.CreatedAt, models.DeliveryPending)
+	if err != nil {
+		return fmt.Errorf("EnqueueDeliveries error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+deliveryColumns+`
+        FROM webhook_deliveries d
+        JOIN webhook_subscriptions s ON d.subscription_id = s.id
+        WHERE d.status = $1 AND d.next_attempt_at <= $2 AND s.active
+        ORDER BY d.next_attempt_at, d.id
+        LIMIT $3
+    `, models.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("DueDeliveries error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+func (s *PostgresWebhookStore) RecordAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $1, attempts = $2, response_code = $3, response_body = $4, last_error = $5,
+            next_attempt_at = $6, delivered_at = $7
+        WHERE id = $8
+    `,
+		d.Status,
+		d.Attempts,
+		d.ResponseCode,
+		d.ResponseBody,
+		d.LastError,
+		d.NextAttemptAt,
+		d.DeliveredAt,
+		d.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("RecordAttempt error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) GetDelivery(ctx context.Context, id int) (models.WebhookDelivery, error) {
+	d, err := scanDelivery(s.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+`
+        FROM webhook_deliveries d
+        JOIN webhook_subscriptions s ON d.subscription_id = s.id
+        WHERE d.id = $1
+    `, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return d, fmt.Errorf("webhook delivery not found with id: %d", id)
+		}
+		return d, err
+	}
+	return d, nil
+}
+
+func (s *PostgresWebhookStore) ListDeliveries(ctx context.Context, subscriptionID int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+deliveryColumns+`
+        FROM webhook_deliveries d
+        JOIN webhook_subscriptions s ON d.subscription_id = s.id
+        WHERE d.subscription_id = $1
+        ORDER BY d.id DESC
+    `, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("ListDeliveries error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+func (s *PostgresWebhookStore) Redeliver(ctx context.Context, deliveryID int) (models.WebhookDelivery, error) {
+	var id int
+	err := s.db.QueryRowContext(ctx, `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, event_created_at,
+                                        status, next_attempt_at, created_at, redelivery_of)
+        SELECT subscription_id, event_id, event_type, payload, event_created_at, $1, NOW(), NOW(), id
+        FROM webhook_deliveries
+        WHERE id = $2
+        RETURNING id
+    `, models.DeliveryPending, deliveryID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.WebhookDelivery{}, fmt.Errorf("webhook delivery not found with id: %d", deliveryID)
+		}
+		return models.WebhookDelivery{}, fmt.Errorf("Redeliver error: %w", err)
+	}
+	return s.GetDelivery(ctx, id)
+}
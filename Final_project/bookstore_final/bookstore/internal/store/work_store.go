@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+)
+
+type PostgresWorkStore struct {
+	db *sql.DB
+}
+
+func NewPostgresWorkStore(db *sql.DB) (interfaces.WorkStore, error) {
+	return &PostgresWorkStore{db: db}, nil
+}
+
+func (s *PostgresWorkStore) CreateWork(ctx context.Context, work models.Work) (models.Work, error) {
+	work.CreatedAt = time.Now()
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO works (title, created_at) VALUES ($1, $2) RETURNING id`,
+		work.Title, work.CreatedAt,
+	).Scan(&work.ID)
+	if err != nil {
+		return work, fmt.Errorf("CreateWork error: %w", err)
+	}
+	return work, nil
+}
+
+func (s *PostgresWorkStore) GetWork(ctx context.Context, id int) (models.Work, error) {
+	var w models.Work
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, title, created_at FROM works WHERE id = $1`, id,
+	).Scan(&w.ID, &w.Title, &w.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return w, fmt.Errorf("work not found with id: %d", id)
+		}
+		return w, fmt.Errorf("GetWork error: %w", err)
+	}
+	return w, nil
+}
+
+func (s *PostgresWorkStore) UpdateWork(ctx context.Context, id int, work models.Work) (models.Work, error) {
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE works SET title = $1 WHERE id = $2 RETURNING created_at`,
+		work.Title, id,
+	).Scan(&work.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return work, fmt.Errorf("work not found with id: %d", id)
+		}
+		return work, fmt.Errorf("UpdateWork error: %w", err)
+	}
+	work.ID = id
+	return work, nil
+}
+
+func (s *PostgresWorkStore) DeleteWork(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM works WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteWork error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresWorkStore) ListWorks(ctx context.Context) ([]models.Work, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, title, created_at FROM works ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ListWorks error: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.Work
+	for rows.Next() {
+		var w models.Work
+		if err := rows.Scan(&w.ID, &w.Title, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, w)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,166 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bookstore/internal/events"
+	"bookstore/internal/interfaces"
+	"bookstore/internal/models"
+	"bookstore/pkg/utils"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is
+	// marked failed.
+	MaxAttempts = 8
+
+	sendBatch       = 50
+	requestTimeout  = 10 * time.Second
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = 6 * time.Hour
+	maxResponseBody = 1024
+)
+
+// Envelope is the JSON body of a delivery.
+type Envelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Subscriber returns a bus handler that queues a delivery of every event
+// for the matching subscriptions.
+func Subscriber(store interfaces.WebhookStore) events.Handler {
+	return func(ctx context.Context, event models.Event) error {
+		return store.EnqueueDeliveries(ctx, event)
+	}
+}
+
+// Sender posts due deliveries to their subscriptions. A delivery succeeds on
+// any 2xx response; otherwise it is retried with exponential backoff until
+// MaxAttempts.
+type Sender struct {
+	store    interfaces.WebhookStore
+	client   *http.Client
+	interval time.Duration
+	logger   *utils.Logger
+}
+
+func NewSender(store interfaces.WebhookStore, interval time.Duration, logger *utils.Logger) *Sender {
+	return &Sender{
+		store:    store,
+		client:   &http.Client{Timeout: requestTimeout},
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (s *Sender) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			due, err := s.store.DueDeliveries(ctx, now, sendBatch)
+			if err != nil {
+				s.logger.Error("Failed to load webhook deliveries: %v", err)
+				continue
+			}
+			for _, d := range due {
+				s.deliver(ctx, d)
+			}
+		}
+	}
+}
+
+func (s *Sender) deliver(ctx context.Context, d models.WebhookDelivery) {
+	d.Attempts++
+	code, body, err := s.send(ctx, d)
+	d.ResponseCode = code
+	d.ResponseBody = body
+	d.LastError = ""
+
+	now := time.Now()
+	switch {
+	case err == nil && code >= 200 && code < 300:
+		d.Status = models.DeliverySucceeded
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+	default:
+		if err != nil {
+			d.LastError = err.Error()
+		} else {
+			d.LastError = fmt.Sprintf("unexpected status %d", code)
+		}
+		if d.Attempts >= MaxAttempts {
+			d.Status = models.DeliveryFailed
+			d.NextAttemptAt = nil
+			s.logger.Error("Webhook delivery %d to %s failed permanently: %s", d.ID, d.URL, d.LastError)
+		} else {
+			next := now.Add(retryDelay(d.Attempts))
+			d.NextAttemptAt = &next
+		}
+	}
+
+	if err := s.store.RecordAttempt(ctx, d); err != nil {
+		s.logger.Error("Failed to record webhook delivery %d: %v", d.ID, err)
+	}
+}
+
+// send posts the delivery and returns the response code and the start of
+// the response body.
+func (s *Sender) send(ctx context.Context, d models.WebhookDelivery) (int, string, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        d.EventID,
+		Type:      d.EventType,
+		CreatedAt: d.EventCreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(d.ID))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(respBody), nil
+}
+
+// retryDelay doubles from firstRetryDelay after each attempt, up to
+// maxRetryDelay.
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
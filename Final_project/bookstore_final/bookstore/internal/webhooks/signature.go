@@ -0,0 +1,56 @@
+// Package webhooks sends domain events to the HTTP endpoints registered as
+// webhook subscriptions.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set on every delivery.
+const (
+	EventHeader     = "X-Bookstore-Event"
+	DeliveryHeader  = "X-Bookstore-Delivery"
+	TimestampHeader = "X-Bookstore-Timestamp"
+	SignatureHeader = "X-Bookstore-Signature"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the signature header value for a delivery body sent at
+// timestamp: "sha256=" followed by the hex HMAC-SHA256, keyed with the
+// subscription secret, of "<timestamp>.<body>". Including the timestamp
+// lets receivers reject replayed deliveries.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the timestamp and signature headers of a delivery received
+// at now. Deliveries older than tolerance are rejected; a zero tolerance
+// accepts any age.
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, now time.Time, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrInvalidSignature
+		}
+	}
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(strings.TrimSpace(signatureHeader)), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
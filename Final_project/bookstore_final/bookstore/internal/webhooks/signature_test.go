@@ -0,0 +1,76 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		want      string
+	}{
+		{
+			name:      "body",
+			secret:    "secret",
+			timestamp: 1700000000,
+			body:      `{"id":1}`,
+			want:      "sha256=3dd1b9aef568d75f6790a84bd2e5dfa1f44409eef3cbdbd3f10b837376100c11",
+		},
+		{
+			name: "empty",
+			want: "sha256=b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("Sign = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const secret = "secret"
+	body := []byte(`{"id":1}`)
+	now := time.Unix(1700000000, 0)
+	sig := Sign(secret, now.Unix(), body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		tolerance time.Duration
+		wantErr   bool
+	}{
+		{"valid", secret, "1700000000", sig, body, now, 5 * time.Minute, false},
+		{"surrounding space", secret, "1700000000", " " + sig + " ", body, now, 5 * time.Minute, false},
+		{"within tolerance", secret, "1700000000", sig, body, now.Add(4 * time.Minute), 5 * time.Minute, false},
+		{"too old", secret, "1700000000", sig, body, now.Add(6 * time.Minute), 5 * time.Minute, true},
+		{"from the future", secret, "1700000000", sig, body, now.Add(-6 * time.Minute), 5 * time.Minute, true},
+		{"any age without tolerance", secret, "1700000000", sig, body, now.Add(24 * time.Hour), 0, false},
+		{"wrong secret", "other", "1700000000", sig, body, now, 5 * time.Minute, true},
+		{"changed body", secret, "1700000000", sig, []byte(`{"id":2}`), now, 5 * time.Minute, true},
+		{"changed timestamp", secret, "1700000001", sig, body, now, 5 * time.Minute, true},
+		{"bad timestamp", secret, "yesterday", sig, body, now, 5 * time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, tt.now, tt.tolerance)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify = %v, want ErrInvalidSignature", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Verify = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -3,31 +3,52 @@
 package utils
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
-	"strings"
+	"syscall"
 	"time"
 )
 
+// RequestIDHeader carries the request id. A valid incoming value is reused,
+// so that ids can be correlated across services.
+const RequestIDHeader = "X-Request-Id"
+
+// Logger writes structured logs through log/slog: JSON to the log file, and
+// to stdout either colored text when it is a terminal or JSON otherwise.
+// Records logged with a context carry the request id, user and trace id of
+// the request that context belongs to.
 type Logger struct {
-	infoLogger  *log.Logger
-	errorLogger *log.Logger
-	debugLogger *log.Logger
+	slog *slog.Logger
+	file *RotatingFile
 }
 
 type LogConfig struct {
-	LogDir     string
-	LogFile    string
-	Debug      bool
+	LogDir  string
+	LogFile string
+	// Level is the minimum level logged: "debug", "info", "warn" or
+	// "error". Empty means "info".
+	Level      string
 	TimeFormat string
+	// Rotate controls rotation and retention of the log file.
+	Rotate RotateConfig
 }
 
 func NewLogger(config LogConfig) (*Logger, error) {
+	var level slog.Level
+	if config.Level != "" {
+		if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q", config.Level)
+		}
+	}
+
 	// Create log directory if it doesn't exist
 	if err := os.MkdirAll(config.LogDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create log directory: %v", err)
@@ -35,88 +56,211 @@ func NewLogger(config LogConfig) (*Logger, error) {
 
 	// Open log file
 	logPath := filepath.Join(config.LogDir, config.LogFile)
-	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	file, err := OpenRotatingFile(logPath, config.Rotate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %v", err)
+		return nil, err
 	}
 
-	// Create multi-writer for both file and stdout
-	multiWriter := io.MultiWriter(os.Stdout, file)
-
-	// Create formatters for different log levels
 	timeFormat := config.TimeFormat
 	if timeFormat == "" {
 		timeFormat = "2006/01/02 15:04:05"
 	}
 
-	flags := log.Ldate | log.Ltime
-	if config.Debug {
-		flags |= log.Llongfile
+	opts := &slog.HandlerOptions{Level: level, AddSource: true}
+	var console slog.Handler
+	if isTerminal(os.Stdout) {
+		console = newTextHandler(os.Stdout, level, timeFormat)
+	} else {
+		console = slog.NewJSONHandler(os.Stdout, opts)
 	}
 
-	return &Logger{
-		infoLogger: log.New(multiWriter,
-			fmt.Sprintf("\033[36m%s\033[0m ", "INFO "),
-			flags,
-		),
-		errorLogger: log.New(multiWriter,
-			fmt.Sprintf("\033[31m%s\033[0m ", "ERROR"),
-			flags,
-		),
-		debugLogger: log.New(multiWriter,
-			fmt.Sprintf("\033[33m%s\033[0m ", "DEBUG"),
-			flags,
-		),
-	}, nil
+	handler := &contextHandler{fanoutHandler{console, slog.NewJSONHandler(file, opts)}}
+	return &Logger{slog: slog.New(handler), file: file}, nil
+}
+
+// With returns a Logger that adds the given key-value pairs to every record.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{slog: l.slog.With(args...), file: l.file}
+}
+
+// Reopen reopens the log file, for use after it has been moved by an
+// external tool such as logrotate.
+func (l *Logger) Reopen() error {
+	return l.file.Reopen()
+}
+
+// ReopenOnSIGHUP reopens the log file whenever the process receives SIGHUP,
+// until stop is called.
+func (l *Logger) ReopenOnSIGHUP() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigs:
+				if err := l.Reopen(); err != nil {
+					l.Error("Failed to reopen log file: %v", err)
+					continue
+				}
+				l.Info("Reopened log file")
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
+
+// Close closes the log file and waits for any rotated file to finish
+// compressing. Records logged afterwards only go to stdout.
+func (l *Logger) Close() error {
+	return l.file.Close()
 }
 
 func (l *Logger) Info(format string, v ...interface{}) {
-	l.log(l.infoLogger, format, v...)
+	l.log(context.Background(), slog.LevelInfo, format, v...)
+}
+
+func (l *Logger) Warn(format string, v ...interface{}) {
+	l.log(context.Background(), slog.LevelWarn, format, v...)
 }
 
 func (l *Logger) Error(format string, v ...interface{}) {
-	l.log(l.errorLogger, format, v...)
+	l.log(context.Background(), slog.LevelError, format, v...)
 }
 
 func (l *Logger) Debug(format string, v ...interface{}) {
-	l.log(l.debugLogger, format, v...)
+	l.log(context.Background(), slog.LevelDebug, format, v...)
 }
 
-func (l *Logger) log(logger *log.Logger, format string, v ...interface{}) {
-	// Get caller information
-	_, file, line, ok := runtime.Caller(2)
-	if ok {
-		// Extract just the file name without the full path
-		file = filepath.Base(file)
-		prefix := fmt.Sprintf("[%s:%d] ", file, line)
-		logger.Printf(prefix+format, v...)
-	} else {
-		logger.Printf(format, v...)
+// The Context variants add the request fields found in ctx.
+
+func (l *Logger) InfoContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, slog.LevelInfo, format, v...)
+}
+
+func (l *Logger) WarnContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, slog.LevelWarn, format, v...)
+}
+
+func (l *Logger) ErrorContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, slog.LevelError, format, v...)
+}
+
+func (l *Logger) DebugContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, slog.LevelDebug, format, v...)
+}
+
+// LogAttrs logs a message with structured attributes.
+func (l *Logger) LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	l.write(ctx, level, msg, attrs, 3)
+}
+
+func (l *Logger) log(ctx context.Context, level slog.Level, format string, v ...interface{}) {
+	if !l.slog.Enabled(ctx, level) {
+		return
+	}
+	msg := format
+	if len(v) > 0 {
+		msg = fmt.Sprintf(format, v...)
+	}
+	l.write(ctx, level, msg, nil, 4)
+}
+
+// write logs a record whose source is skip frames up the stack, counted as
+// for runtime.Callers, so that it points at the caller of the exported
+// method rather than at this file.
+func (l *Logger) write(ctx context.Context, level slog.Level, msg string, attrs []slog.Attr, skip int) {
+	if !l.slog.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(skip, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.AddAttrs(attrs...)
+	_ = l.slog.Handler().Handle(ctx, r)
+}
+
+// requestInfo is kept in the context of every request. It is a pointer so
+// that middleware further down, such as authentication, can fill in the
+// user for the final request log line.
+type requestInfo struct {
+	id   string
+	user string
+}
+
+type requestInfoKey struct{}
+
+// RequestID returns the id of the request ctx belongs to, or "".
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.id
 	}
+	return ""
 }
 
-// HTTP request logging middleware
+// SetRequestUser records the authenticated user of the request ctx belongs
+// to, for its log records.
+func SetRequestUser(ctx context.Context, user string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.user = user
+	}
+}
+
+// HTTP request logging middleware. It assigns every request an id, returned
+// in the X-Request-Id header and added to records logged with its context.
 func (l *Logger) HTTPMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestInfoKey{}, &requestInfo{id: id})
+
 		// Create a response wrapper to capture the status code
 		wrapped := wrapResponseWriter(w)
 
 		// Process request
-		next.ServeHTTP(wrapped, r)
-
-		// Log request details
-		duration := time.Since(start)
-		l.Info("HTTP %s %s %d %v",
-			r.Method,
-			r.URL.Path,
-			wrapped.status,
-			duration,
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if wrapped.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		l.LogAttrs(ctx, level, "HTTP request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", wrapped.status),
+			slog.Duration("duration", time.Since(start)),
 		)
 	})
 }
 
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
 // responseWriter wrapper to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -143,45 +287,69 @@ func (rw *responseWriter) Write(buf []byte) (int, error) {
 	return rw.ResponseWriter.Write(buf)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streaming responses.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Helper functions for common logging patterns
 
 func (l *Logger) LogAPIRequest(method, path string, status int, duration time.Duration) {
-	l.Info("API Request: %s %s - Status: %d - Duration: %v",
-		method, path, status, duration)
+	l.LogAttrs(context.Background(), slog.LevelInfo, "API request",
+		slog.String("method", method),
+		slog.String("path", path),
+		slog.Int("status", status),
+		slog.Duration("duration", duration),
+	)
 }
 
-func (l *Logger) LogDatabaseOperation(operation, entity string, duration time.Duration, err error) {
+func (l *Logger) LogDatabaseOperation(ctx context.Context, operation, entity string, duration time.Duration, err error) {
 	if err != nil {
-		l.Error("DB Operation: %s %s - Error: %v - Duration: %v",
-			operation, entity, err, duration)
-	} else {
-		l.Debug("DB Operation: %s %s - Duration: %v",
-			operation, entity, duration)
+		l.LogAttrs(ctx, slog.LevelWarn, "DB operation failed",
+			slog.String("operation", operation),
+			slog.String("entity", entity),
+			slog.Duration("duration", duration),
+			slog.String("error", err.Error()),
+		)
+		return
 	}
+	l.LogAttrs(ctx, slog.LevelDebug, "DB operation",
+		slog.String("operation", operation),
+		slog.String("entity", entity),
+		slog.Duration("duration", duration),
+	)
 }
 
 func (l *Logger) LogReportGeneration(reportType string, start, end time.Time, err error) {
+	attrs := []slog.Attr{
+		slog.String("report", reportType),
+		slog.String("from", start.Format("2006-01-02")),
+		slog.String("to", end.Format("2006-01-02")),
+	}
 	if err != nil {
-		l.Error("Report Generation: %s (%v to %v) - Error: %v",
-			reportType, start.Format("2006-01-02"), end.Format("2006-01-02"), err)
-	} else {
-		l.Info("Report Generation: %s (%v to %v) - Success",
-			reportType, start.Format("2006-01-02"), end.Format("2006-01-02"))
+		l.LogAttrs(context.Background(), slog.LevelError, "Report generation failed",
+			append(attrs, slog.String("error", err.Error()))...)
+		return
 	}
+	l.LogAttrs(context.Background(), slog.LevelInfo, "Report generated", attrs...)
 }
 
 // Transaction logging
 func (l *Logger) LogTransaction(transactionType string, details map[string]interface{}, err error) {
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Transaction: %s - ", transactionType))
-
+	attrs := []slog.Attr{slog.String("transaction", transactionType)}
 	for k, v := range details {
-		sb.WriteString(fmt.Sprintf("%s: %v, ", k, v))
+		attrs = append(attrs, slog.Any(k, v))
 	}
-
 	if err != nil {
-		l.Error("%s - Error: %v", sb.String(), err)
-	} else {
-		l.Info("%s - Success", sb.String())
+		l.LogAttrs(context.Background(), slog.LevelError, "Transaction failed",
+			append(attrs, slog.String("error", err.Error()))...)
+		return
 	}
+	l.LogAttrs(context.Background(), slog.LevelInfo, "Transaction succeeded", attrs...)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
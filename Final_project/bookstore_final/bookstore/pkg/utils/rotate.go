@@ -0,0 +1,304 @@
+// pkg/utils/rotate.go
+
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat names rotated files so that they sort chronologically.
+const rotatedTimeFormat = "20060102T150405.000"
+
+// RotateConfig controls when a RotatingFile is rotated and how long rotated
+// files are kept. Zero values disable the corresponding limit.
+type RotateConfig struct {
+	// MaxSize rotates the file before a write would take it past this many
+	// bytes.
+	MaxSize int64
+	// Interval rotates the file once it has been written to for this long.
+	Interval time.Duration
+	// MaxFiles is the number of rotated files kept.
+	MaxFiles int
+	// MaxAge deletes rotated files older than this.
+	MaxAge time.Duration
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// RotatingFile is an append-only log file that rotates itself by size and
+// age. A rotated file is renamed to <name>-<time><ext> next to it, then
+// compressed and old files swept in the background.
+type RotatingFile struct {
+	path   string
+	config RotateConfig
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	bgMu sync.Mutex // serialises compression and sweeps
+	bg   sync.WaitGroup
+	stop chan struct{}
+}
+
+// OpenRotatingFile opens path for appending, creating it if needed.
+func OpenRotatingFile(path string, config RotateConfig) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, config: config, stop: make(chan struct{})}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.background(func() {
+		f.compressPending()
+		f.sweep()
+	})
+	go f.run()
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %v", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.due(int64(len(p)), time.Now()) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// due reports whether the file should be rotated before writing n bytes.
+func (f *RotatingFile) due(n int64, now time.Time) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.config.MaxSize > 0 && f.size+n > f.config.MaxSize {
+		return true
+	}
+	return f.config.Interval > 0 && now.Sub(f.opened) >= f.config.Interval
+}
+
+// Rotate rotates the file now.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %v", err)
+	}
+	f.file = nil
+
+	rotated := f.rotatedName(time.Now())
+	if err := os.Rename(f.path, rotated); err != nil && !os.IsNotExist(err) {
+		// Keep writing to the current file rather than losing logs.
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("failed to rotate log file: %v", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.background(func() {
+		if f.config.Compress {
+			compressFile(rotated)
+		}
+		f.sweep()
+	})
+	return nil
+}
+
+// Reopen closes and reopens the file at its path, for use after an
+// external tool such as logrotate has moved it.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %v", err)
+	}
+	f.file = nil
+	return f.open()
+}
+
+// Close closes the file and waits for background compression to finish.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.file == nil {
+		f.mu.Unlock()
+		return os.ErrClosed
+	}
+	close(f.stop)
+	err := f.file.Close()
+	f.file = nil
+	f.mu.Unlock()
+
+	f.bg.Wait()
+	return err
+}
+
+// run rotates by age even when nothing is written, and applies MaxAge.
+func (f *RotatingFile) run() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case now := <-ticker.C:
+			// Background work is only started under mu while the file is
+			// open, so that Close never waits while more is being added.
+			f.mu.Lock()
+			if f.file != nil {
+				if f.config.Interval > 0 && f.due(0, now) {
+					f.rotate()
+				}
+				f.background(f.sweep)
+			}
+			f.mu.Unlock()
+		}
+	}
+}
+
+func (f *RotatingFile) background(fn func()) {
+	f.bg.Add(1)
+	go func() {
+		defer f.bg.Done()
+		f.bgMu.Lock()
+		defer f.bgMu.Unlock()
+		fn()
+	}()
+}
+
+func (f *RotatingFile) rotatedName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	name := base + "-" + t.Format(rotatedTimeFormat) + ext
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%s-%d%s", base, t.Format(rotatedTimeFormat), i, ext)
+	}
+	return name
+}
+
+// rotatedFiles lists the rotated files, oldest first.
+func (f *RotatingFile) rotatedFiles() []string {
+	ext := filepath.Ext(f.path)
+	pattern := strings.TrimSuffix(f.path, ext) + "-*" + ext
+	plain, _ := filepath.Glob(pattern)
+	compressed, _ := filepath.Glob(pattern + ".gz")
+	files := append(plain, compressed...)
+	sort.Strings(files)
+	return files
+}
+
+// compressPending compresses files left uncompressed by an earlier run.
+func (f *RotatingFile) compressPending() {
+	if !f.config.Compress {
+		return
+	}
+	for _, name := range f.rotatedFiles() {
+		if !strings.HasSuffix(name, ".gz") {
+			compressFile(name)
+		}
+	}
+}
+
+// sweep deletes rotated files beyond MaxFiles or older than MaxAge.
+func (f *RotatingFile) sweep() {
+	files := f.rotatedFiles()
+	if f.config.MaxFiles > 0 && len(files) > f.config.MaxFiles {
+		for _, name := range files[:len(files)-f.config.MaxFiles] {
+			os.Remove(name)
+		}
+		files = files[len(files)-f.config.MaxFiles:]
+	}
+	if f.config.MaxAge > 0 {
+		cutoff := time.Now().Add(-f.config.MaxAge)
+		for _, name := range files {
+			if info, err := os.Stat(name); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(name)
+			}
+		}
+	}
+}
+
+// compressFile replaces name with name.gz.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := name + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Keep the original time so that MaxAge counts from the rotation.
+	if info, err := src.Stat(); err == nil {
+		os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	}
+	if err := os.Rename(tmp, name+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(name)
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}